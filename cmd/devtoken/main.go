@@ -0,0 +1,122 @@
+// Command devtoken mints the tokens of a session of any user in developer mode (DEV_AUTH=true), signed with the
+// local keys of JWT_KEYS_DIR. With -event it prints the given local event authorized with the access token,
+// as the custom authorizer would do, to be used with sam local invoke.
+//
+//	go run ./cmd/devtoken -user someone@example.com
+//	go run ./cmd/devtoken -user someone@example.com -event functions/BookGetFun/event.local.json > event.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/auth"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+const defaultDeviceID = "dev-cli"
+
+func main() {
+	userFlag := flag.String("user", "", "GUID or email of the user")
+	deviceID := flag.String("device", defaultDeviceID, "device of the session, the session of the device is reused")
+	eventPath := flag.String("event", "", "local event to authorize with the access token")
+	flag.Parse()
+
+	if *userFlag == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, err := auth.NewBaseContext()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !ctx.Config.Dev.Auth {
+		log.Fatal("developer mode is disabled, set DEV_AUTH=true")
+	}
+
+	user, err := findUser(ctx, *userFlag)
+	if err != nil {
+		log.Fatalf("user %s not found: %v", *userFlag, err)
+	}
+
+	sessionID, err := ctx.UserService.CreateSession(user.Guid, *deviceID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token, err := ctx.Service.GenerateToken(user, sessionID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	output := interface{}(token)
+
+	if *eventPath != "" {
+		output, err = authorizeEvent(ctx, *eventPath, token.AccessToken)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(output); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// findUser finds the user by GUID or by email
+func findUser(ctx *auth.Context, value string) (*domain.User, error) {
+	if guid, err := uuid.Parse(value); err == nil {
+		return ctx.UserService.GetUserByGuid(guid)
+	}
+
+	var user domain.User
+	if err := ctx.DB.Where("email = ?", value).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// authorizeEvent sets the bearer token and the authorizer context of the principal in the event
+func authorizeEvent(ctx *auth.Context, path string, accessToken string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	event := map[string]interface{}{}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+
+	sessionCache := auth.NewSessionCache(time.Duration(ctx.Config.Auth.Jwt.SessionCacheSeconds) * time.Second)
+
+	principal, err := auth.NewContextAuthorizer(ctx, sessionCache).Authorize(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, _ := event["headers"].(map[string]interface{})
+	if headers == nil {
+		headers = map[string]interface{}{}
+	}
+	headers["Authorization"] = "Bearer " + accessToken
+	event["headers"] = headers
+
+	requestContext, _ := event["requestContext"].(map[string]interface{})
+	if requestContext == nil {
+		requestContext = map[string]interface{}{}
+	}
+	requestContext["authorizer"] = principal.Context()
+	event["requestContext"] = requestContext
+
+	return event, nil
+}
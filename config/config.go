@@ -15,11 +15,31 @@ type (
 		Database Database
 		// Apple represents the Apple configuration.
 		Apple Apple
+		// AppStore represents the App Store Server API configuration.
+		AppStore AppStore
 		// Langchain represents the langchain configuration.
 		Langchain Langchain
+		// Library represents the ask-your-library configuration.
+		Library Library
+		// Review represents the spaced repetition configuration.
+		Review Review
+		// Push represents the push notifications configuration.
+		Push Push
+		// Fcm represents the Firebase Cloud Messaging configuration.
+		Fcm Fcm
+		// Entitlements represents the limits of the free plan and the trial period.
+		Entitlements Entitlements
+
+		// Smtp represents the configuration of the server sending the emails.
+		Smtp Smtp
+		// Digest represents the weekly email digest configuration.
+		Digest Digest
 
 		// Telegram represents the Telegram configuration.
 		Telegram Telegram
+
+		// Dev represents the developer mode configuration.
+		Dev Dev
 	}
 
 	// Auth represents the authentication configuration.
@@ -27,6 +47,7 @@ type (
 		Google AuthGoogle
 		Apple  Apple
 		Jwt    AuthJwt
+		Email  AuthEmail
 	}
 
 	// AuthGoogle represents the Google authentication configuration.
@@ -40,20 +61,52 @@ type (
 		AppBundleId string `env-required:"true" env:"IOS_APP_BUNDLE_ID"`
 		IssuerId    string `env-required:"true" env:"APPSTORE_ISSUER_ID"`
 
-		SignInCertificate    string `env-required:"true" env:"APPLE_SIGNIN_CERTIFICATE"`
-		SignInCertificateKey string `env-required:"true" env:"APPLE_SIGNIN_CERTIFICATE_KEY"`
-
 		ApnsCertificate    string `env-required:"true" env:"APPLE_APNS_CERTIFICATE"`
 		ApnsCertificateKey string `env-required:"true" env:"APPLE_APNS_CERTIFICATE_KEY"`
+	}
 
-		// IAPCertificate    string `env-required:"true" env:"APPLE_IAP_CERTIFICATE"`
-		// IAPCertificateKey string `env-required:"true" env:"APPLE_IAP_CERTIFICATE_KEY"`
+	// AppStore represents the App Store Server API configuration. PrivateKey is the PEM in-app purchase key
+	// KeyID of App Store Connect, RootCertificate is the PEM Apple Root CA - G3 the signed data is chained to.
+	// The Sandbox environment is tried when a transaction is not found in Production, for TestFlight builds.
+	AppStore struct {
+		KeyID           string `env:"APPSTORE_KEY_ID"`
+		PrivateKey      string `env:"APPSTORE_PRIVATE_KEY"`
+		RootCertificate string `env:"APPSTORE_ROOT_CERTIFICATE"`
+		Environment     string `env-default:"Production" env:"APPSTORE_ENVIRONMENT"`
 	}
 
-	// AuthJwt represents the JWT authentication configuration.
+	// AuthJwt represents the JWT authentication configuration, token durations are in minutes.
+	// The refresh token is rotated at every refresh, so its duration is the maximum inactivity of a session.
+	// The authorizer caches the validity of a session for SessionCacheSeconds.
+	//
+	// Tokens are signed with the PEM PrivateKey identified by KeyID, or with the key KeyID of the KeysDir
+	// directory for local use. PreviousKeys is the JWKS document of the rotated keys still accepted.
+	// Secret is only used to verify the legacy HS256 tokens, remove it once they have expired.
 	AuthJwt struct {
-		Secret              string `env-required:"true" env:"JWT_SECRET"`
-		AccessTokenDuration int    `env-default:"604800" env:"JWT_ACCESS_TOKEN_DURATION"`
+		Secret               string `env:"JWT_SECRET"`
+		Issuer               string `env-default:"feynman-backend" env:"JWT_ISSUER"`
+		Audience             string `env-default:"feynman-app" env:"JWT_AUDIENCE"`
+		KeyID                string `env:"JWT_KEY_ID"`
+		PrivateKey           string `env:"JWT_PRIVATE_KEY"`
+		PreviousKeys         string `env:"JWT_PREVIOUS_KEYS"`
+		KeysDir              string `env:"JWT_KEYS_DIR"`
+		AccessTokenDuration  int    `env-default:"60" env:"JWT_ACCESS_TOKEN_DURATION"`
+		RefreshTokenDuration int    `env-default:"86400" env:"JWT_REFRESH_TOKEN_DURATION"`
+		SessionCacheSeconds  int    `env-default:"60" env:"JWT_SESSION_CACHE_SECONDS"`
+	}
+
+	// AuthEmail represents the passwordless email sign in configuration, durations are in minutes.
+	// At most MaxCodesPerEmail and MaxCodesPerIP codes are sent within RateLimitWindow, a code is
+	// burned after MaxAttempts wrong guesses. The sign in link is only sent when LinkURL is set.
+	// Codes and link tokens are stored as HMAC keyed with CodeSecret, no code is sent without it.
+	AuthEmail struct {
+		CodeSecret       string `env:"AUTH_EMAIL_CODE_SECRET"`
+		LinkURL          string `env:"AUTH_EMAIL_LINK_URL"`
+		CodeDuration     int    `env-default:"10" env:"AUTH_EMAIL_CODE_DURATION"`
+		MaxAttempts      int    `env-default:"5" env:"AUTH_EMAIL_MAX_ATTEMPTS"`
+		MaxCodesPerEmail int    `env-default:"5" env:"AUTH_EMAIL_MAX_CODES_PER_EMAIL"`
+		MaxCodesPerIP    int    `env-default:"20" env:"AUTH_EMAIL_MAX_CODES_PER_IP"`
+		RateLimitWindow  int    `env-default:"60" env:"AUTH_EMAIL_RATE_LIMIT_WINDOW"`
 	}
 
 	// Database represents the database configuration.
@@ -73,11 +126,98 @@ type (
 		GPTModel     string `env-required:"true" env:"GPT_MODEL"`
 	}
 
+	// Library represents the limits applied when answering questions over the user's picks.
+	Library struct {
+		MaxContextTokens   int `env-default:"3000" env:"LIBRARY_MAX_CONTEXT_TOKENS"`
+		MaxAnswerTokens    int `env-default:"600" env:"LIBRARY_MAX_ANSWER_TOKENS"`
+		MaxCandidatePicks  int `env-default:"12" env:"LIBRARY_MAX_CANDIDATE_PICKS"`
+		MaxHistoryMessages int `env-default:"6" env:"LIBRARY_MAX_HISTORY_MESSAGES"`
+	}
+
+	// Review represents the default daily limits of the spaced repetition reviews.
+	Review struct {
+		DailyLimit    int `env-default:"20" env:"REVIEW_DAILY_LIMIT"`
+		NewDailyLimit int `env-default:"10" env:"REVIEW_NEW_DAILY_LIMIT"`
+	}
+
+	// Entitlements represents the limits of the plans, a negative limit is unlimited.
+	// New users are premium for TrialDays after signing up, 0 disables the trial.
+	Entitlements struct {
+		TrialDays               int `env-default:"7" env:"ENTITLEMENTS_TRIAL_DAYS"`
+		FreeMaxBooks            int `env-default:"5" env:"ENTITLEMENTS_FREE_MAX_BOOKS"`
+		FreeMaxPicksPerBook     int `env-default:"20" env:"ENTITLEMENTS_FREE_MAX_PICKS_PER_BOOK"`
+		FreeMaxAICallsPerDay    int `env-default:"10" env:"ENTITLEMENTS_FREE_MAX_AI_CALLS_PER_DAY"`
+		PremiumMaxAICallsPerDay int `env-default:"500" env:"ENTITLEMENTS_PREMIUM_MAX_AI_CALLS_PER_DAY"`
+	}
+
+	// Push represents the scheduling of the daily pick, ScheduleWindow is the interval in minutes between
+	// the runs of PushNotificationEventBridgeRule, the users whose local time falls within it are notified.
+	// A pick is not surfaced again for PickCooldown days.
+	Push struct {
+		ScheduleWindow int `env-default:"15" env:"PUSH_SCHEDULE_WINDOW"`
+		PickCooldown   int `env-default:"14" env:"PUSH_PICK_COOLDOWN"`
+		// SendAttempts and RetryBackoff (milliseconds, doubled on every attempt) retry the transient errors of the providers
+		SendAttempts int `env-default:"3" env:"PUSH_SEND_ATTEMPTS"`
+		RetryBackoff int `env-default:"500" env:"PUSH_RETRY_BACKOFF"`
+		// MaxFailures is the number of failed push notifications in a row disabling the push notifications of a session
+		MaxFailures int `env-default:"5" env:"PUSH_MAX_FAILURES"`
+		// PageSize is the number of sessions read at once, BatchSize the number of sessions of an SNS message
+		// (ten messages share the 256 KB of a batch publish) and Concurrency the number of pushes sent at once
+		PageSize    int `env-default:"500" env:"PUSH_PAGE_SIZE"`
+		BatchSize   int `env-default:"20" env:"PUSH_BATCH_SIZE"`
+		Concurrency int `env-default:"8" env:"PUSH_CONCURRENCY"`
+	}
+
+	// Fcm represents the Firebase Cloud Messaging configuration, ServiceAccount is the JSON key of a service
+	// account allowed to send the messages of the project. The Android devices get no push without it.
+	Fcm struct {
+		ServiceAccount string `env:"FCM_SERVICE_ACCOUNT"`
+	}
+
+	// Digest represents the weekly email digest, the unsubscribe links of the emails are signed with
+	// UnsubscribeSecret. MaxItems is the number of picks and topics of each section.
+	Digest struct {
+		UnsubscribeSecret string `env:"DIGEST_UNSUBSCRIBE_SECRET"`
+		UnsubscribeURL    string `env-default:"https://api.feynman.app/v1/digest/unsubscribe" env:"DIGEST_UNSUBSCRIBE_URL"`
+		PageSize          int    `env-default:"200" env:"DIGEST_PAGE_SIZE"`
+		MaxItems          int    `env-default:"5" env:"DIGEST_MAX_ITEMS"`
+	}
+
+	// Smtp represents the SMTP server configuration, STARTTLS is used when the server supports it.
+	Smtp struct {
+		Host     string `env:"SMTP_HOST"`
+		Port     int    `env-default:"587" env:"SMTP_PORT"`
+		Username string `env:"SMTP_USERNAME"`
+		Password string `env:"SMTP_PASSWORD"`
+		From     string `env-default:"Feynman <no-reply@feynman.app>" env:"SMTP_FROM"`
+	}
+
 	Telegram struct {
 		ApiToken string `env-required:"true" env:"TELEGRAM_API_TOKEN"`
 	}
+
+	// Dev represents the developer mode, where the tokens are signed with the local keys of JWT_KEYS_DIR
+	// and can be minted for any user with cmd/devtoken.
+	// SAM local sets AWS_SAM_LOCAL in its containers, the CLI runs outside of Lambda.
+	Dev struct {
+		Auth         bool   `env-default:"false" env:"DEV_AUTH"`
+		SamLocal     bool   `env-default:"false" env:"AWS_SAM_LOCAL"`
+		FunctionName string `env:"AWS_LAMBDA_FUNCTION_NAME"`
+	}
+)
+
+var (
+	// ErrDevAuthNotLocal is returned when the developer mode is enabled in a deployed stage.
+	ErrDevAuthNotLocal = errors.New("DEV_AUTH is only allowed when running locally")
+	// ErrKeysDirWithoutDevAuth is returned when the local keys are configured outside of the developer mode.
+	ErrKeysDirWithoutDevAuth = errors.New("JWT_KEYS_DIR requires DEV_AUTH")
 )
 
+// IsLocal tells whether the application runs locally, with SAM local or outside of Lambda.
+func (d Dev) IsLocal() bool {
+	return d.SamLocal || d.FunctionName == ""
+}
+
 // NewConfig creates a new configuration.
 func NewConfig() (*Config, error) {
 	cfg := &Config{}
@@ -87,5 +227,13 @@ func NewConfig() (*Config, error) {
 		return nil, errors.New("failed to load config: " + err.Error())
 	}
 
+	if cfg.Dev.Auth && !cfg.Dev.IsLocal() {
+		return nil, ErrDevAuthNotLocal
+	}
+
+	if cfg.Auth.Jwt.KeysDir != "" && !cfg.Dev.Auth {
+		return nil, ErrKeysDirWithoutDevAuth
+	}
+
 	return cfg, nil
 }
@@ -46,7 +46,7 @@ var _ = Describe("Config", func() {
 			Expect(cfg).NotTo(BeNil())
 			Expect(cfg.Auth.Google.ClientID).To(Equal("google"))
 			Expect(cfg.Auth.Jwt.Secret).To(Equal("secret"))
-			Expect(cfg.Auth.Jwt.AccessTokenDuration).To(Equal(604800))
+			Expect(cfg.Auth.Jwt.AccessTokenDuration).To(Equal(60))
 			Expect(cfg.Database.Host).To(Equal("localhost"))
 			Expect(cfg.Database.Port).To(Equal(5432))
 			Expect(cfg.Database.User).To(Equal("postgres"))
@@ -77,4 +77,12 @@ var _ = Describe("Config", func() {
 			Expect(cfg.Database.Name).To(Equal("postgres"))
 		})
 	})
+
+	Describe("Dev", func() {
+		It("should be local with SAM local or outside of Lambda only", func() {
+			Expect(config.Dev{}.IsLocal()).To(BeTrue())
+			Expect(config.Dev{SamLocal: true, FunctionName: "UserGetFun"}.IsLocal()).To(BeTrue())
+			Expect(config.Dev{FunctionName: "UserGetFun"}.IsLocal()).To(BeFalse())
+		})
+	})
 })
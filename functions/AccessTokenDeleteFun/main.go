@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/accesstoken"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/utility"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	tokenID, err := uuid.Parse(request.PathParameters["tokenId"])
+	if err != nil {
+		logger.Error("Invalid token guid", zap.Error(err))
+		return *failure.NewBadRequest("Invalid Token ID"), nil
+	}
+
+	userID := utility.GetUserIDBy(request)
+
+	ctx, err := accesstoken.NewContext()
+	if err != nil {
+		logger.Error("Failed to create context", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	if err := ctx.Service.RevokeToken(userID, tokenID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return *failure.NewNotFound("Access token not found"), nil
+		}
+
+		logger.Error("Failed to revoke access token", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 204,
+	}, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
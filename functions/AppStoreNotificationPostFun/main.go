@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/go-playground/validator/v10"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/subscription"
+	"go.uber.org/zap"
+)
+
+/*
+	App Store Server Notifications V2, Apple retries the notification until it gets a 200.
+*/
+
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	body := &domain.AppStoreNotificationBody{}
+	if err := json.Unmarshal([]byte(request.Body), body); err != nil {
+		logger.Error("Invalid request body", zap.Error(err))
+		return *failure.NewBadRequest("Invalid request body"), nil
+	}
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(body); err != nil {
+		logger.Error("Body validation failed", zap.Error(err))
+		return *failure.NewBadRequest("Body validation failed"), nil
+	}
+
+	subscriptionCtx, err := subscription.NewContext()
+	if err != nil {
+		logger.Error("Failed to create context", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	if err := subscriptionCtx.Service.HandleNotification(ctx, body.SignedPayload); err != nil {
+		switch {
+		// handle payload not signed by the App Store
+		case errors.Is(err, subscription.ErrInvalidSignedData):
+			logger.Error("Invalid notification payload", zap.Error(err))
+			return *failure.NewUnauthorized("Invalid payload"), nil
+		case errors.Is(err, subscription.ErrBundleMismatch):
+			logger.Error("Notification of another app", zap.Error(err))
+			return *failure.NewBadRequest("Invalid payload"), nil
+		// the purchase has not been verified by any user yet, nothing to do
+		case errors.Is(err, subscription.ErrSubscriptionNotFound):
+			logger.Info("Subscription of the notification not found")
+			return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+		}
+
+		// handle internal server error, Apple retries the notification
+		logger.Error("Failed to handle app store notification", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+	}, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pietro-putelli/feynman-backend/internal/auth"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func handler(handleFunc auth.AppleNotificationHandler) func(events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	return func(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+
+		body := &domain.AppleNotificationBody{}
+		if err := json.Unmarshal([]byte(request.Body), body); err != nil {
+			logger.Error("Invalid request body", zap.Error(err))
+			return *failure.NewBadRequest("Invalid request body"), nil
+		}
+
+		context, err := auth.NewBaseContext()
+		if err != nil {
+			logger.Error("Failed to create context", zap.Error(err))
+			return *failure.NewInternalServerError(), nil
+		}
+
+		if err := handleFunc(context, body); err != nil {
+			// handle validator error
+			if errors.Is(err, &failure.ValidationErr{}) {
+				logger.Error("Validation error", zap.Error(err))
+				return *failure.NewBadRequest(err.Error()), nil
+			}
+
+			// handle payload not signed by Apple
+			if errors.Is(err, auth.ErrInvalidToken) {
+				logger.Error("Invalid notification payload", zap.Error(err))
+				return *failure.NewUnauthorized("Invalid payload"), nil
+			}
+
+			// the identity is already gone, nothing to do
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				logger.Info("Identity of the notification not found")
+				return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+			}
+
+			// handle internal server error, Apple retries the notification
+			logger.Error("Failed to handle apple notification", zap.Error(err))
+			return *failure.NewInternalServerError(), nil
+		}
+
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+		}, nil
+	}
+}
+
+func main() {
+	lambda.Start(handler(auth.HandleAppleNotification))
+}
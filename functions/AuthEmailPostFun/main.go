@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pietro-putelli/feynman-backend/internal/auth"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"go.uber.org/zap"
+)
+
+func handler(handleFunc auth.EmailLoginHandler) func(events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	return func(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+
+		body := &domain.EmailLoginBody{}
+		if err := json.Unmarshal([]byte(request.Body), body); err != nil {
+			logger.Error("Invalid request body", zap.Error(err))
+			return *failure.NewBadRequest("Invalid request body"), nil
+		}
+
+		context, err := auth.NewBaseContext()
+		if err != nil {
+			logger.Error("Failed to create context", zap.Error(err))
+			return *failure.NewInternalServerError(), nil
+		}
+
+		if err := handleFunc(context, body, request.RequestContext.Identity.SourceIP); err != nil {
+			// handle validator error
+			if errors.Is(err, &failure.ValidationErr{}) {
+				logger.Error("Validation error", zap.Error(err))
+				return *failure.NewBadRequest(err.Error()), nil
+			}
+
+			// handle rate limit
+			if errors.Is(err, auth.ErrTooManyEmailCodes) {
+				logger.Warn("Too many sign in codes", zap.Error(err))
+				return *failure.NewTooManyRequests("Too many sign in codes requested, try again later"), nil
+			}
+
+			// handle internal server error
+			logger.Error("Failed to send sign in code", zap.Error(err))
+			return *failure.NewInternalServerError(), nil
+		}
+
+		return events.APIGatewayProxyResponse{
+			StatusCode: 202,
+		}, nil
+	}
+}
+
+func main() {
+	lambda.Start(handler(auth.HandleEmailLogin))
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/pietro-putelli/feynman-backend/internal/auth"
 	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
 	"go.uber.org/zap"
 )
 
@@ -30,6 +32,12 @@ func handler(handleFunc auth.AuthRefreshTokenHandler) func(events.APIGatewayProx
 
 		tokenInfo, err := handleFunc(context, refreshToken)
 		if err != nil {
+			// handle revoked, expired, reused or malformed refresh tokens
+			if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, user.ErrInvalidRefreshToken) || errors.Is(err, user.ErrRefreshTokenReused) {
+				logger.Warn("Refresh token rejected", zap.Error(err))
+				return *failure.NewUnauthorized("Invalid refresh token"), nil
+			}
+
 			logger.Error("Failed to handle auth token", zap.Error(err))
 			return *failure.NewInternalServerError(), nil
 		}
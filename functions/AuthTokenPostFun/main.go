@@ -9,6 +9,7 @@ import (
 	"github.com/pietro-putelli/feynman-backend/internal/auth"
 	"github.com/pietro-putelli/feynman-backend/internal/domain"
 	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
 	"go.uber.org/zap"
 )
 
@@ -44,6 +45,18 @@ func handler(handleFunc auth.AuthTokenHandler) func(events.APIGatewayProxyReques
 				return *failure.NewBadRequest("Invalid provider"), nil
 			}
 
+			// handle token not valid or sign in code already used
+			if errors.Is(err, auth.ErrInvalidToken) {
+				logger.Error("Invalid token", zap.Error(err))
+				return *failure.NewUnauthorized("Invalid token"), nil
+			}
+
+			// handle email of an account created with another provider
+			if errors.Is(err, user.ErrEmailAlreadyRegistered) {
+				logger.Error("Email already registered", zap.Error(err))
+				return *failure.NewConflict("Email already registered with another provider, sign in and link the account"), nil
+			}
+
 			// handle internal server error
 			logger.Error("Failed to handle auth token", zap.Error(err))
 			return *failure.NewInternalServerError(), nil
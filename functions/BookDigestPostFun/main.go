@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/book"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/utility"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	bookID, err := uuid.Parse(request.PathParameters["bookId"])
+	if err != nil {
+		logger.Error("Invalid Book ID", zap.Error(err))
+		return *failure.NewBadRequest("Invalid Book ID"), nil
+	}
+
+	userID := utility.GetUserIDBy(request)
+
+	ctx, err := book.NewContext()
+	if err != nil {
+		logger.Error("Failed to create context", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	digest, err := ctx.Service.RequestBookDigest(userID, bookID)
+	if err != nil {
+		var upgradeErr *failure.UpgradeRequiredErr
+		if errors.As(err, &upgradeErr) {
+			return *failure.NewUpgradeRequired(upgradeErr), nil
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return *failure.NewNotFound("Book not found"), nil
+		}
+
+		logger.Error("Failed to request book digest", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	response, err := json.Marshal(digest)
+	if err != nil {
+		logger.Error("Failed to marshal book digest", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Body:       string(response),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
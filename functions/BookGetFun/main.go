@@ -28,13 +28,16 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 
 	userID := utility.GetUserIDBy(request)
 
+	/* The AI digest is only read here, it is generated with BookDigestPostFun */
+	withDigest := request.QueryStringParameters["digest"] == "true"
+
 	ctx, err := book.NewContext()
 	if err != nil {
 		logger.Error("Failed to create context", zap.Error(err))
 		return *failure.NewInternalServerError(), nil
 	}
 
-	bookResponse, err := ctx.Service.GetCompleteBookByGuid(userID, bookID)
+	bookResponse, err := ctx.Service.GetCompleteBookByGuid(userID, bookID, withDigest)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return *failure.NewNotFound("Book not found"), nil
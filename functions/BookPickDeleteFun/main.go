@@ -23,6 +23,10 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 		return *failure.NewBadRequest("Error parsing path params"), nil
 	}
 
+	if !utility.HasScope(request, domain.ScopePicksWrite) {
+		return *failure.NewForbidden("Missing scope " + domain.ScopePicksWrite), nil
+	}
+
 	userID := utility.GetUserIDBy(request)
 
 	validate := validator.New(validator.WithRequiredStructEnabled())
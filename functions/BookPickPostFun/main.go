@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -17,6 +18,10 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
+	if !utility.HasScope(request, domain.ScopePicksWrite) {
+		return *failure.NewForbidden("Missing scope " + domain.ScopePicksWrite), nil
+	}
+
 	userID := utility.GetUserIDBy(request)
 
 	body := &domain.CreateBookBody{}
@@ -39,6 +44,11 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 
 	bookData, err := ctx.Service.CreateBookPick(userID, body)
 	if err != nil {
+		var upgradeErr *failure.UpgradeRequiredErr
+		if errors.As(err, &upgradeErr) {
+			return *failure.NewUpgradeRequired(upgradeErr), nil
+		}
+
 		logger.Error("Failed to create book or pick", zap.Error(err))
 		return *failure.NewInternalServerError(), nil
 	}
@@ -17,6 +17,10 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
+	if !utility.HasScope(request, domain.ScopePicksWrite) {
+		return *failure.NewForbidden("Missing scope " + domain.ScopePicksWrite), nil
+	}
+
 	userID := utility.GetUserIDBy(request)
 
 	body := &domain.EditBookPickBody{}
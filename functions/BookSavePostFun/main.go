@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -33,6 +34,11 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 
 	bookData, err := ctx.Service.SaveBook(userID, body)
 	if err != nil {
+		var upgradeErr *failure.UpgradeRequiredErr
+		if errors.As(err, &upgradeErr) {
+			return *failure.NewUpgradeRequired(upgradeErr), nil
+		}
+
 		logger.Error("Failed to save book", zap.Error(err))
 		return *failure.NewInternalServerError(), nil
 	}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pietro-putelli/feynman-backend/internal/book"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func handler(context context.Context, sqsEvent events.SQSEvent) error {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	messageBody := sqsEvent.Records[0].Body
+	message := &domain.BookDigestMessage{}
+
+	err := json.Unmarshal([]byte(messageBody), &message)
+	if err != nil {
+		logger.Error("Failed to unmarshal SQS message", zap.Error(err))
+		return err
+	}
+
+	ctx, err := book.NewContext()
+	if err != nil {
+		logger.Error("Error creating new context", zap.Error(err))
+		return err
+	}
+
+	err = ctx.Service.GenerateBookDigest(message.BookID)
+	if err != nil {
+		/* The book or all its picks have been deleted since the digest was queued, nothing to retry */
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Info("Book not found, skipping digest", zap.Uint("book_id", message.BookID))
+			return nil
+		}
+
+		logger.Error("Error generating book digest", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
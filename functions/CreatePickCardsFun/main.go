@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pietro-putelli/feynman-backend/internal/card"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/langchain"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func handler(context context.Context, sqsEvent events.SQSEvent) error {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	messageBody := sqsEvent.Records[0].Body
+	message := &domain.BookPickCardsMessage{}
+
+	err := json.Unmarshal([]byte(messageBody), &message)
+	if err != nil {
+		logger.Error("Failed to unmarshal SQS message", zap.Error(err))
+		return err
+	}
+
+	ctx, err := card.NewContext()
+	if err != nil {
+		logger.Error("Error creating new context", zap.Error(err))
+		return err
+	}
+
+	generated, err := langchain.GeneratePickCards(message.PickContent)
+	if err != nil {
+		logger.Error("Error generating pick cards", zap.Error(err))
+		return err
+	}
+
+	cards := []domain.GeneratedPickCard{}
+	if err := mapstructure.Decode(generated, &cards); err != nil {
+		logger.Error("Error decoding pick cards", zap.Error(err))
+		return err
+	}
+
+	err = ctx.Service.ReplacePickCards(message.UserGuid, message.PickID, message.PickContent, cards)
+	if err != nil {
+		/* The pick has been deleted before its cards were generated, nothing to retry */
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Info("Pick not found, skipping cards", zap.Uint("pick_id", message.PickID))
+			return nil
+		}
+
+		logger.Error("Error adding pick cards", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
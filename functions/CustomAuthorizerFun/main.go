@@ -2,18 +2,21 @@ package main
 
 import (
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/pietro-putelli/feynman-backend/internal/auth"
 	"go.uber.org/zap"
 )
 
 // https://repost.aws/knowledge-center/api-gateway-lambda-authorization-errors
 
+// Validity of the sessions, kept across warm invocations
+var sessionCache *auth.SessionCache
+
 // Help function to generate an IAM policy
-func generatePolicy(principalId, effect, resource string) events.APIGatewayCustomAuthorizerResponse {
+func generatePolicy(principal *auth.Principal, effect string, resources []string) events.APIGatewayCustomAuthorizerResponse {
 	authResponse := events.APIGatewayCustomAuthorizerResponse{PrincipalID: "user"}
 
 	authResponse.PolicyDocument = events.APIGatewayCustomAuthorizerPolicy{
@@ -22,13 +25,13 @@ func generatePolicy(principalId, effect, resource string) events.APIGatewayCusto
 			{
 				Action:   []string{"execute-api:Invoke"},
 				Effect:   effect,
-				Resource: []string{resource},
+				Resource: resources,
 			},
 		},
 	}
 
-	authResponse.Context = map[string]interface{}{
-		"userID": principalId,
+	if principal != nil {
+		authResponse.Context = principal.Context()
 	}
 
 	return authResponse
@@ -43,36 +46,34 @@ func handleRequest(event events.APIGatewayCustomAuthorizerRequest) (events.APIGa
 
 	tmp := strings.Split(event.MethodArn, ":")
 	apiGatewayArnTmp := strings.Split(tmp[5], "/")
-	resource := tmp[0] + ":" + tmp[1] + ":" + tmp[2] + ":" + tmp[3] + ":" + tmp[4] + ":" + apiGatewayArnTmp[0] + "/*/*"
+	apiArn := tmp[0] + ":" + tmp[1] + ":" + tmp[2] + ":" + tmp[3] + ":" + tmp[4] + ":" + apiGatewayArnTmp[0]
+	resource := apiArn + "/*/*"
+
+	deniedPolicy := generatePolicy(nil, "Deny", []string{resource})
 
 	context, err := auth.NewBaseContext()
 
 	if err != nil {
 		logger.Error("NewBaseContext", zap.Error(err))
-		return generatePolicy("User", "Deny", resource), nil
+		return deniedPolicy, nil
 	}
 
-	deniedPolicy := generatePolicy("User", "Deny", resource)
-
-	claims := jwt.MapClaims{}
+	if sessionCache == nil {
+		sessionCache = auth.NewSessionCache(time.Duration(context.Config.Auth.Jwt.SessionCacheSeconds) * time.Second)
+	}
 
-	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(context.Config.Auth.Jwt.Secret), nil
-	})
+	principal, err := auth.NewContextAuthorizer(context, sessionCache).Authorize(token)
 	if err != nil {
-		logger.Error("ParseWithClaims", zap.Error(err))
+		logger.Error("Authorize", zap.Error(err))
 		return deniedPolicy, nil
 	}
 
-	// Check if the token is not of refresh kind
-	if claims["kind"] == "refresh" {
-		logger.Error("Invalid Token Kind", zap.Error(err))
-		return deniedPolicy, nil
+	// Personal access tokens are only allowed the routes of their scopes
+	if principal.IsAccessToken() {
+		return generatePolicy(principal, "Allow", auth.ScopeResources(apiArn, principal.Scopes)), nil
 	}
 
-	userGuid := claims["sub"].(string)
-
-	return generatePolicy(userGuid, "Allow", resource), nil
+	return generatePolicy(principal, "Allow", []string{resource}), nil
 }
 
 func main() {
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/digest"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+/*
+	Unsubscribe link of the weekly digest. Opening it from the email (GET) only asks for a confirmation, since link
+	scanners and prefetches follow the links of the emails, the form of the page and the one-click unsubscribe of the
+	mail clients (POST, RFC 8058) unsubscribe the user.
+	It is public, the token signed for the user authorizes the request.
+*/
+
+var confirmationPage = template.Must(template.New("confirmation").Parse(`<!DOCTYPE html>
+<html><body style="font-family: -apple-system, Helvetica, Arial, sans-serif; text-align: center; padding-top: 64px;">
+<p>Do you want to stop receiving the weekly digest?</p>
+<form method="post" action="?user={{.User}}&amp;token={{.Token}}">
+<button type="submit">Unsubscribe</button>
+</form>
+</body></html>`))
+
+const unsubscribedPage = `<!DOCTYPE html>
+<html><body style="font-family: -apple-system, Helvetica, Arial, sans-serif; text-align: center; padding-top: 64px;">
+<p>You will not receive the weekly digest anymore.</p>
+<p>You can enable it again in the settings of the app.</p>
+</body></html>`
+
+func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	digestContext, err := digest.NewContext()
+	if err != nil {
+		logger.Error("Error creating digest context", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	userID, err := uuid.Parse(request.QueryStringParameters["user"])
+	if err != nil {
+		return *failure.NewBadRequest("invalid unsubscribe link"), nil
+	}
+
+	err = digest.VerifyUnsubscribeToken(digestContext.Config.Digest.UnsubscribeSecret, userID, request.QueryStringParameters["token"])
+	if err != nil {
+		return *failure.NewForbidden("invalid unsubscribe link"), nil
+	}
+
+	if request.HTTPMethod != http.MethodPost {
+		link := struct{ User, Token string }{userID.String(), request.QueryStringParameters["token"]}
+
+		page := bytes.Buffer{}
+		if err := confirmationPage.Execute(&page, link); err != nil {
+			logger.Error("Error rendering unsubscribe confirmation", zap.Error(err))
+			return *failure.NewInternalServerError(), nil
+		}
+
+		return htmlResponse(page.String()), nil
+	}
+
+	err = digestContext.Service.Unsubscribe(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return *failure.NewNotFound("user not found"), nil
+	}
+	if err != nil {
+		logger.Error("Error unsubscribing from digest", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	/* The mail clients post List-Unsubscribe=One-Click and show nothing, the form of the page shows the outcome */
+	if isOneClickUnsubscribe(request) {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+	}
+
+	return htmlResponse(unsubscribedPage), nil
+}
+
+func isOneClickUnsubscribe(request events.APIGatewayProxyRequest) bool {
+	body := request.Body
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return false
+		}
+		body = string(decoded)
+	}
+
+	form, err := url.ParseQuery(body)
+	if err != nil {
+		return false
+	}
+
+	return form.Get("List-Unsubscribe") == "One-Click"
+}
+
+func htmlResponse(body string) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       body,
+		Headers:    map[string]string{"Content-Type": "text/html; charset=utf-8"},
+	}
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -2,52 +2,81 @@ package main
 
 import (
 	"context"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/notification"
 	"github.com/pietro-putelli/feynman-backend/internal/sns"
-	"github.com/pietro-putelli/feynman-backend/internal/user"
 	"go.uber.org/zap"
 )
 
+/*
+	Invoked every PUSH_SCHEDULE_WINDOW minutes, it sends the daily pick to the sessions whose local notification time falls within the window of the run.
+	The sessions are read a page at a time and published in batches, each SNS message holds several sessions.
+*/
+
 func handler(ctx context.Context, event events.EventBridgeEvent) error {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
-	userContext, err := user.NewContext()
+	notificationContext, err := notification.NewContext()
 	if err != nil {
-		logger.Error("Error creating user context", zap.Error(err))
+		logger.Error("Error creating notification context", zap.Error(err))
 		return err
 	}
 
-	database := userContext.Database
-
-	sessions := []domain.ShortSession{}
-	err = database.Model(&domain.Session{}).
-		Select("sessions.guid, sessions.user_id, sessions.device_token, users.settings").
-		Joins("JOIN users ON users.id = sessions.user_id").
-		Where("sessions.expired_at = ? AND (device_token = '') IS NOT TRUE", "0001-01-01 00:00:00").
-		Where("users.is_notification_enabled = ?", true).
-		Find(&sessions).Error
+	window := time.Duration(notificationContext.Config.Push.ScheduleWindow) * time.Minute
 
-	if err != nil {
-		logger.Error("Error fetching eligible users", zap.Error(err))
-		return err
+	/* The window starts at the scheduled time of the run, so a late or retried run covers the same sessions */
+	scheduledAt := event.Time
+	if scheduledAt.IsZero() {
+		scheduledAt = time.Now()
 	}
+	from := scheduledAt.Truncate(window)
 
-	for _, session := range sessions {
-		err = sns.SendMessage(sns.TopicNames.PushNotification, session)
+	pushConfig := notificationContext.Config.Push
+	publisher := sns.NewPublisher()
+
+	var selected, sent, failed int
+
+	for after := uint(0); ; {
+		sessions, next, err := notificationContext.Service.GetDueSessions(from, window, after, pushConfig.PageSize)
 		if err != nil {
-			logger.Error("Error sending SNS message", zap.Error(err))
+			logger.Error("Error fetching eligible users", zap.Error(err))
+			return err
 		}
-	}
 
-	if err != nil {
-		logger.Error("Error fetching eligible users", zap.Error(err))
-		return err
+		selected += len(sessions)
+
+		/* The sessions already sent today are left out */
+		claimed, err := notificationContext.Service.ClaimDailyPicks(sessions)
+		if err != nil {
+			logger.Error("Error claiming daily picks", zap.Error(err))
+			failed += len(sessions)
+		} else {
+			published, unpublished := notification.PublishDailyPicks(publisher, claimed, pushConfig.BatchSize)
+			sent += published
+			failed += len(unpublished)
+
+			if err := notificationContext.Service.ReleaseDailyPicks(unpublished); err != nil {
+				logger.Error("Error releasing daily picks", zap.Error(err))
+			}
+		}
+
+		if next == 0 {
+			break
+		}
+		after = next
 	}
 
+	logger.Info("Daily picks fanned out",
+		zap.Time("from", from),
+		zap.Int("selected", selected),
+		zap.Int("sent", sent),
+		zap.Int("failed", failed),
+	)
+
 	return nil
 }
 
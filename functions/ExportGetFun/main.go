@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pietro-putelli/feynman-backend/internal/book"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/utility"
+	"go.uber.org/zap"
+)
+
+func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	if !utility.HasScope(request, domain.ScopeExport) {
+		return *failure.NewForbidden("Missing scope " + domain.ScopeExport), nil
+	}
+
+	/* The library is exported as JSON unless another format is asked */
+	format := request.QueryStringParameters["format"]
+	if format == "" {
+		format = domain.ExportFormatJSON
+	}
+
+	if format != domain.ExportFormatJSON && format != domain.ExportFormatMarkdown {
+		return *failure.NewBadRequest("Invalid export format"), nil
+	}
+
+	userID := utility.GetUserIDBy(request)
+
+	ctx, err := book.NewContext()
+	if err != nil {
+		logger.Error("Failed to create context", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	books, err := ctx.Service.ExportBooks(userID, format)
+	if err != nil {
+		var upgradeErr *failure.UpgradeRequiredErr
+		if errors.As(err, &upgradeErr) {
+			return *failure.NewUpgradeRequired(upgradeErr), nil
+		}
+
+		logger.Error("Failed to export books", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	if format == domain.ExportFormatMarkdown {
+		return events.APIGatewayProxyResponse{
+			Body:       book.ExportMarkdown(books),
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Content-Type": "text/markdown; charset=utf-8",
+			},
+		}, nil
+	}
+
+	response, err := json.Marshal(books)
+	if err != nil {
+		logger.Error("Failed to marshal response", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		Body:       string(response),
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
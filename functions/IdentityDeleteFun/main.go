@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+	"github.com/pietro-putelli/feynman-backend/internal/utility"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	provider := request.PathParameters["provider"]
+	if provider == "" {
+		return *failure.NewBadRequest("Invalid provider"), nil
+	}
+
+	userID := utility.GetUserIDBy(request)
+
+	ctx, err := user.NewContext()
+	if err != nil {
+		logger.Error("Failed to create context", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	if err := ctx.Service.UnlinkIdentity(userID, provider); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return *failure.NewNotFound("Identity not found"), nil
+		}
+
+		if errors.Is(err, user.ErrLastIdentity) {
+			return *failure.NewConflict(err.Error()), nil
+		}
+
+		logger.Error("Failed to unlink identity", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 204,
+	}, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
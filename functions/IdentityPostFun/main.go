@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pietro-putelli/feynman-backend/internal/auth"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+	"github.com/pietro-putelli/feynman-backend/internal/utility"
+	"go.uber.org/zap"
+)
+
+func handler(handleFunc auth.LinkIdentityHandler) func(events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	return func(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+
+		body := &domain.LinkIdentityBody{}
+		if err := json.Unmarshal([]byte(request.Body), body); err != nil {
+			logger.Error("Invalid request body", zap.Error(err))
+			return *failure.NewBadRequest("Invalid request body"), nil
+		}
+
+		userID := utility.GetUserIDBy(request)
+
+		context, err := auth.NewBaseContext()
+		if err != nil {
+			logger.Error("Failed to create context", zap.Error(err))
+			return *failure.NewInternalServerError(), nil
+		}
+
+		identity, err := handleFunc(context, userID, body)
+		if err != nil {
+			// handle validator error
+			if errors.Is(err, &failure.ValidationErr{}) {
+				logger.Error("Validation error", zap.Error(err))
+				return *failure.NewBadRequest(err.Error()), nil
+			}
+
+			// handle provider error
+			if errors.Is(err, &auth.InvalidProviderError{}) {
+				logger.Error("Invalid provider", zap.Error(err))
+				return *failure.NewBadRequest("Invalid provider"), nil
+			}
+
+			// handle identity conflicts
+			if errors.Is(err, user.ErrIdentityLinkedToAnotherUser) || errors.Is(err, user.ErrProviderAlreadyLinked) {
+				logger.Error("Identity conflict", zap.Error(err))
+				return *failure.NewConflict(err.Error()), nil
+			}
+
+			// handle internal server error
+			logger.Error("Failed to link identity", zap.Error(err))
+			return *failure.NewInternalServerError(), nil
+		}
+
+		response, err := json.Marshal(identity)
+		if err != nil {
+			logger.Error("Failed to marshal response", zap.Error(err))
+			return *failure.NewInternalServerError(), nil
+		}
+
+		return events.APIGatewayProxyResponse{
+			StatusCode: 201,
+			Body:       string(response),
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+		}, nil
+	}
+}
+
+func main() {
+	lambda.Start(handler(auth.HandleLinkIdentity))
+}
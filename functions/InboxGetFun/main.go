@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/go-playground/validator/v10"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/inbox"
+	"github.com/pietro-putelli/feynman-backend/internal/utility"
+	"go.uber.org/zap"
+)
+
+func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	params := &domain.InboxListParams{}
+	utility.ParseQueryParams(request, params)
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(params); err != nil {
+		logger.Error("Params validation failed", zap.Error(err))
+		return *failure.NewBadRequest("Params validation failed"), nil
+	}
+
+	userID := utility.GetUserIDBy(request)
+
+	ctx, err := inbox.NewContext()
+	if err != nil {
+		logger.Error("Failed to create context", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	notifications, err := ctx.Service.GetNotifications(userID, params)
+	if err != nil {
+		if errors.Is(err, inbox.ErrInvalidCursor) {
+			return *failure.NewBadRequest("Invalid cursor"), nil
+		}
+
+		logger.Error("Failed to get notifications", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	response, err := json.Marshal(notifications)
+	if err != nil {
+		logger.Error("Failed to marshal response", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		Body:       string(response),
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
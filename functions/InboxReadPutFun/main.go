@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/inbox"
+	"github.com/pietro-putelli/feynman-backend/internal/utility"
+	"go.uber.org/zap"
+)
+
+func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	notificationID, err := uuid.Parse(request.PathParameters["notificationId"])
+	if err != nil {
+		logger.Error("Invalid notification guid", zap.Error(err))
+		return *failure.NewBadRequest("Invalid Notification ID"), nil
+	}
+
+	userID := utility.GetUserIDBy(request)
+
+	ctx, err := inbox.NewContext()
+	if err != nil {
+		logger.Error("Failed to create context", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	if err := ctx.Service.MarkRead(userID, notificationID); err != nil {
+		if errors.Is(err, inbox.ErrNotificationNotFound) {
+			return *failure.NewNotFound("Notification not found"), nil
+		}
+
+		logger.Error("Failed to mark notification as read", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 204,
+	}, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/auth"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"go.uber.org/zap"
+)
+
+/* Publish the public keys used to verify the tokens, the previous ones included during a rotation */
+func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		logger.Error("Failed to load config", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	keyStore, err := auth.NewKeyStore(&cfg.Auth.Jwt)
+	if err != nil {
+		logger.Error("Failed to load keys", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	set, err := auth.NewJWKSet(keyStore)
+	if err != nil {
+		logger.Error("Failed to build JWKS", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	response, err := json.Marshal(set)
+	if err != nil {
+		logger.Error("Failed to marshal response", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Body:       string(response),
+		Headers: map[string]string{
+			"Content-Type":  "application/json",
+			"Cache-Control": "public, max-age=3600",
+		},
+	}, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/go-playground/validator/v10"
+	"github.com/pietro-putelli/feynman-backend/internal/card"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/utility"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	userID := utility.GetUserIDBy(request)
+
+	body := &domain.EditPickCardBody{}
+	if err := json.Unmarshal([]byte(request.Body), body); err != nil {
+		logger.Error("Invalid Request Body", zap.Error(err))
+		return *failure.NewBadRequest("Invalid Request Body"), nil
+	}
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(body); err != nil {
+		logger.Error("Invalid Request Body", zap.Error(err))
+		return *failure.NewBadRequest("Body Validation Failed"), nil
+	}
+
+	ctx, err := card.NewContext()
+	if err != nil {
+		logger.Error("Failed to Create Context", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	editedCard, err := ctx.Service.EditPickCard(userID, body)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return *failure.NewNotFound("Card not found"), nil
+		}
+
+		logger.Error("Unable to Update Card", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	response, err := json.Marshal(editedCard)
+	if err != nil {
+		logger.Error("Failed to marshal response", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		Body:       string(response),
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -44,6 +44,11 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 	} else {
 		bookPicks, err = ctx.Service.SemanticSearch(userID, params)
 		if err != nil {
+			var upgradeErr *failure.UpgradeRequiredErr
+			if errors.As(err, &upgradeErr) {
+				return *failure.NewUpgradeRequired(upgradeErr), nil
+			}
+
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return *failure.NewNotFound("No results found"), nil
 			}
@@ -3,33 +3,51 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/pietro-putelli/feynman-backend/config"
 	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/inbox"
+	"github.com/pietro-putelli/feynman-backend/internal/notification"
 	"github.com/pietro-putelli/feynman-backend/internal/user"
-	"github.com/sideshow/apns2"
-	"github.com/sideshow/apns2/token"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+/*
+	Invoked with the batches of sessions published by EligibleUsersForPNFun, it sends the daily pick to each of them, Push.Concurrency at once.
+*/
+
 type SnsMessage struct {
 	Message string `json:"message"`
 }
 
+// dailyPickSender sends the daily pick to the sessions, it is shared by the sessions of an invocation.
+type dailyPickSender struct {
+	logger              *zap.Logger
+	notificationService notification.Service
+	inboxService        inbox.Service
+	sender              notification.PushSender
+}
+
 func handler(ctx context.Context, event events.SNSEvent) error {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
-	message := event.Records[0].SNS.Message
+	sessions := []domain.ShortSession{}
 
-	userSession := domain.ShortSession{}
+	for _, record := range event.Records {
+		recordSessions, err := decodeSessions(record.SNS.Message)
+		if err != nil {
+			logger.Error("Error unmarshalling sessions", zap.Error(err))
+			return err
+		}
 
-	err := json.Unmarshal([]byte(message), &userSession)
-	if err != nil {
-		logger.Error("Error unmarshalling user", zap.Error(err))
-		return err
+		sessions = append(sessions, recordSessions...)
 	}
 
 	cfg, err := config.NewConfig()
@@ -44,77 +62,161 @@ func handler(ctx context.Context, event events.SNSEvent) error {
 		return err
 	}
 
-	database := userContext.Database
-
-	userSettings := domain.UserSettings{}
-	err = json.Unmarshal(userSession.Settings, &userSettings)
+	sender, err := notification.NewPushSender(ctx, cfg)
 	if err != nil {
-		logger.Error("Error unmarshalling user settings", zap.Error(err))
+		logger.Error("Error creating push sender", zap.Error(err))
 		return err
 	}
 
-	limit := 3
-	if userSettings.NotificationMode == "all" {
-		limit = -1
+	/* The transient errors are retried here, SNS retrying the whole invocation would not help a dead token */
+	sender = notification.NewRetryingSender(
+		sender,
+		cfg.Push.SendAttempts,
+		time.Duration(cfg.Push.RetryBackoff)*time.Millisecond,
+	)
+
+	dailyPicks := &dailyPickSender{
+		logger:              logger,
+		notificationService: notification.NewService(userContext.Database, &cfg.Push),
+		inboxService:        inbox.NewService(userContext.Database, userContext.Service),
+		sender:              sender,
+	}
+
+	var (
+		mu                    sync.Mutex
+		wg                    sync.WaitGroup
+		sent, failed, skipped int
+		slots                 = make(chan struct{}, max(cfg.Push.Concurrency, 1))
+	)
+
+	for i := range sessions {
+		slots <- struct{}{}
+		wg.Add(1)
+
+		go func(session *domain.ShortSession) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			isSent, err := dailyPicks.send(ctx, session)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err != nil:
+				failed++
+			case isSent:
+				sent++
+			default:
+				skipped++
+			}
+		}(&sessions[i])
+	}
+
+	wg.Wait()
+
+	logger.Info("Daily picks sent",
+		zap.Int("selected", len(sessions)),
+		zap.Int("sent", sent),
+		zap.Int("failed", failed),
+		zap.Int("skipped", skipped),
+	)
+
+	return nil
+}
+
+// decodeSessions decodes the sessions of a message, the messages published before the batches hold a single session.
+func decodeSessions(message string) ([]domain.ShortSession, error) {
+	sessions := []domain.ShortSession{}
+	if err := json.Unmarshal([]byte(message), &sessions); err == nil {
+		return sessions, nil
 	}
 
-	var pick domain.BookPickPushNotification
+	session := domain.ShortSession{}
+	if err := json.Unmarshal([]byte(message), &session); err != nil {
+		return nil, err
+	}
 
-	err = database.Model(&domain.Book{}).
-		Select("books.guid AS book_id, book_picks.content_text AS content").
-		Where("books.user_id = ?", userSession.UserID).
-		Order("books.updated_at DESC").
-		Limit(limit).
-		Joins("JOIN book_picks ON books.id = book_picks.book_id").
-		Order("RANDOM()").
-		First(&pick).Error
+	return []domain.ShortSession{session}, nil
+}
 
+// send sends the daily pick to the session, false when the user has no pick to notify.
+func (s *dailyPickSender) send(ctx context.Context, userSession *domain.ShortSession) (bool, error) {
+	/* The same pick for every device and run of the day, the picks surfaced within the cooldown are skipped */
+	pick, err := s.notificationService.SelectDailyPick(userSession)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.Info("No pick to notify", zap.Uint("user_id", userSession.UserID))
+		return false, nil
+	}
 	if err != nil {
-		logger.Error("Error fetching book pick", zap.Error(err))
-		return err
+		s.logger.Error("Error selecting daily pick", zap.Error(err))
+		return false, err
 	}
 
-	apnsKey, err := token.AuthKeyFromBytes([]byte(cfg.Apple.ApnsCertificate))
+	payload, err := notification.DailyPickPayload(pick, notification.SessionLanguage(userSession))
 	if err != nil {
-		logger.Error("Error loading APNS key", zap.Error(err))
-		return err
+		s.logger.Error("Error rendering daily pick", zap.Error(err))
+		return false, err
+	}
+
+	localDate := userSession.LocalDate
+	if localDate == "" {
+		localDate = time.Now().UTC().Format(time.DateOnly)
 	}
 
-	token := &token.Token{
-		AuthKey: apnsKey,
-		KeyID:   cfg.Apple.ApnsCertificateKey,
-		TeamID:  cfg.Apple.TeamId,
+	/* The daily pick stays in the inbox, once a day for all the devices of the user */
+	err = s.inboxService.Notify(&domain.InboxNotification{
+		UserID:    userSession.UserID,
+		Kind:      domain.InboxKindDailyPick,
+		Title:     payload.Aps.Alert.Title,
+		Body:      payload.Aps.Alert.Body,
+		BookID:    &pick.BookID,
+		PickID:    &pick.PickGuid,
+		DedupeKey: inbox.DedupeKey(domain.InboxKindDailyPick, localDate),
+	})
+	if err != nil {
+		s.logger.Error("Error adding daily pick to inbox", zap.Error(err))
 	}
 
-	payload := domain.PushNotificationPayload{
-		Aps: domain.PusNotificationAps{
-			Alert: domain.PushNotificationAlert{
-				Title: "📚 Daily Pick Reminder",
-				Body:  pick.Content,
-			},
-			Badge: 1,
-		},
-		Data: domain.PushNotificationPayloadData{
-			BookID: pick.BookID,
-		},
+	/* The badge is the number of unread notifications of the inbox */
+	payload.Aps.Badge = 1
+	if unread, err := s.inboxService.CountUnread(userSession.UserID); err != nil {
+		s.logger.Error("Error counting unread notifications", zap.Error(err))
+	} else {
+		payload.Aps.Badge = int(unread)
 	}
 
-	notification := &apns2.Notification{
+	err = s.sender.Send(ctx, &notification.PushMessage{
 		DeviceToken: userSession.DeviceToken,
-		Topic:       cfg.Apple.AppBundleId,
+		Platform:    userSession.Platform,
+		Environment: userSession.PushEnvironment,
 		Payload:     payload,
+	})
+
+	outcome := notification.ClassifyPush(err)
+
+	reason := ""
+	var pushErr *notification.PushError
+	if errors.As(err, &pushErr) {
+		reason = pushErr.Reason
 	}
 
-	client := apns2.NewTokenClient(token)
-	client.Host = apns2.HostProduction
+	if err := s.notificationService.RecordPushDelivery(userSession.Guid, outcome, reason); err != nil {
+		s.logger.Error("Error recording push delivery", zap.Error(err))
+	}
 
-	_, err = client.Push(notification)
 	if err != nil {
-		logger.Error("Error sending push notification", zap.Error(err))
-		return err
+		s.logger.Warn("Push notification not delivered", zap.String("outcome", outcome), zap.Error(err))
+		return false, err
 	}
 
-	return nil
+	if err := s.notificationService.RecordDailyPick(userSession.UserID, userSession.LocalDate, pick); err != nil {
+		s.logger.Error("Error recording daily pick", zap.Error(err))
+	}
+
+	return true, nil
 }
 
 func main() {
@@ -2,11 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/go-playground/validator/v10"
 	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/entitlement"
 	"github.com/pietro-putelli/feynman-backend/internal/failure"
 	"github.com/pietro-putelli/feynman-backend/internal/utility"
 	"github.com/pietro-putelli/feynman-backend/langchain"
@@ -26,6 +28,24 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 		return *failure.NewBadRequest("Params validation failed"), nil
 	}
 
+	userID := utility.GetUserIDBy(request)
+
+	ctx, err := entitlement.NewContext()
+	if err != nil {
+		logger.Error("Failed to create context", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	if err := ctx.Service.UseAICall(userID); err != nil {
+		var upgradeErr *failure.UpgradeRequiredErr
+		if errors.As(err, &upgradeErr) {
+			return *failure.NewUpgradeRequired(upgradeErr), nil
+		}
+
+		logger.Error("Failed to count AI call", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
 	enrichedText, err := langchain.EnrichPickContent(params.Text)
 	if err != nil {
 		logger.Error("Error enriching pick content", zap.Error(err))
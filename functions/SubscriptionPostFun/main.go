@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/go-playground/validator/v10"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/subscription"
+	"github.com/pietro-putelli/feynman-backend/internal/utility"
+	"go.uber.org/zap"
+)
+
+/*
+	Invoke this function after every purchase or restore of the app, the premium status is granted once the transaction is verified with the App Store.
+*/
+
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	userID := utility.GetUserIDBy(request)
+
+	body := &domain.VerifySubscriptionBody{}
+	if err := json.Unmarshal([]byte(request.Body), body); err != nil {
+		logger.Error("Invalid request body", zap.Error(err))
+		return *failure.NewBadRequest("Invalid request body"), nil
+	}
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(body); err != nil {
+		logger.Error("Body validation failed", zap.Error(err))
+		return *failure.NewBadRequest("Body validation failed"), nil
+	}
+
+	subscriptionCtx, err := subscription.NewContext()
+	if err != nil {
+		logger.Error("Failed to create context", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	result, err := subscriptionCtx.Service.VerifyTransaction(ctx, userID, body.TransactionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, subscription.ErrTransactionNotFound):
+			return *failure.NewNotFound("Transaction not found"), nil
+		case errors.Is(err, subscription.ErrInvalidSignedData), errors.Is(err, subscription.ErrBundleMismatch):
+			logger.Error("Invalid transaction", zap.Error(err))
+			return *failure.NewBadRequest("Invalid transaction"), nil
+		case errors.Is(err, subscription.ErrSubscriptionOwnedByAnotherUser):
+			return *failure.NewConflict("Subscription belongs to another user"), nil
+		}
+
+		logger.Error("Failed to verify transaction", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	response, err := json.Marshal(result)
+	if err != nil {
+		logger.Error("Failed to marshal response", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		Body:       string(response),
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
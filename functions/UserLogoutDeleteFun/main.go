@@ -13,9 +13,10 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 
 	userID := utility.GetUserIDBy(request)
 
-	rawSessionID := request.QueryStringParameters["guid"]
+	/* The session of the access token used for the request */
+	sessionID := utility.GetSessionIDBy(request)
 
-	if rawSessionID == "" {
+	if sessionID == uuid.Nil {
 		return *failure.NewBadRequest("Invalid Session ID"), nil
 	}
 
@@ -24,11 +25,6 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 		return *failure.NewInternalServerError(), nil
 	}
 
-	sessionID, err := uuid.Parse(rawSessionID)
-	if err != nil {
-		return *failure.NewBadRequest("Invalid Session ID"), nil
-	}
-
 	if err := ctx.Service.LogoutSession(userID, sessionID); err != nil {
 		return *failure.NewInternalServerError(), nil
 	}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pietro-putelli/feynman-backend/internal/entitlement"
 	"github.com/pietro-putelli/feynman-backend/internal/failure"
 	"github.com/pietro-putelli/feynman-backend/internal/user"
 	"github.com/pietro-putelli/feynman-backend/internal/utility"
@@ -33,6 +34,15 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 		return *failure.NewBadRequest(err.Error()), nil
 	}
 
+	/* The capabilities of the plan and their usage, so the app can show the limits before they are hit */
+	entitlementService := entitlement.NewService(ctx.Database, ctx.Service, &ctx.Config.Entitlements)
+
+	userInfo.Entitlements, err = entitlementService.GetEntitlements(userID)
+	if err != nil {
+		logger.Error("Failed to get entitlements", zap.Error(err))
+		return *failure.NewInternalServerError(), nil
+	}
+
 	response, err := json.Marshal(userInfo)
 	if err != nil {
 		logger.Error("Failed to marshal response", zap.Error(err))
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pietro-putelli/feynman-backend/internal/digest"
+	"go.uber.org/zap"
+)
+
+/*
+	Invoked every Monday by DigestEventBridgeRule, it sends the weekly digest of the week before to the users who opted in.
+	The users are read a page at a time, the digests without any activity are not sent.
+*/
+
+func handler(ctx context.Context, event events.EventBridgeEvent) error {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	digestContext, err := digest.NewContext()
+	if err != nil {
+		logger.Error("Error creating digest context", zap.Error(err))
+		return err
+	}
+
+	if digestContext.Mailer == nil {
+		logger.Error("Mailer not configured")
+		return errors.New("mailer not configured")
+	}
+
+	/* The week of the scheduled time of the run, so a late or retried run sends the same digests */
+	scheduledAt := event.Time
+	if scheduledAt.IsZero() {
+		scheduledAt = time.Now()
+	}
+	weekStart := digest.WeekStart(scheduledAt)
+
+	digestConfig := &digestContext.Config.Digest
+
+	var selected, sent, skipped, failed int
+
+	for after := uint(0); ; {
+		users, next, err := digestContext.Service.GetRecipients(weekStart, after, digestConfig.PageSize)
+		if err != nil {
+			logger.Error("Error fetching digest recipients", zap.Error(err))
+			return err
+		}
+
+		selected += len(users)
+
+		for i := range users {
+			user := &users[i]
+
+			claimed, err := digestContext.Service.ClaimDigest(user.ID, weekStart)
+			if err != nil {
+				logger.Error("Error claiming digest", zap.Error(err))
+				failed++
+				continue
+			}
+
+			/* Already sent this week */
+			if !claimed {
+				skipped++
+				continue
+			}
+
+			userDigest, err := digestContext.Service.BuildDigest(user, weekStart)
+			if err == nil && userDigest.IsEmpty() {
+				skipped++
+				continue
+			}
+			if err == nil {
+				err = digest.Send(ctx, digestContext.Mailer, digestConfig, userDigest)
+			}
+
+			if err != nil {
+				logger.Error("Error sending digest", zap.Uint("user_id", user.ID), zap.Error(err))
+				failed++
+
+				if err := digestContext.Service.ReleaseDigest(user.ID, weekStart); err != nil {
+					logger.Error("Error releasing digest", zap.Error(err))
+				}
+				continue
+			}
+
+			sent++
+
+			/* The next digests and daily picks go on with the other picks */
+			if err := digestContext.Service.RecordResurfacedPicks(userDigest); err != nil {
+				logger.Error("Error recording resurfaced picks", zap.Error(err))
+			}
+		}
+
+		if next == 0 {
+			break
+		}
+		after = next
+	}
+
+	logger.Info("Weekly digests sent",
+		zap.Time("week_start", weekStart),
+		zap.Int("selected", selected),
+		zap.Int("sent", sent),
+		zap.Int("skipped", skipped),
+		zap.Int("failed", failed),
+	)
+
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,183 @@
+package accesstoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+	"gorm.io/gorm"
+)
+
+// TokenPrefix marks the personal access tokens, so that they are told apart from the JWTs and found by secret scanners.
+const TokenPrefix = "fpat_"
+
+// ErrInvalidAccessToken is returned when the token is unknown, revoked, expired or its user is not active.
+var ErrInvalidAccessToken = errors.New("invalid access token")
+
+var _ Service = (*serviceImpl)(nil)
+
+// The Service interface is defined to abstract the behaviors that the serviceImpl struct will implement.
+type Service interface {
+	// CreateToken Create a personal access token, the plain token is only returned here
+	CreateToken(userID uuid.UUID, body *domain.CreateAccessTokenBody) (*domain.CreatedAccessTokenResponse, error)
+
+	// GetTokens List the personal access tokens of the user that are not revoked
+	GetTokens(userID uuid.UUID) ([]domain.AccessTokenResponse, error)
+
+	// RevokeToken Revoke a personal access token of the user
+	RevokeToken(userID uuid.UUID, tokenID uuid.UUID) error
+
+	// Authenticate Find the user and the scopes of the token and record its use
+	Authenticate(token string) (*domain.AccessTokenPrincipal, error)
+}
+
+type serviceImpl struct {
+	db          *gorm.DB
+	userService user.Service
+}
+
+func NewService(db *gorm.DB, userService user.Service) Service {
+	return &serviceImpl{
+		db:          db,
+		userService: userService,
+	}
+}
+
+// IsAccessToken tells whether the bearer token is a personal access token.
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, TokenPrefix)
+}
+
+// NewToken generates a new personal access token and the hash to store.
+func NewToken() (string, string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+
+	token := TokenPrefix + base64.RawURLEncoding.EncodeToString(bytes)
+	return token, hashToken(token), nil
+}
+
+/* Tokens are random and long, a plain SHA-256 is enough */
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (service *serviceImpl) CreateToken(userID uuid.UUID, body *domain.CreateAccessTokenBody) (*domain.CreatedAccessTokenResponse, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, tokenHash, err := NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken := domain.PersonalAccessToken{
+		UserID:    user.ID,
+		Name:      body.Name,
+		TokenHash: tokenHash,
+		Scopes:    body.Scopes,
+	}
+
+	if body.ExpiresInDays != nil {
+		expiresAt := time.Now().AddDate(0, 0, *body.ExpiresInDays)
+		accessToken.ExpiresAt = &expiresAt
+	}
+
+	if err := service.db.Create(&accessToken).Error; err != nil {
+		return nil, err
+	}
+
+	return &domain.CreatedAccessTokenResponse{
+		AccessTokenResponse: domain.AccessTokenResponseFromModel(&accessToken),
+		Token:               token,
+	}, nil
+}
+
+func (service *serviceImpl) GetTokens(userID uuid.UUID) ([]domain.AccessTokenResponse, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []domain.PersonalAccessToken{}
+	if err := service.db.Where("user_id = ? AND revoked_at IS NULL", user.ID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]domain.AccessTokenResponse, len(tokens))
+	for i := range tokens {
+		response[i] = domain.AccessTokenResponseFromModel(&tokens[i])
+	}
+
+	return response, nil
+}
+
+func (service *serviceImpl) RevokeToken(userID uuid.UUID, tokenID uuid.UUID) error {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return err
+	}
+
+	result := service.db.Model(&domain.PersonalAccessToken{}).
+		Where("guid = ? AND user_id = ? AND revoked_at IS NULL", tokenID, user.ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (service *serviceImpl) Authenticate(token string) (*domain.AccessTokenPrincipal, error) {
+	if !IsAccessToken(token) {
+		return nil, ErrInvalidAccessToken
+	}
+
+	var row struct {
+		ID       uint
+		Guid     uuid.UUID
+		UserGuid uuid.UUID
+		Scopes   []string `gorm:"serializer:json"`
+	}
+
+	now := time.Now()
+
+	err := service.db.Table("personal_access_tokens AS t").
+		Select("t.id, t.guid, t.scopes, u.guid AS user_guid").
+		Joins("JOIN users u ON u.id = t.user_id").
+		Where("t.token_hash = ? AND t.revoked_at IS NULL AND (t.expires_at IS NULL OR t.expires_at > ?) AND u.is_active = ?", hashToken(token), now, true).
+		Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	/* The authorizer result is cached, so the last use is precise to its cache duration */
+	if err := service.db.Model(&domain.PersonalAccessToken{}).Where("id = ?", row.ID).Update("last_used_at", now).Error; err != nil {
+		return nil, err
+	}
+
+	return &domain.AccessTokenPrincipal{
+		UserID:  row.UserGuid,
+		TokenID: row.Guid,
+		Scopes:  row.Scopes,
+	}, nil
+}
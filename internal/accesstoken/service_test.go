@@ -0,0 +1,230 @@
+package accesstoken_test
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/pietro-putelli/feynman-backend/internal/accesstoken"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+)
+
+func sha256Hex(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
+
+var _ = Describe("Service", func() {
+	var (
+		service     accesstoken.Service
+		sqlMock     sqlmock.Sqlmock
+		userService *user.MockService
+	)
+
+	BeforeEach(func() {
+		db, sqlMockGen, _ := sqlmock.New()
+		sqlMock = sqlMockGen
+
+		conn := postgres.New(postgres.Config{
+			Conn: db,
+		})
+
+		database, _ := database.NewDB(conn)
+		userService = user.NewMockService(gomock.NewController(GinkgoT()))
+		service = accesstoken.NewService(database, userService)
+	})
+
+	Describe("NewToken", func() {
+		It("should generate prefixed tokens with a different hash each time", func() {
+			// Act
+			first, firstHash, err := accesstoken.NewToken()
+			Expect(err).To(BeNil())
+			second, secondHash, err := accesstoken.NewToken()
+			Expect(err).To(BeNil())
+
+			// Assert
+			Expect(accesstoken.IsAccessToken(first)).To(BeTrue())
+			Expect(first).NotTo(Equal(second))
+			Expect(firstHash).To(HaveLen(64))
+			Expect(firstHash).NotTo(Equal(secondHash))
+			Expect(firstHash).NotTo(ContainSubstring(first))
+		})
+	})
+
+	Describe("CreateToken", func() {
+		It("should store only the hash of the token", func() {
+			// Arrange
+			userID := uuid.New()
+			expiresInDays := 30
+			body := &domain.CreateAccessTokenBody{Name: "Obsidian", Scopes: []string{domain.ScopeRead}, ExpiresInDays: &expiresInDays}
+
+			userService.EXPECT().GetUserByGuid(userID).Return(&domain.User{ID: 7, Guid: userID}, nil)
+
+			var storedHash string
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`INSERT INTO "personal_access_tokens" (.+) RETURNING`).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 7, "Obsidian", hashArg{&storedHash}, `["read"]`, sqlmock.AnyArg(), nil, nil).
+				WillReturnRows(sqlmock.NewRows([]string{"guid", "id"}).AddRow(uuid.New(), 1))
+			sqlMock.ExpectCommit()
+
+			// Act
+			result, err := service.CreateToken(userID, body)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(accesstoken.IsAccessToken(result.Token)).To(BeTrue())
+			Expect(storedHash).To(Equal(sha256Hex(result.Token)))
+			Expect(result.Scopes).To(Equal([]string{domain.ScopeRead}))
+			Expect(result.ExpiresAt).NotTo(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+
+	Describe("RevokeToken", func() {
+		expectedUpdate := `UPDATE "personal_access_tokens" SET "revoked_at"=\$1,"updated_at"=\$2 WHERE guid = \$3 AND user_id = \$4 AND revoked_at IS NULL`
+
+		It("should revoke the token of the user", func() {
+			// Arrange
+			userID := uuid.New()
+			tokenID := uuid.New()
+
+			userService.EXPECT().GetUserByGuid(userID).Return(&domain.User{ID: 7, Guid: userID}, nil)
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectExec(expectedUpdate).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), tokenID, 7).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectCommit()
+
+			// Act
+			err := service.RevokeToken(userID, tokenID)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should not find the tokens of other users or already revoked", func() {
+			// Arrange
+			userID := uuid.New()
+			tokenID := uuid.New()
+
+			userService.EXPECT().GetUserByGuid(userID).Return(&domain.User{ID: 7, Guid: userID}, nil)
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectExec(expectedUpdate).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), tokenID, 7).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			sqlMock.ExpectCommit()
+
+			// Act
+			err := service.RevokeToken(userID, tokenID)
+
+			// Assert
+			Expect(err).To(MatchError(gorm.ErrRecordNotFound))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+
+	Describe("Authenticate", func() {
+		expectedSelect := `SELECT t.id, t.guid, t.scopes, u.guid AS user_guid FROM personal_access_tokens AS t JOIN users u ON u.id = t.user_id ` +
+			`WHERE t.token_hash = \$1 AND t.revoked_at IS NULL AND \(t.expires_at IS NULL OR t.expires_at > \$2\) AND u.is_active = \$3`
+
+		It("should reject JWTs without querying the database", func() {
+			// Arrange
+			service := accesstoken.NewService(nil, nil)
+
+			// Act
+			result, err := service.Authenticate("eyJhbGciOiJFZERTQSJ9.e30.signature")
+
+			// Assert
+			Expect(result).To(BeNil())
+			Expect(err).To(MatchError(accesstoken.ErrInvalidAccessToken))
+		})
+
+		It("should find the token by its hash and record its use", func() {
+			// Arrange
+			token, tokenHash, err := accesstoken.NewToken()
+			Expect(err).To(BeNil())
+			tokenID := uuid.New()
+			userID := uuid.New()
+
+			sqlMock.ExpectQuery(expectedSelect).
+				WithArgs(tokenHash, sqlmock.AnyArg(), true, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "guid", "scopes", "user_guid"}).AddRow(3, tokenID, `["read","export"]`, userID))
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectExec(`UPDATE "personal_access_tokens" SET "last_used_at"=\$1,"updated_at"=\$2 WHERE id = \$3`).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 3).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectCommit()
+
+			// Act
+			result, err := service.Authenticate(token)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(result.UserID).To(Equal(userID))
+			Expect(result.TokenID).To(Equal(tokenID))
+			Expect(result.Scopes).To(Equal([]string{domain.ScopeRead, domain.ScopeExport}))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should reject revoked, expired or unknown tokens", func() {
+			// Arrange
+			token, tokenHash, err := accesstoken.NewToken()
+			Expect(err).To(BeNil())
+
+			/* Revoked and expired tokens are filtered out by the query */
+			sqlMock.ExpectQuery(expectedSelect).
+				WithArgs(tokenHash, sqlmock.AnyArg(), true, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "guid", "scopes", "user_guid"}))
+
+			// Act
+			result, err := service.Authenticate(token)
+
+			// Assert
+			Expect(result).To(BeNil())
+			Expect(err).To(MatchError(accesstoken.ErrInvalidAccessToken))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should return the database errors", func() {
+			// Arrange
+			token, tokenHash, err := accesstoken.NewToken()
+			Expect(err).To(BeNil())
+
+			sqlMock.ExpectQuery(expectedSelect).
+				WithArgs(tokenHash, sqlmock.AnyArg(), true, 1).
+				WillReturnError(errors.New("connection refused"))
+
+			// Act
+			result, err := service.Authenticate(token)
+
+			// Assert
+			Expect(result).To(BeNil())
+			Expect(err).To(MatchError("connection refused"))
+		})
+	})
+})
+
+/* Capture the stored hash of the token */
+type hashArg struct {
+	value *string
+}
+
+func (a hashArg) Match(v driver.Value) bool {
+	hash, ok := v.(string)
+	*a.value = hash
+	return ok
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+const (
+	// AppleKeysURL is the JWKS document of the keys signing the Apple identity tokens and notifications.
+	AppleKeysURL = "https://appleid.apple.com/auth/keys"
+	// appleIssuer is the issuer of the Apple identity tokens and notifications.
+	appleIssuer = "https://appleid.apple.com"
+)
+
+// JWKSFetcher provides the verification keys published at a JWKS endpoint.
+//
+//go:generate mockgen -source=apple_keys.go -destination=./apple_keys_mock.go -package=auth
+type JWKSFetcher interface {
+	// Key returns the key of the given ID, ErrInvalidToken when it is not published.
+	Key(ctx context.Context, kid string) (*Key, error)
+}
+
+// defaultAppleKeys lives as long as the Lambda container so that warm invocations skip the download.
+var defaultAppleKeys = NewJWKSFetcher(AppleKeysURL, 24*time.Hour)
+
+// cachedJWKSFetcher represents a JWKS fetcher keeping the keys for a while.
+type cachedJWKSFetcher struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*Key
+	fetchedAt time.Time
+}
+
+// NewJWKSFetcher creates a fetcher caching the keys of the JWKS endpoint for the given TTL.
+//
+// An unknown key ID refreshes the keys before the TTL, at most once a minute, so that rotated keys are picked up.
+func NewJWKSFetcher(url string, ttl time.Duration) JWKSFetcher {
+	return &cachedJWKSFetcher{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (f *cachedJWKSFetcher) Key(ctx context.Context, kid string) (*Key, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	age := time.Since(f.fetchedAt)
+
+	if key, ok := f.keys[kid]; ok && age < f.ttl {
+		return key, nil
+	}
+
+	if f.keys == nil || age >= time.Minute {
+		keys, err := f.fetch(ctx)
+		if err != nil {
+			/* Keep verifying with the stale keys while the endpoint is unreachable */
+			if key, ok := f.keys[kid]; ok {
+				return key, nil
+			}
+			return nil, err
+		}
+
+		f.keys = keys
+		f.fetchedAt = time.Now()
+	}
+
+	if key, ok := f.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, ErrInvalidToken
+}
+
+func (f *cachedJWKSFetcher) fetch(ctx context.Context) (map[string]*Key, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := f.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: status %d", f.url, response.StatusCode)
+	}
+
+	set := domain.JWKSet{}
+	if err := json.NewDecoder(response.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*Key{}
+	for _, jwk := range set.Keys {
+		/* Keys of unsupported types are skipped, the others keep working */
+		key, err := KeyFromJWK(jwk)
+		if err != nil {
+			continue
+		}
+		keys[key.ID] = key
+	}
+
+	return keys, nil
+}
+
+// parseAppleToken verifies a token signed by Apple for the given client ID, the app bundle ID.
+func parseAppleToken(ctx context.Context, keys JWKSFetcher, clientID string, tokenString string, options ...jwt.ParserOption) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+
+	options = append(options,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(appleIssuer),
+		jwt.WithAudience(clientID),
+	)
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, err := keys.Key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+
+		return key.PublicKey, nil
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,55 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: apple_keys.go
+//
+// Generated by this command:
+//
+//	mockgen -source=apple_keys.go -destination=./apple_keys_mock.go -package=auth
+//
+
+// Package auth is a generated GoMock package.
+package auth
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockJWKSFetcher is a mock of JWKSFetcher interface.
+type MockJWKSFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockJWKSFetcherMockRecorder
+}
+
+// MockJWKSFetcherMockRecorder is the mock recorder for MockJWKSFetcher.
+type MockJWKSFetcherMockRecorder struct {
+	mock *MockJWKSFetcher
+}
+
+// NewMockJWKSFetcher creates a new mock instance.
+func NewMockJWKSFetcher(ctrl *gomock.Controller) *MockJWKSFetcher {
+	mock := &MockJWKSFetcher{ctrl: ctrl}
+	mock.recorder = &MockJWKSFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJWKSFetcher) EXPECT() *MockJWKSFetcherMockRecorder {
+	return m.recorder
+}
+
+// Key mocks base method.
+func (m *MockJWKSFetcher) Key(ctx context.Context, kid string) (*Key, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Key", ctx, kid)
+	ret0, _ := ret[0].(*Key)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Key indicates an expected call of Key.
+func (mr *MockJWKSFetcherMockRecorder) Key(ctx, kid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Key", reflect.TypeOf((*MockJWKSFetcher)(nil).Key), ctx, kid)
+}
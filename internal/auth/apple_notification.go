@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+// Types of the Sign in with Apple server-to-server notifications.
+const (
+	AppleEventEmailDisabled  = "email-disabled"
+	AppleEventEmailEnabled   = "email-enabled"
+	AppleEventConsentRevoked = "consent-revoked"
+	AppleEventAccountDelete  = "account-delete"
+)
+
+// ParseAppleNotification verifies the payload of a server-to-server notification and returns its event.
+func ParseAppleNotification(ctx context.Context, keys JWKSFetcher, clientID string, payload string) (*domain.AppleNotificationEvent, error) {
+	claims, err := parseAppleToken(ctx, keys, clientID, payload, jwt.WithIssuedAt())
+	if err != nil {
+		return nil, err
+	}
+
+	/* The events claim is a JSON object encoded as a string */
+	var data []byte
+	switch events := claims["events"].(type) {
+	case string:
+		data = []byte(events)
+	case map[string]interface{}:
+		if data, err = json.Marshal(events); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidToken
+	}
+
+	event := &domain.AppleNotificationEvent{}
+	if err := json.Unmarshal(data, event); err != nil {
+		return nil, err
+	}
+
+	if event.Sub == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return event, nil
+}
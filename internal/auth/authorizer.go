@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/accesstoken"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+)
+
+// ErrInactiveSession is returned when the session of the token has been logged out or the user is not active anymore.
+var ErrInactiveSession = errors.New("inactive session")
+
+// Principal represents the user authenticated by the authorizer, through the session of an access token
+// or the scopes of a personal access token.
+type Principal struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	TokenID   uuid.UUID
+	// Scopes are only set for the personal access tokens, the sessions are allowed every route
+	Scopes []string
+}
+
+// IsAccessToken tells whether the principal has been authenticated by a personal access token.
+func (p *Principal) IsAccessToken() bool {
+	return p.TokenID != uuid.Nil
+}
+
+// Context returns the authorizer context of the principal, read by the functions with the utility helpers.
+func (p *Principal) Context() map[string]interface{} {
+	if p.IsAccessToken() {
+		return map[string]interface{}{
+			"userID":  p.UserID.String(),
+			"tokenID": p.TokenID.String(),
+			"scopes":  strings.Join(p.Scopes, " "),
+		}
+	}
+
+	return map[string]interface{}{
+		"userID":    p.UserID.String(),
+		"sessionID": p.SessionID.String(),
+	}
+}
+
+// Authorizer verifies the bearer tokens of the requests. It is used by the custom authorizer
+// and by the local tooling, so that local requests are authorized as in production.
+type Authorizer struct {
+	keyStore     KeyStore
+	jwtConfig    *config.AuthJwt
+	userService  user.Service
+	accessTokens accesstoken.Service
+	sessionCache *SessionCache
+}
+
+// NewAuthorizer creates a new authorizer.
+func NewAuthorizer(keyStore KeyStore, jwtConfig *config.AuthJwt, userService user.Service, accessTokens accesstoken.Service, sessionCache *SessionCache) *Authorizer {
+	return &Authorizer{
+		keyStore:     keyStore,
+		jwtConfig:    jwtConfig,
+		userService:  userService,
+		accessTokens: accessTokens,
+		sessionCache: sessionCache,
+	}
+}
+
+// NewContextAuthorizer creates the authorizer of the authentication context.
+func NewContextAuthorizer(ctx *Context, sessionCache *SessionCache) *Authorizer {
+	return NewAuthorizer(ctx.KeyStore, &ctx.Config.Auth.Jwt, ctx.UserService, accesstoken.NewService(ctx.DB, ctx.UserService), sessionCache)
+}
+
+// Authorize returns the principal of the token, a personal access token or the access token of an active session.
+func (a *Authorizer) Authorize(token string) (*Principal, error) {
+	// Personal access tokens are checked against their hash and only allowed the routes of their scopes
+	if accesstoken.IsAccessToken(token) {
+		principal, err := a.accessTokens.Authenticate(token)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Principal{UserID: principal.UserID, TokenID: principal.TokenID, Scopes: principal.Scopes}, nil
+	}
+
+	// Verify signature, issuer, audience and that the token is of access kind
+	claims, err := ParseToken(a.keyStore, a.jwtConfig, token, TokenKindAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	// Access tokens issued before the session claim are not accepted anymore
+	userGuid, _ := claims["sub"].(string)
+	sessionGuid, _ := claims["sid"].(string)
+
+	userID, err := uuid.Parse(userGuid)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	sessionID, err := uuid.Parse(sessionGuid)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Check the session has not been logged out and the user is still active
+	isActive, err := a.sessionCache.IsSessionActive(a.userService, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isActive {
+		return nil, ErrInactiveSession
+	}
+
+	return &Principal{UserID: userID, SessionID: sessionID}, nil
+}
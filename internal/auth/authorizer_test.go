@@ -0,0 +1,111 @@
+package auth_test
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/auth"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+)
+
+var _ = Describe("Authorizer", func() {
+	var (
+		keyStore    auth.KeyStore
+		userService *user.MockService
+		authorizer  *auth.Authorizer
+		userID      uuid.UUID
+		sessionID   uuid.UUID
+	)
+
+	jwtConfig := &config.AuthJwt{
+		Issuer:   "feynman-backend",
+		Audience: "feynman-app",
+	}
+
+	signToken := func(claims jwt.MapClaims) string {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+		claims["iat"] = time.Now().Unix()
+
+		token, err := auth.SignToken(keyStore, jwtConfig, claims)
+		Expect(err).To(BeNil())
+
+		return token
+	}
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		keyStore = newTestKeyStore("current")
+		userService = user.NewMockService(ctrl)
+		authorizer = auth.NewAuthorizer(keyStore, jwtConfig, userService, nil, auth.NewSessionCache(0))
+		userID = uuid.Must(uuid.NewRandom())
+		sessionID = uuid.Must(uuid.NewRandom())
+	})
+
+	It("should return the principal of the access token of an active session", func() {
+		// Arrange
+		token := signToken(jwt.MapClaims{"sub": userID, "sid": sessionID, "kind": auth.TokenKindAccess})
+		userService.EXPECT().IsSessionActive(userID, sessionID).Return(true, nil)
+
+		// Act
+		principal, err := authorizer.Authorize(token)
+
+		// Assert
+		Expect(err).To(BeNil())
+		Expect(principal.UserID).To(Equal(userID))
+		Expect(principal.SessionID).To(Equal(sessionID))
+		Expect(principal.IsAccessToken()).To(BeFalse())
+		Expect(principal.Context()).To(Equal(map[string]interface{}{
+			"userID":    userID.String(),
+			"sessionID": sessionID.String(),
+		}))
+	})
+
+	It("should reject the access tokens of a logged out session", func() {
+		// Arrange
+		token := signToken(jwt.MapClaims{"sub": userID, "sid": sessionID, "kind": auth.TokenKindAccess})
+		userService.EXPECT().IsSessionActive(userID, sessionID).Return(false, nil)
+
+		// Act
+		_, err := authorizer.Authorize(token)
+
+		// Assert
+		Expect(err).To(MatchError(auth.ErrInactiveSession))
+	})
+
+	It("should reject refresh tokens and tokens without session", func() {
+		// Arrange
+		refresh := signToken(jwt.MapClaims{"sub": userID, "sid": sessionID, "kind": auth.TokenKindRefresh})
+		withoutSession := signToken(jwt.MapClaims{"sub": userID, "kind": auth.TokenKindAccess})
+
+		// Act
+		_, refreshErr := authorizer.Authorize(refresh)
+		_, sessionErr := authorizer.Authorize(withoutSession)
+
+		// Assert
+		Expect(refreshErr).To(MatchError(auth.ErrInvalidToken))
+		Expect(sessionErr).To(MatchError(auth.ErrInvalidToken))
+	})
+
+	It("should set the scopes in the context of the personal access tokens", func() {
+		// Arrange
+		tokenID := uuid.Must(uuid.NewRandom())
+		principal := &auth.Principal{UserID: userID, TokenID: tokenID, Scopes: []string{"read", "export"}}
+
+		// Act
+		context := principal.Context()
+
+		// Assert
+		Expect(principal.IsAccessToken()).To(BeTrue())
+		Expect(context).To(Equal(map[string]interface{}{
+			"userID":  userID.String(),
+			"tokenID": tokenID.String(),
+			"scopes":  "read export",
+		}))
+	})
+})
@@ -5,6 +5,7 @@ import (
 
 	"github.com/pietro-putelli/feynman-backend/config"
 	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/mailer"
 	"github.com/pietro-putelli/feynman-backend/internal/user"
 	"gorm.io/gorm"
 )
@@ -13,6 +14,10 @@ import (
 type Context struct {
 	Service         Service
 	UserService     user.Service
+	KeyStore        KeyStore
+	AppleKeys       JWKSFetcher
+	EmailCodes      EmailCodeStore
+	Mailer          mailer.Mailer
 	Config          *config.Config
 	DB              *gorm.DB
 	ProviderHandler ProviderHandler
@@ -23,6 +28,8 @@ func NewContext(cfg *config.Config, db *gorm.DB, providerHandler ProviderHandler
 	return &Context{
 		Service:         service,
 		UserService:     user.NewService(db),
+		AppleKeys:       defaultAppleKeys,
+		EmailCodes:      NewEmailCodeStore(db),
 		Config:          cfg,
 		DB:              db,
 		ProviderHandler: providerHandler,
@@ -43,8 +50,28 @@ func NewBaseContext() (*Context, error) {
 		return nil, errors.New("failed load auth context database: " + err.Error())
 	}
 
+	// signing and verification keys
+	keyStore, err := NewKeyStore(&config.Auth.Jwt)
+	if err != nil {
+		return nil, errors.New("failed load auth context keys: " + err.Error())
+	}
+
 	// user service
 	userService := user.NewService(database)
 
-	return NewContext(config, database, NewProvider, NewService(&config.Auth.Jwt, userService)), nil
+	emailCodes := NewEmailCodeStore(database)
+
+	context := NewContext(config, database, NewProviderHandler(emailCodes), NewService(&config.Auth.Jwt, keyStore, userService))
+	context.KeyStore = keyStore
+
+	// mailer of the email provider, only when a SMTP server is configured
+	if config.Smtp.Host != "" {
+		smtpMailer, err := mailer.NewSMTPMailer(config.Smtp)
+		if err != nil {
+			return nil, errors.New("failed load auth context mailer: " + err.Error())
+		}
+		context.Mailer = smtpMailer
+	}
+
+	return context, nil
 }
@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"context"
+
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/pietro-putelli/feynman-backend/internal/domain"
 	"github.com/pietro-putelli/feynman-backend/internal/failure"
 )
@@ -36,7 +39,7 @@ func HandleAuthToken(ctx *Context, body *domain.AuthTokenBody) (*domain.AuthUser
 	}
 
 	// Generate tokens
-	authToken, err := ctx.Service.GenerateToken(user)
+	authToken, err := ctx.Service.GenerateToken(user, sessionID)
 	if err != nil {
 		return nil, err
 	}
@@ -59,3 +62,82 @@ func HandleRefreshToken(ctx *Context, refreshToken string) (*domain.AuthTokenDto
 
 	return authToken, nil
 }
+
+type LinkIdentityHandler func(*Context, uuid.UUID, *domain.LinkIdentityBody) (*domain.UserIdentityResponse, error)
+
+// HandleLinkIdentity links the provider account of the sign in token to the authenticated user.
+func HandleLinkIdentity(ctx *Context, userID uuid.UUID, body *domain.LinkIdentityBody) (*domain.UserIdentityResponse, error) {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
+	// Validate the request body
+	if err := validate.Struct(body); err != nil {
+		return nil, failure.NewValidationErr(err)
+	}
+
+	// Get authentication provider
+	provider, err := ctx.ProviderHandler(body.Provider, &ctx.Config.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validating the token, no session is created so the device is left empty
+	thirdPartyUser, err := provider.ValidateToken(&domain.AuthTokenBody{
+		Token:    body.Token,
+		IDToken:  body.IDToken,
+		Provider: body.Provider,
+		Email:    body.Email,
+		Data:     body.Data,
+		Device:   &domain.AuthTokenDevice{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx.UserService.LinkIdentity(userID, thirdPartyUser)
+}
+
+type AppleNotificationHandler func(*Context, *domain.AppleNotificationBody) error
+
+// HandleAppleNotification handles the Sign in with Apple server-to-server notifications.
+func HandleAppleNotification(ctx *Context, body *domain.AppleNotificationBody) error {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
+	// Validate the request body
+	if err := validate.Struct(body); err != nil {
+		return failure.NewValidationErr(err)
+	}
+
+	// Verify the signed event
+	event, err := ParseAppleNotification(context.Background(), ctx.AppleKeys, ctx.Config.Auth.Apple.AppBundleId, body.Payload)
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case AppleEventConsentRevoked:
+		return ctx.UserService.RevokeIdentitySessions(Apple, event.Sub)
+	case AppleEventAccountDelete:
+		return ctx.UserService.DeleteIdentity(Apple, event.Sub)
+	case AppleEventEmailEnabled:
+		return ctx.UserService.SetIdentityEmailEnabled(Apple, event.Sub, event.Email, true)
+	case AppleEventEmailDisabled:
+		return ctx.UserService.SetIdentityEmailEnabled(Apple, event.Sub, event.Email, false)
+	default:
+		// Unknown events are acknowledged so that Apple does not retry them
+		return nil
+	}
+}
+
+type EmailLoginHandler func(*Context, *domain.EmailLoginBody, string) error
+
+// HandleEmailLogin sends a sign in code to the email, the code is then exchanged with the email provider.
+func HandleEmailLogin(ctx *Context, body *domain.EmailLoginBody, ip string) error {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
+	// Validate the request body
+	if err := validate.Struct(body); err != nil {
+		return failure.NewValidationErr(err)
+	}
+
+	return SendEmailLogin(context.Background(), ctx.EmailCodes, ctx.Mailer, &ctx.Config.Auth.Email, body.Email, ip)
+}
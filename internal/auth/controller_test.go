@@ -12,6 +12,7 @@ import (
 	"github.com/pietro-putelli/feynman-backend/config"
 	"github.com/pietro-putelli/feynman-backend/internal/auth"
 	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
 )
 
 var _ = Describe("Auth Controller", func() {
@@ -36,12 +37,13 @@ var _ = Describe("Auth Controller", func() {
 			},
 		}
 
-		Context("when provided auth provider is not valid", func() {
+		Context("when provided auth provider is not supported", func() {
 			It("should return an error", func() {
 				// Arrange
 				body := &domain.AuthTokenBody{
 					Token:    "example-token",
-					Provider: "not-supported-provider",
+					Provider: "google",
+					Device:   &domain.AuthTokenDevice{ID: "device-id"},
 				}
 
 				providerHandler := func(provider string, config *config.Auth) (auth.ThirdPartyProvider, error) {
@@ -65,11 +67,12 @@ var _ = Describe("Auth Controller", func() {
 				// Arrange
 				body := &domain.AuthTokenBody{
 					Token:    "example-token",
-					Provider: "example-provider",
+					Provider: "google",
+					Device:   &domain.AuthTokenDevice{ID: "device-id"},
 				}
 
 				mockThirdPartyProvider := auth.NewMockThirdPartyProvider(ctrl)
-				mockThirdPartyProvider.EXPECT().ValidateToken(body.Token).Return(nil, errors.New("invalid token"))
+				mockThirdPartyProvider.EXPECT().ValidateToken(body).Return(nil, errors.New("invalid token"))
 
 				providerHandler := func(provider string, config *config.Auth) (auth.ThirdPartyProvider, error) {
 					return mockThirdPartyProvider, nil
@@ -92,7 +95,8 @@ var _ = Describe("Auth Controller", func() {
 				// Arrange
 				body := &domain.AuthTokenBody{
 					Token:    "example-token",
-					Provider: "example-provider",
+					Provider: "google",
+					Device:   &domain.AuthTokenDevice{ID: "device-id"},
 				}
 				thirdPartyUser := &domain.ThirdPartyUser{
 					Email:      "test@gmail.com",
@@ -103,10 +107,10 @@ var _ = Describe("Auth Controller", func() {
 				}
 
 				mockThirdPartyProvider := auth.NewMockThirdPartyProvider(ctrl)
-				mockThirdPartyProvider.EXPECT().ValidateToken(body.Token).Return(thirdPartyUser, nil)
+				mockThirdPartyProvider.EXPECT().ValidateToken(body).Return(thirdPartyUser, nil)
 
 				mockService := auth.NewMockService(ctrl)
-				mockService.EXPECT().CreateUserIfNotExists(thirdPartyUser).Return(nil, errors.New("failed to create user"))
+				mockService.EXPECT().CreateUserIfNotExists(thirdPartyUser).Return(nil, uuid.Nil, errors.New("failed to create user"))
 
 				providerHandler := func(provider string, config *config.Auth) (auth.ThirdPartyProvider, error) {
 					return mockThirdPartyProvider, nil
@@ -129,7 +133,8 @@ var _ = Describe("Auth Controller", func() {
 				// Arrange
 				body := &domain.AuthTokenBody{
 					Token:    "example-token",
-					Provider: "example-provider",
+					Provider: "google",
+					Device:   &domain.AuthTokenDevice{ID: "device-id"},
 				}
 				thirdPartyUser := &domain.ThirdPartyUser{
 					Email:      "test@gmail.com",
@@ -147,13 +152,14 @@ var _ = Describe("Auth Controller", func() {
 					Settings:   domain.NewUserSettings(),
 					FamilyName: thirdPartyUser.FamilyName,
 				}
+				sessionID := uuid.Must(uuid.NewRandom())
 
 				mockThirdPartyProvider := auth.NewMockThirdPartyProvider(ctrl)
-				mockThirdPartyProvider.EXPECT().ValidateToken(body.Token).Return(thirdPartyUser, nil)
+				mockThirdPartyProvider.EXPECT().ValidateToken(body).Return(thirdPartyUser, nil)
 
 				mockService := auth.NewMockService(ctrl)
-				mockService.EXPECT().CreateUserIfNotExists(thirdPartyUser).Return(user, nil)
-				mockService.EXPECT().GenerateToken(user).Return(nil, errors.New("failed to generate token"))
+				mockService.EXPECT().CreateUserIfNotExists(thirdPartyUser).Return(user, sessionID, nil)
+				mockService.EXPECT().GenerateToken(user, sessionID).Return(nil, errors.New("failed to generate token"))
 
 				providerHandler := func(provider string, config *config.Auth) (auth.ThirdPartyProvider, error) {
 					return mockThirdPartyProvider, nil
@@ -176,7 +182,8 @@ var _ = Describe("Auth Controller", func() {
 				// Arrange
 				body := &domain.AuthTokenBody{
 					Token:    "example-token",
-					Provider: "example-provider",
+					Provider: "google",
+					Device:   &domain.AuthTokenDevice{ID: "device-id"},
 				}
 				thirdPartyUser := &domain.ThirdPartyUser{
 					Email:      "test@gmail.com",
@@ -194,6 +201,7 @@ var _ = Describe("Auth Controller", func() {
 					Settings:   domain.NewUserSettings(),
 					FamilyName: thirdPartyUser.FamilyName,
 				}
+				sessionID := uuid.Must(uuid.NewRandom())
 				authToken := &domain.AuthTokenDto{
 					AccessToken:          "access-token",
 					RefreshToken:         "refresh-token",
@@ -201,11 +209,11 @@ var _ = Describe("Auth Controller", func() {
 				}
 
 				mockThirdPartyProvider := auth.NewMockThirdPartyProvider(ctrl)
-				mockThirdPartyProvider.EXPECT().ValidateToken(body.Token).Return(thirdPartyUser, nil)
+				mockThirdPartyProvider.EXPECT().ValidateToken(body).Return(thirdPartyUser, nil)
 
 				mockService := auth.NewMockService(ctrl)
-				mockService.EXPECT().CreateUserIfNotExists(thirdPartyUser).Return(user, nil)
-				mockService.EXPECT().GenerateToken(user).Return(authToken, nil)
+				mockService.EXPECT().CreateUserIfNotExists(thirdPartyUser).Return(user, sessionID, nil)
+				mockService.EXPECT().GenerateToken(user, sessionID).Return(authToken, nil)
 
 				providerHandler := func(provider string, config *config.Auth) (auth.ThirdPartyProvider, error) {
 					return mockThirdPartyProvider, nil
@@ -230,4 +238,70 @@ var _ = Describe("Auth Controller", func() {
 			})
 		})
 	})
+
+	Describe("HandleLinkIdentity", func() {
+		cfg := &config.Config{}
+		userID := uuid.Must(uuid.NewRandom())
+
+		body := &domain.LinkIdentityBody{
+			Token:    "example-token",
+			Provider: "google",
+		}
+
+		thirdPartyUser := &domain.ThirdPartyUser{
+			Sub:      "google-sub",
+			Email:    "user@example.com",
+			Provider: "google",
+		}
+
+		newContext := func(userService user.Service) *auth.Context {
+			mockThirdPartyProvider := auth.NewMockThirdPartyProvider(ctrl)
+			mockThirdPartyProvider.EXPECT().ValidateToken(gomock.Any()).Return(thirdPartyUser, nil)
+
+			providerHandler := func(provider string, config *config.Auth) (auth.ThirdPartyProvider, error) {
+				return mockThirdPartyProvider, nil
+			}
+
+			ctx := auth.NewContext(cfg, nil, providerHandler, nil)
+			ctx.UserService = userService
+			return ctx
+		}
+
+		It("should link the identity of the token to the user", func() {
+			// Arrange
+			identity := &domain.UserIdentityResponse{Provider: "google", Email: thirdPartyUser.Email}
+
+			userService := user.NewMockService(ctrl)
+			userService.EXPECT().LinkIdentity(userID, thirdPartyUser).Return(identity, nil)
+
+			// Act
+			result, err := auth.HandleLinkIdentity(newContext(userService), userID, body)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(result).To(Equal(identity))
+		})
+
+		It("should return the conflict when the identity belongs to another user", func() {
+			// Arrange
+			userService := user.NewMockService(ctrl)
+			userService.EXPECT().LinkIdentity(userID, thirdPartyUser).Return(nil, user.ErrIdentityLinkedToAnotherUser)
+
+			// Act
+			result, err := auth.HandleLinkIdentity(newContext(userService), userID, body)
+
+			// Assert
+			Expect(result).To(BeNil())
+			Expect(err).To(MatchError(user.ErrIdentityLinkedToAnotherUser))
+		})
+
+		It("should fail validation without a token", func() {
+			// Act
+			result, err := auth.HandleLinkIdentity(auth.NewContext(cfg, nil, nil, nil), userID, &domain.LinkIdentityBody{Provider: "google"})
+
+			// Assert
+			Expect(result).To(BeNil())
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EmailCodeStore stores the sign in codes of the email provider.
+//
+//go:generate mockgen -source=email_codes.go -destination=./email_codes_mock.go -package=auth
+type EmailCodeStore interface {
+	CountCodesByEmail(email string, since time.Time) (int64, error)
+	CountCodesByIP(ip string, since time.Time) (int64, error)
+	CreateCode(code *domain.EmailLoginCode) error
+	// ConsumeCode uses the last code sent to the email when the hash matches its code or its link token,
+	// ErrInvalidToken is returned otherwise and the code is burned after maxAttempts wrong guesses.
+	ConsumeCode(email string, tokenHash string, maxAttempts int) error
+}
+
+// emailCodeStore represents the database email code store.
+type emailCodeStore struct {
+	db *gorm.DB
+}
+
+// NewEmailCodeStore creates a new email code store.
+func NewEmailCodeStore(db *gorm.DB) EmailCodeStore {
+	return &emailCodeStore{
+		db: db,
+	}
+}
+
+func (s *emailCodeStore) CountCodesByEmail(email string, since time.Time) (int64, error) {
+	var count int64
+	err := s.db.Model(&domain.EmailLoginCode{}).Where("email = ? AND created_at > ?", email, since).Count(&count).Error
+	return count, err
+}
+
+func (s *emailCodeStore) CountCodesByIP(ip string, since time.Time) (int64, error) {
+	var count int64
+	err := s.db.Model(&domain.EmailLoginCode{}).Where("ip = ? AND created_at > ?", ip, since).Count(&count).Error
+	return count, err
+}
+
+func (s *emailCodeStore) CreateCode(code *domain.EmailLoginCode) error {
+	return s.db.Create(code).Error
+}
+
+func (s *emailCodeStore) ConsumeCode(email string, tokenHash string, maxAttempts int) error {
+	isValid := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		code := domain.EmailLoginCode{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("email = ? AND used_at IS NULL AND expires_at > ?", email, now).
+			Order("created_at DESC").
+			First(&code).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		isValid = subtle.ConstantTimeCompare([]byte(code.CodeHash), []byte(tokenHash)) == 1 ||
+			subtle.ConstantTimeCompare([]byte(code.LinkTokenHash), []byte(tokenHash)) == 1
+
+		if isValid {
+			return tx.Model(&domain.EmailLoginCode{}).Where("id = ?", code.ID).Update("used_at", now).Error
+		}
+
+		/* The wrong guesses are committed, the code is burned once they reach the maximum */
+		updates := map[string]interface{}{"attempts": gorm.Expr("attempts + 1")}
+		if code.Attempts+1 >= maxAttempts {
+			updates["used_at"] = now
+		}
+
+		return tx.Model(&domain.EmailLoginCode{}).Where("id = ?", code.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if !isValid {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: email_codes.go
+//
+// Generated by this command:
+//
+//	mockgen -source=email_codes.go -destination=./email_codes_mock.go -package=auth
+//
+
+// Package auth is a generated GoMock package.
+package auth
+
+import (
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/pietro-putelli/feynman-backend/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockEmailCodeStore is a mock of EmailCodeStore interface.
+type MockEmailCodeStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockEmailCodeStoreMockRecorder
+}
+
+// MockEmailCodeStoreMockRecorder is the mock recorder for MockEmailCodeStore.
+type MockEmailCodeStoreMockRecorder struct {
+	mock *MockEmailCodeStore
+}
+
+// NewMockEmailCodeStore creates a new mock instance.
+func NewMockEmailCodeStore(ctrl *gomock.Controller) *MockEmailCodeStore {
+	mock := &MockEmailCodeStore{ctrl: ctrl}
+	mock.recorder = &MockEmailCodeStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEmailCodeStore) EXPECT() *MockEmailCodeStoreMockRecorder {
+	return m.recorder
+}
+
+// ConsumeCode mocks base method.
+func (m *MockEmailCodeStore) ConsumeCode(email, tokenHash string, maxAttempts int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeCode", email, tokenHash, maxAttempts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConsumeCode indicates an expected call of ConsumeCode.
+func (mr *MockEmailCodeStoreMockRecorder) ConsumeCode(email, tokenHash, maxAttempts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeCode", reflect.TypeOf((*MockEmailCodeStore)(nil).ConsumeCode), email, tokenHash, maxAttempts)
+}
+
+// CountCodesByEmail mocks base method.
+func (m *MockEmailCodeStore) CountCodesByEmail(email string, since time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountCodesByEmail", email, since)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountCodesByEmail indicates an expected call of CountCodesByEmail.
+func (mr *MockEmailCodeStoreMockRecorder) CountCodesByEmail(email, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountCodesByEmail", reflect.TypeOf((*MockEmailCodeStore)(nil).CountCodesByEmail), email, since)
+}
+
+// CountCodesByIP mocks base method.
+func (m *MockEmailCodeStore) CountCodesByIP(ip string, since time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountCodesByIP", ip, since)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountCodesByIP indicates an expected call of CountCodesByIP.
+func (mr *MockEmailCodeStoreMockRecorder) CountCodesByIP(ip, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountCodesByIP", reflect.TypeOf((*MockEmailCodeStore)(nil).CountCodesByIP), ip, since)
+}
+
+// CreateCode mocks base method.
+func (m *MockEmailCodeStore) CreateCode(code *domain.EmailLoginCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCode", code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateCode indicates an expected call of CreateCode.
+func (mr *MockEmailCodeStoreMockRecorder) CreateCode(code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCode", reflect.TypeOf((*MockEmailCodeStore)(nil).CreateCode), code)
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/mailer"
+)
+
+var (
+	// ErrTooManyEmailCodes is returned when too many sign in codes were requested for the email or from the IP.
+	ErrTooManyEmailCodes = errors.New("too many sign in codes requested")
+
+	// ErrMailerNotConfigured is returned when no mailer is available to send the sign in codes.
+	ErrMailerNotConfigured = errors.New("mailer not configured")
+
+	// ErrEmailCodeSecretNotConfigured is returned when there is no secret to hash the sign in codes.
+	ErrEmailCodeSecretNotConfigured = errors.New("email code secret not configured")
+)
+
+// SendEmailLogin issues a single-use sign in code and link for the email and sends them,
+// the requests are rate limited per email and per IP.
+func SendEmailLogin(ctx context.Context, codes EmailCodeStore, m mailer.Mailer, emailConfig *config.AuthEmail, email string, ip string) error {
+	if m == nil {
+		return ErrMailerNotConfigured
+	}
+
+	if emailConfig.CodeSecret == "" {
+		return ErrEmailCodeSecretNotConfigured
+	}
+
+	email = normalizeEmail(email)
+	since := time.Now().Add(-time.Duration(emailConfig.RateLimitWindow) * time.Minute)
+
+	byEmail, err := codes.CountCodesByEmail(email, since)
+	if err != nil {
+		return err
+	}
+
+	byIP, err := codes.CountCodesByIP(ip, since)
+	if err != nil {
+		return err
+	}
+
+	if byEmail >= int64(emailConfig.MaxCodesPerEmail) || byIP >= int64(emailConfig.MaxCodesPerIP) {
+		return ErrTooManyEmailCodes
+	}
+
+	code, err := newEmailCode()
+	if err != nil {
+		return err
+	}
+
+	linkToken, err := newLinkToken()
+	if err != nil {
+		return err
+	}
+
+	if err := codes.CreateCode(&domain.EmailLoginCode{
+		Email:         email,
+		IP:            ip,
+		CodeHash:      hashEmailCode(emailConfig.CodeSecret, code),
+		LinkTokenHash: hashEmailCode(emailConfig.CodeSecret, linkToken),
+		ExpiresAt:     time.Now().Add(time.Duration(emailConfig.CodeDuration) * time.Minute),
+	}); err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("Your Feynman sign in code is %s, it expires in %d minutes.\n", code, emailConfig.CodeDuration)
+	if emailConfig.LinkURL != "" {
+		link := emailConfig.LinkURL + "?" + url.Values{"email": {email}, "token": {linkToken}}.Encode()
+		text += fmt.Sprintf("\nOr sign in with this link: %s\n", link)
+	}
+	text += "\nIf you did not request it, you can ignore this email.\n"
+
+	return m.Send(ctx, &mailer.Message{
+		To:      email,
+		Subject: "Your Feynman sign in code",
+		Text:    text,
+	})
+}
+
+/* The codes have only a million values, a plain hash of them would be reversed from a dump of the database */
+func hashEmailCode(secret string, code string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(code))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+/* Six digits code, easy to type on another device */
+func newEmailCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+/* Random token of the sign in link, too long to be guessed */
+func newLinkToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
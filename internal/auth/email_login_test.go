@@ -0,0 +1,143 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/auth"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/mailer"
+)
+
+func hmacHex(secret string, value string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var _ = Describe("Email Login", func() {
+	var (
+		codes      *auth.MockEmailCodeStore
+		mail       *mailer.MemoryMailer
+		authConfig *config.Auth
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		codes = auth.NewMockEmailCodeStore(ctrl)
+		mail = mailer.NewMemoryMailer()
+		authConfig = &config.Auth{
+			Email: config.AuthEmail{
+				CodeSecret:       "code-secret",
+				LinkURL:          "https://feynman.app/auth/email",
+				CodeDuration:     10,
+				MaxAttempts:      5,
+				MaxCodesPerEmail: 5,
+				MaxCodesPerIP:    20,
+				RateLimitWindow:  60,
+			},
+		}
+	})
+
+	Describe("SendEmailLogin", func() {
+		It("should store the hashes of the code and link sent by email", func() {
+			// Arrange
+			var stored *domain.EmailLoginCode
+			codes.EXPECT().CountCodesByEmail("user@example.com", gomock.Any()).Return(int64(0), nil)
+			codes.EXPECT().CountCodesByIP("1.2.3.4", gomock.Any()).Return(int64(0), nil)
+			codes.EXPECT().CreateCode(gomock.Any()).DoAndReturn(func(code *domain.EmailLoginCode) error {
+				stored = code
+				return nil
+			})
+
+			// Act
+			err := auth.SendEmailLogin(context.Background(), codes, mail, &authConfig.Email, " User@Example.com", "1.2.3.4")
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(mail.Messages()).To(HaveLen(1))
+
+			message := mail.Messages()[0]
+			Expect(message.To).To(Equal("user@example.com"))
+
+			code := regexp.MustCompile(`code is (\d{6})`).FindStringSubmatch(message.Text)
+			Expect(code).To(HaveLen(2))
+			Expect(stored.Email).To(Equal("user@example.com"))
+			Expect(stored.CodeHash).To(Equal(hmacHex("code-secret", code[1])))
+			Expect(stored.CodeHash).NotTo(Equal(hmacHex("another-secret", code[1])))
+			Expect(message.Text).To(ContainSubstring("https://feynman.app/auth/email?email=user%40example.com&token="))
+			Expect(message.Text).NotTo(ContainSubstring(stored.LinkTokenHash))
+		})
+
+		It("should refuse to send more codes than allowed per IP", func() {
+			// Arrange
+			codes.EXPECT().CountCodesByEmail(gomock.Any(), gomock.Any()).Return(int64(0), nil)
+			codes.EXPECT().CountCodesByIP("1.2.3.4", gomock.Any()).Return(int64(20), nil)
+
+			// Act
+			err := auth.SendEmailLogin(context.Background(), codes, mail, &authConfig.Email, "user@example.com", "1.2.3.4")
+
+			// Assert
+			Expect(err).To(MatchError(auth.ErrTooManyEmailCodes))
+			Expect(mail.Messages()).To(BeEmpty())
+		})
+		It("should refuse to send codes without the secret to hash them", func() {
+			// Arrange
+			authConfig.Email.CodeSecret = ""
+
+			// Act
+			err := auth.SendEmailLogin(context.Background(), codes, mail, &authConfig.Email, "user@example.com", "1.2.3.4")
+
+			// Assert
+			Expect(err).To(MatchError(auth.ErrEmailCodeSecretNotConfigured))
+			Expect(mail.Messages()).To(BeEmpty())
+		})
+	})
+
+	Describe("EmailProvider", func() {
+		body := &domain.AuthTokenBody{
+			Token:    " 123456 ",
+			Provider: "email",
+			Email:    "User@Example.com",
+			Device:   &domain.AuthTokenDevice{ID: "device-id"},
+		}
+
+		It("should return the user of the email when the code is valid", func() {
+			// Arrange
+			codes.EXPECT().ConsumeCode("user@example.com", hmacHex("code-secret", "123456"), 5).Return(nil)
+
+			provider, err := auth.NewProviderHandler(codes)("email", authConfig)
+			Expect(err).To(BeNil())
+
+			// Act
+			result, err := provider.ValidateToken(body)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(result.Provider).To(Equal("email"))
+			Expect(result.Sub).To(Equal("user@example.com"))
+			Expect(result.Email).To(Equal("user@example.com"))
+			Expect(result.DeviceID).To(Equal("device-id"))
+		})
+
+		It("should fail when the code is not valid", func() {
+			// Arrange
+			codes.EXPECT().ConsumeCode("user@example.com", hmacHex("code-secret", "123456"), 5).Return(auth.ErrInvalidToken)
+
+			// Act
+			result, err := auth.NewEmailProvider(codes, authConfig.Email).ValidateToken(body)
+
+			// Assert
+			Expect(result).To(BeNil())
+			Expect(err).To(MatchError(auth.ErrInvalidToken))
+		})
+	})
+})
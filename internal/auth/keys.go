@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+// Key represents a key used to sign or verify tokens, identified by the kid header.
+type Key struct {
+	ID        string
+	Algorithm string
+	PublicKey crypto.PublicKey
+	// PrivateKey is nil for the keys only used to verify tokens
+	PrivateKey crypto.Signer
+}
+
+// KeyStore provides the keys of the tokens.
+//
+// During a rotation the new key signs the tokens while the previous ones keep verifying
+// the tokens already issued, until they expire.
+type KeyStore interface {
+	// SigningKey returns the key used to sign new tokens.
+	SigningKey() (*Key, error)
+	// VerificationKeys returns the keys accepted when validating tokens, the signing key included.
+	VerificationKeys() ([]*Key, error)
+}
+
+// NewKey creates a key from a RSA or Ed25519 key, private or public, the algorithm is inferred from its type.
+func NewKey(id string, key interface{}) (*Key, error) {
+	if id == "" {
+		return nil, errors.New("key id is required")
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, Algorithm: jwt.SigningMethodRS256.Alg(), PublicKey: &k.PublicKey, PrivateKey: k}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: id, Algorithm: jwt.SigningMethodRS256.Alg(), PublicKey: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Algorithm: jwt.SigningMethodEdDSA.Alg(), PublicKey: k.Public(), PrivateKey: k}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Algorithm: jwt.SigningMethodEdDSA.Alg(), PublicKey: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// ParseKeyPEM parses a PKCS#8, PKCS#1 or PKIX PEM encoded key.
+func ParseKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s is not PEM encoded", id)
+	}
+
+	var key interface{}
+	var err error
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %s has unsupported PEM type %s", id, block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKey(id, key)
+}
+
+//-------------------------------------
+// Key Stores
+//-------------------------------------
+
+// staticKeyStore represents a key store with a fixed set of keys.
+type staticKeyStore struct {
+	signingKey *Key
+	keys       []*Key
+}
+
+// NewStaticKeyStore creates a key store signing with the given key, the previous keys are only used to verify.
+func NewStaticKeyStore(signingKey *Key, previousKeys ...*Key) (KeyStore, error) {
+	if signingKey == nil || signingKey.PrivateKey == nil {
+		return nil, errors.New("signing key must be a private key")
+	}
+
+	return &staticKeyStore{
+		signingKey: signingKey,
+		keys:       append([]*Key{signingKey}, previousKeys...),
+	}, nil
+}
+
+func (s *staticKeyStore) SigningKey() (*Key, error) {
+	return s.signingKey, nil
+}
+
+func (s *staticKeyStore) VerificationKeys() ([]*Key, error) {
+	return s.keys, nil
+}
+
+// NewConfigKeyStore creates a key store from the configuration: the PEM private key signs
+// and the previous public keys, given as a JWKS document, are only used to verify.
+func NewConfigKeyStore(jwtConfig *config.AuthJwt) (KeyStore, error) {
+	signingKey, err := ParseKeyPEM(jwtConfig.KeyID, []byte(jwtConfig.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	previousKeys := []*Key{}
+	if jwtConfig.PreviousKeys != "" {
+		set := domain.JWKSet{}
+		if err := json.Unmarshal([]byte(jwtConfig.PreviousKeys), &set); err != nil {
+			return nil, fmt.Errorf("invalid previous keys: %w", err)
+		}
+
+		for _, jwk := range set.Keys {
+			key, err := KeyFromJWK(jwk)
+			if err != nil {
+				return nil, err
+			}
+			previousKeys = append(previousKeys, key)
+		}
+	}
+
+	return NewStaticKeyStore(signingKey, previousKeys...)
+}
+
+// NewFileKeyStore creates a key store from a directory of PEM files named after their key ID, e.g. 2024-06.pem.
+// It is meant for local use: the key with the given ID signs, all the others are only used to verify.
+func NewFileKeyStore(dir string, signingKeyID string) (KeyStore, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	var signingKey *Key
+	previousKeys := []*Key{}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := ParseKeyPEM(strings.TrimSuffix(filepath.Base(path), ".pem"), data)
+		if err != nil {
+			return nil, err
+		}
+
+		if key.ID == signingKeyID {
+			signingKey = key
+		} else {
+			previousKeys = append(previousKeys, key)
+		}
+	}
+
+	if signingKey == nil {
+		return nil, fmt.Errorf("signing key %s not found in %s", signingKeyID, dir)
+	}
+
+	return NewStaticKeyStore(signingKey, previousKeys...)
+}
+
+// NewKeyStore creates the key store of the configuration, the file based one when a keys directory is set.
+func NewKeyStore(jwtConfig *config.AuthJwt) (KeyStore, error) {
+	if jwtConfig.KeysDir != "" {
+		return NewFileKeyStore(jwtConfig.KeysDir, jwtConfig.KeyID)
+	}
+
+	return NewConfigKeyStore(jwtConfig)
+}
+
+//-------------------------------------
+// JWKS
+//-------------------------------------
+
+// JWKFromKey returns the public JSON Web Key of the key.
+func JWKFromKey(key *Key) (domain.JWK, error) {
+	jwk := domain.JWK{
+		Kid: key.ID,
+		Use: "sig",
+		Alg: key.Algorithm,
+	}
+
+	switch k := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(k)
+	default:
+		return jwk, fmt.Errorf("unsupported key type %T", key.PublicKey)
+	}
+
+	return jwk, nil
+}
+
+// KeyFromJWK returns the verification key of a public JSON Web Key.
+func KeyFromJWK(jwk domain.JWK) (*Key, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey(jwk.Kid, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())})
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return NewKey(jwk.Kid, ed25519.PublicKey(x))
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+	}
+}
+
+// NewJWKSet returns the JWKS document of the verification keys of the store.
+func NewJWKSet(keyStore KeyStore) (*domain.JWKSet, error) {
+	keys, err := keyStore.VerificationKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	set := &domain.JWKSet{Keys: []domain.JWK{}}
+	for _, key := range keys {
+		jwk, err := JWKFromKey(key)
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set, nil
+}
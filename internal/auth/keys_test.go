@@ -0,0 +1,217 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/auth"
+)
+
+/* Key store signing with a new Ed25519 key */
+func newTestKeyStore(id string) auth.KeyStore {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).To(BeNil())
+
+	key, err := auth.NewKey(id, privateKey)
+	Expect(err).To(BeNil())
+
+	keyStore, err := auth.NewStaticKeyStore(key)
+	Expect(err).To(BeNil())
+
+	return keyStore
+}
+
+func encodePEM(blockType string, bytes []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: bytes})
+}
+
+var _ = Describe("Auth Keys", func() {
+	jwtConfig := &config.AuthJwt{
+		Issuer:   "feynman-backend",
+		Audience: "feynman-app",
+	}
+
+	claims := func(kind string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":  "user",
+			"exp":  time.Now().Add(time.Hour).Unix(),
+			"iat":  time.Now().Unix(),
+			"kind": kind,
+		}
+	}
+
+	Describe("NewKey", func() {
+		It("should infer the algorithm from the key type", func() {
+			// Arrange
+			rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).To(BeNil())
+			_, edKey, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).To(BeNil())
+
+			// Act
+			rsaResult, rsaErr := auth.NewKey("rsa", rsaKey)
+			edResult, edErr := auth.NewKey("ed", edKey.Public())
+
+			// Assert
+			Expect(rsaErr).To(BeNil())
+			Expect(rsaResult.Algorithm).To(Equal("RS256"))
+			Expect(rsaResult.PrivateKey).NotTo(BeNil())
+			Expect(edErr).To(BeNil())
+			Expect(edResult.Algorithm).To(Equal("EdDSA"))
+			Expect(edResult.PrivateKey).To(BeNil())
+		})
+	})
+
+	Describe("JWK", func() {
+		It("should convert RSA and Ed25519 keys back and forth", func() {
+			// Arrange
+			rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).To(BeNil())
+			edPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).To(BeNil())
+
+			keys := []interface{}{&rsaKey.PublicKey, edPublicKey}
+
+			for _, publicKey := range keys {
+				key, err := auth.NewKey("kid", publicKey)
+				Expect(err).To(BeNil())
+
+				// Act
+				jwk, err := auth.JWKFromKey(key)
+				Expect(err).To(BeNil())
+				result, err := auth.KeyFromJWK(jwk)
+
+				// Assert
+				Expect(err).To(BeNil())
+				Expect(jwk.Kid).To(Equal("kid"))
+				Expect(jwk.Use).To(Equal("sig"))
+				Expect(result.Algorithm).To(Equal(key.Algorithm))
+				Expect(result.PublicKey).To(Equal(key.PublicKey))
+			}
+		})
+	})
+
+	Describe("NewFileKeyStore", func() {
+		It("should sign with the key of the given ID and verify with all of them", func() {
+			// Arrange
+			dir := GinkgoT().TempDir()
+
+			rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).To(BeNil())
+			Expect(os.WriteFile(filepath.Join(dir, "previous.pem"), encodePEM("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), 0600)).To(Succeed())
+
+			_, edKey, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).To(BeNil())
+			edBytes, err := x509.MarshalPKCS8PrivateKey(edKey)
+			Expect(err).To(BeNil())
+			Expect(os.WriteFile(filepath.Join(dir, "current.pem"), encodePEM("PRIVATE KEY", edBytes), 0600)).To(Succeed())
+
+			// Act
+			keyStore, err := auth.NewFileKeyStore(dir, "current")
+
+			// Assert
+			Expect(err).To(BeNil())
+			signingKey, err := keyStore.SigningKey()
+			Expect(err).To(BeNil())
+			Expect(signingKey.ID).To(Equal("current"))
+			Expect(signingKey.Algorithm).To(Equal("EdDSA"))
+
+			set, err := auth.NewJWKSet(keyStore)
+			Expect(err).To(BeNil())
+			Expect(set.Keys).To(HaveLen(2))
+		})
+
+		It("should fail when the signing key is missing", func() {
+			// Act
+			_, err := auth.NewFileKeyStore(GinkgoT().TempDir(), "current")
+
+			// Assert
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ParseToken", func() {
+		It("should accept tokens signed with a previous key during a rotation", func() {
+			// Arrange
+			previousStore := newTestKeyStore("previous")
+			previousKey, _ := previousStore.SigningKey()
+			token, err := auth.SignToken(previousStore, jwtConfig, claims(auth.TokenKindAccess))
+			Expect(err).To(BeNil())
+
+			_, currentPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+			currentKey, _ := auth.NewKey("current", currentPrivateKey)
+			previousPublicKey, _ := auth.NewKey("previous", previousKey.PublicKey)
+			keyStore, err := auth.NewStaticKeyStore(currentKey, previousPublicKey)
+			Expect(err).To(BeNil())
+
+			// Act
+			result, err := auth.ParseToken(keyStore, jwtConfig, token, auth.TokenKindAccess)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(result["iss"]).To(Equal(jwtConfig.Issuer))
+			Expect(result["aud"]).To(Equal(jwtConfig.Audience))
+		})
+
+		It("should reject tokens signed with an unknown key", func() {
+			// Arrange
+			token, err := auth.SignToken(newTestKeyStore("current"), jwtConfig, claims(auth.TokenKindAccess))
+			Expect(err).To(BeNil())
+
+			// Act
+			_, err = auth.ParseToken(newTestKeyStore("current"), jwtConfig, token, auth.TokenKindAccess)
+
+			// Assert
+			Expect(err).To(MatchError(auth.ErrInvalidToken))
+		})
+
+		It("should reject tokens of another audience or kind", func() {
+			// Arrange
+			keyStore := newTestKeyStore("current")
+			otherConfig := &config.AuthJwt{Issuer: jwtConfig.Issuer, Audience: "another-app"}
+
+			otherAudience, err := auth.SignToken(keyStore, otherConfig, claims(auth.TokenKindAccess))
+			Expect(err).To(BeNil())
+			refresh, err := auth.SignToken(keyStore, jwtConfig, claims(auth.TokenKindRefresh))
+			Expect(err).To(BeNil())
+
+			// Act
+			_, audienceErr := auth.ParseToken(keyStore, jwtConfig, otherAudience, auth.TokenKindAccess)
+			_, kindErr := auth.ParseToken(keyStore, jwtConfig, refresh, auth.TokenKindAccess)
+
+			// Assert
+			Expect(audienceErr).To(MatchError(auth.ErrInvalidToken))
+			Expect(kindErr).To(MatchError(auth.ErrInvalidToken))
+		})
+
+		It("should accept legacy HS256 tokens only while the secret is configured", func() {
+			// Arrange
+			keyStore := newTestKeyStore("current")
+			legacyClaims := claims(auth.TokenKindAccess)
+			legacyClaims["iss"] = "panta.srvless-api"
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, legacyClaims).SignedString([]byte("secret"))
+			Expect(err).To(BeNil())
+
+			legacyConfig := &config.AuthJwt{Issuer: jwtConfig.Issuer, Audience: jwtConfig.Audience, Secret: "secret"}
+
+			// Act
+			_, legacyErr := auth.ParseToken(keyStore, legacyConfig, token, auth.TokenKindAccess)
+			_, err = auth.ParseToken(keyStore, jwtConfig, token, auth.TokenKindAccess)
+
+			// Assert
+			Expect(legacyErr).To(BeNil())
+			Expect(err).To(MatchError(auth.ErrInvalidToken))
+		})
+	})
+})
@@ -8,6 +8,7 @@ import (
 const (
 	Google = "google"
 	Apple  = "apple"
+	Email  = "email"
 )
 
 // ThirdPartyProvider represents the third party provider interface.
@@ -25,12 +26,23 @@ func NewProvider(p string, config *config.Auth) (ThirdPartyProvider, error) {
 	case Google:
 		return NewGoogleProvider(config.Google.ClientID)
 	case Apple:
-		return NewAppleProvider(config.Apple)
+		return NewAppleProvider(config.Apple, defaultAppleKeys)
 	default:
 		return nil, &InvalidProviderError{}
 	}
 }
 
+// NewProviderHandler returns the provider handler of the third party providers and of the email one,
+// which verifies the codes sent by email against the store.
+func NewProviderHandler(codes EmailCodeStore) ProviderHandler {
+	return func(p string, config *config.Auth) (ThirdPartyProvider, error) {
+		if p == Email {
+			return NewEmailProvider(codes, config.Email), nil
+		}
+		return NewProvider(p, config)
+	}
+}
+
 //-------------------------------------
 // Errors
 //-------------------------------------
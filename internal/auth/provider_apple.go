@@ -2,9 +2,9 @@ package auth
 
 import (
 	"context"
-	"fmt"
+	"errors"
 
-	"github.com/Timothylock/go-signin-with-apple/apple"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pietro-putelli/feynman-backend/config"
 	"github.com/pietro-putelli/feynman-backend/internal/domain"
@@ -12,79 +12,63 @@ import (
 
 var _ ThirdPartyProvider = (*AppleProvider)(nil)
 
+// ErrAppleEmailNotFound is returned when the Apple identity token has no email.
+var ErrAppleEmailNotFound = errors.New("email not found in apple identity token")
+
 // AppleProvider represents the Apple authentication provider.
 type AppleProvider struct {
-	clientID     string
-	clientSecret string
+	keys     JWKSFetcher
+	clientID string
 }
 
-// NewAppleProvider creates a new Apple provider.
-func NewAppleProvider(config config.Apple) (*AppleProvider, error) {
-	// Generate the client secret used to authenticate with Apple's validation servers
-	secret, err := apple.GenerateClientSecret(config.SignInCertificate, config.TeamId, config.AppBundleId, config.SignInCertificateKey)
-
-	if err != nil {
-		return nil, err
-	}
-
+// NewAppleProvider creates a new Apple provider verifying the identity tokens with the given keys.
+func NewAppleProvider(config config.Apple, keys JWKSFetcher) (*AppleProvider, error) {
 	return &AppleProvider{
-		clientID:     config.AppBundleId,
-		clientSecret: secret,
+		clientID: config.AppBundleId,
+		keys:     keys,
 	}, nil
 }
 
-// ValidateToken validates the token.
+// ValidateToken validates the identity token of Sign in with Apple, locally against the Apple keys.
+// The token of the body is the authorization code, the identity token is sent apart.
 func (a *AppleProvider) ValidateToken(auth *domain.AuthTokenBody) (*domain.ThirdPartyUser, error) {
-	client := apple.New()
-
-	vReq := apple.AppValidationTokenRequest{
-		ClientID:     a.clientID,
-		ClientSecret: a.clientSecret,
-		Code:         auth.Token,
+	if auth.IDToken == "" {
+		return nil, ErrInvalidToken
 	}
 
-	var resp apple.ValidationResponse
-
-	// Do the verification
-	err := client.VerifyAppToken(context.Background(), vReq, &resp)
-
+	claims, err := parseAppleToken(context.Background(), a.keys, a.clientID, auth.IDToken, jwt.WithExpirationRequired())
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.Error != "" {
-		fmt.Printf("[Apple Provider Error]: %s - %s\n", resp.Error, resp.ErrorDescription)
-		return nil, err
-	}
-
-	// Get the unique user ID
-	unique, err := apple.GetUniqueID(resp.IDToken)
-	if err != nil {
-		return nil, err
+	sub, err := claims.GetSubject()
+	if err != nil || sub == "" {
+		return nil, ErrInvalidToken
 	}
 
-	// Get the email
-	claim, err := apple.GetClaims(resp.IDToken)
-	if err != nil {
-		return nil, err
+	email, _ := claims["email"].(string)
+	if email == "" {
+		return nil, ErrAppleEmailNotFound
 	}
 
-	email := (*claim)["email"]
-
-	if email == nil {
-		return nil, fmt.Errorf("[Apple Provider Error]: Email not found in claims")
+	/* The name is only given by the app on the first sign in */
+	data := auth.Data
+	if data == nil {
+		data = &domain.AuthTokenData{}
 	}
 
 	thirdPartyUser := domain.NewAppleThirdPartyUser()
 
 	if err := mapstructure.Decode(
 		map[string]interface{}{
-			"email":        email,
-			"given_name":   auth.Data.GivenName,
-			"family_name":  auth.Data.FamilyName,
-			"sub":          unique,
-			"device_id":    auth.Device.ID,
-			"device_token": auth.Device.Token,
+			"email":              email,
+			"given_name":         data.GivenName,
+			"family_name":        data.FamilyName,
+			"sub":                sub,
+			"device_id":          auth.Device.ID,
+			"device_token":       auth.Device.Token,
+			"device_platform":    auth.Device.Platform,
+			"device_environment": auth.Device.Environment,
 		}, thirdPartyUser); err != nil {
 		return nil, err
 	}
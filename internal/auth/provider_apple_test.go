@@ -0,0 +1,157 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/auth"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+/* Signs the claims like Apple does, with a RS256 key published with the given kid */
+func signAppleToken(privateKey *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(privateKey)
+	Expect(err).To(BeNil())
+
+	return signed
+}
+
+var _ = Describe("ProviderApple", func() {
+	var (
+		keys       *auth.MockJWKSFetcher
+		privateKey *rsa.PrivateKey
+		provider   *auth.AppleProvider
+	)
+
+	appleConfig := config.Apple{AppBundleId: "com.feynman.app"}
+
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   "https://appleid.apple.com",
+			"aud":   appleConfig.AppBundleId,
+			"sub":   "apple-sub",
+			"email": "user@privaterelay.appleid.com",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+		}
+	}
+
+	body := func(idToken string) *domain.AuthTokenBody {
+		return &domain.AuthTokenBody{
+			Token:    "authorization-code",
+			IDToken:  idToken,
+			Provider: "apple",
+			Data:     &domain.AuthTokenData{GivenName: "Ada", FamilyName: "Lovelace"},
+			Device:   &domain.AuthTokenDevice{ID: "device-id"},
+		}
+	}
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		keys = auth.NewMockJWKSFetcher(ctrl)
+
+		var err error
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).To(BeNil())
+
+		key, err := auth.NewKey("apple-kid", &privateKey.PublicKey)
+		Expect(err).To(BeNil())
+		keys.EXPECT().Key(gomock.Any(), "apple-kid").Return(key, nil).AnyTimes()
+
+		provider, err = auth.NewAppleProvider(appleConfig, keys)
+		Expect(err).To(BeNil())
+	})
+
+	Describe("ValidateToken", func() {
+		It("should return the user of a valid identity token", func() {
+			// Act
+			result, err := provider.ValidateToken(body(signAppleToken(privateKey, "apple-kid", claims())))
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(result.Sub).To(Equal("apple-sub"))
+			Expect(result.Email).To(Equal("user@privaterelay.appleid.com"))
+			Expect(result.GivenName).To(Equal("Ada"))
+			Expect(result.Provider).To(Equal("apple"))
+		})
+
+		It("should reject tokens of another app or expired", func() {
+			// Arrange
+			otherApp := claims()
+			otherApp["aud"] = "com.another.app"
+			expired := claims()
+			expired["exp"] = time.Now().Add(-time.Minute).Unix()
+
+			// Act
+			_, audienceErr := provider.ValidateToken(body(signAppleToken(privateKey, "apple-kid", otherApp)))
+			_, expiredErr := provider.ValidateToken(body(signAppleToken(privateKey, "apple-kid", expired)))
+
+			// Assert
+			Expect(audienceErr).To(MatchError(auth.ErrInvalidToken))
+			Expect(expiredErr).To(MatchError(auth.ErrInvalidToken))
+		})
+
+		It("should reject tokens signed with an unknown key", func() {
+			// Arrange
+			keys.EXPECT().Key(gomock.Any(), "unknown-kid").Return(nil, auth.ErrInvalidToken)
+
+			// Act
+			_, err := provider.ValidateToken(body(signAppleToken(privateKey, "unknown-kid", claims())))
+
+			// Assert
+			Expect(err).To(MatchError(auth.ErrInvalidToken))
+		})
+
+		It("should reject a body without the identity token", func() {
+			// Act
+			_, err := provider.ValidateToken(body(""))
+
+			// Assert
+			Expect(err).To(MatchError(auth.ErrInvalidToken))
+		})
+
+		It("should fail without email", func() {
+			// Arrange
+			noEmail := claims()
+			delete(noEmail, "email")
+
+			// Act
+			_, err := provider.ValidateToken(body(signAppleToken(privateKey, "apple-kid", noEmail)))
+
+			// Assert
+			Expect(err).To(MatchError(auth.ErrAppleEmailNotFound))
+		})
+	})
+
+	Describe("ParseAppleNotification", func() {
+		It("should return the event encoded in the payload", func() {
+			// Arrange
+			notification := jwt.MapClaims{
+				"iss":    "https://appleid.apple.com",
+				"aud":    appleConfig.AppBundleId,
+				"iat":    time.Now().Unix(),
+				"jti":    "notification-id",
+				"events": `{"type":"consent-revoked","sub":"apple-sub","event_time":1700000000000}`,
+			}
+
+			// Act
+			event, err := auth.ParseAppleNotification(context.Background(), keys, appleConfig.AppBundleId, signAppleToken(privateKey, "apple-kid", notification))
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(event.Type).To(Equal(auth.AppleEventConsentRevoked))
+			Expect(event.Sub).To(Equal("apple-sub"))
+		})
+	})
+})
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+var _ ThirdPartyProvider = (*EmailProvider)(nil)
+
+// EmailProvider represents the passwordless email authentication provider,
+// the token is the code or the link token sent by SendEmailLogin.
+type EmailProvider struct {
+	codes       EmailCodeStore
+	codeSecret  string
+	maxAttempts int
+}
+
+// NewEmailProvider creates a new email provider.
+func NewEmailProvider(codes EmailCodeStore, config config.AuthEmail) *EmailProvider {
+	return &EmailProvider{
+		codes:       codes,
+		codeSecret:  config.CodeSecret,
+		maxAttempts: config.MaxAttempts,
+	}
+}
+
+// ValidateToken consumes the sign in code of the email, the email is the subject of the user.
+func (e *EmailProvider) ValidateToken(auth *domain.AuthTokenBody) (*domain.ThirdPartyUser, error) {
+	email := normalizeEmail(auth.Email)
+
+	if e.codeSecret == "" {
+		return nil, ErrEmailCodeSecretNotConfigured
+	}
+
+	if err := e.codes.ConsumeCode(email, hashEmailCode(e.codeSecret, strings.TrimSpace(auth.Token)), e.maxAttempts); err != nil {
+		return nil, err
+	}
+
+	data := auth.Data
+	if data == nil {
+		data = &domain.AuthTokenData{}
+	}
+
+	thirdPartyUser := domain.NewEmailThirdPartyUser()
+
+	if err := mapstructure.Decode(
+		map[string]interface{}{
+			"email":              email,
+			"given_name":         data.GivenName,
+			"family_name":        data.FamilyName,
+			"sub":                email,
+			"device_id":          auth.Device.ID,
+			"device_token":       auth.Device.Token,
+			"device_platform":    auth.Device.Platform,
+			"device_environment": auth.Device.Environment,
+		}, thirdPartyUser); err != nil {
+		return nil, err
+	}
+
+	return thirdPartyUser, nil
+}
+
+/* Emails are compared case insensitively */
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
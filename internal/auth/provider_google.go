@@ -44,12 +44,14 @@ func (g *GoogleProvider) ValidateToken(auth *domain.AuthTokenBody) (*domain.Thir
 	claims := payload.Claims
 
 	if err := mapstructure.Decode(map[string]interface{}{
-		"email":        claims["email"],
-		"given_name":   claims["given_name"],
-		"family_name":  claims["family_name"],
-		"sub":          claims["sub"],
-		"device_id":    auth.Device.ID,
-		"device_token": auth.Device.Token,
+		"email":              claims["email"],
+		"given_name":         claims["given_name"],
+		"family_name":        claims["family_name"],
+		"sub":                claims["sub"],
+		"device_id":          auth.Device.ID,
+		"device_token":       auth.Device.Token,
+		"device_platform":    auth.Device.Platform,
+		"device_environment": auth.Device.Environment,
 	}, thirdPartyUser); err != nil {
 		return nil, err
 	}
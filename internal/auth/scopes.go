@@ -0,0 +1,38 @@
+package auth
+
+import "github.com/pietro-putelli/feynman-backend/internal/domain"
+
+// uuidSegment matches exactly one UUID path parameter, the ? wildcard matches a single character.
+const uuidSegment = "????????-????-????-????-????????????"
+
+// scopeRoutes are the routes allowed by each scope of the personal access tokens, as METHOD/path of the execute-api ARNs.
+// The * wildcard matches the rest of the path too, e.g. the cards of the picks, so the routes are listed exactly.
+var scopeRoutes = map[string][]string{
+	domain.ScopeRead: {
+		"GET/v1/books",
+		"GET/v1/books/picks",
+		"GET/v1/books/topics",
+		"GET/v1/books/" + uuidSegment,
+		"GET/v1/search",
+	},
+	domain.ScopePicksWrite: {
+		"POST/v1/books/picks",
+		"PUT/v1/books/picks",
+		"DELETE/v1/books/" + uuidSegment + "/picks/" + uuidSegment,
+	},
+	domain.ScopeExport: {"GET/v1/export"},
+}
+
+// ScopeResources returns the execute-api resources of the API allowed by the scopes, in every stage.
+// Unknown scopes allow nothing.
+func ScopeResources(apiArn string, scopes []string) []string {
+	resources := []string{}
+
+	for _, scope := range scopes {
+		for _, route := range scopeRoutes[scope] {
+			resources = append(resources, apiArn+"/*/"+route)
+		}
+	}
+
+	return resources
+}
@@ -0,0 +1,79 @@
+package auth_test
+
+import (
+	"regexp"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pietro-putelli/feynman-backend/internal/auth"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+/* Match the method ARN like the IAM policy does, * matches any characters and ? a single one */
+func isAllowed(resources []string, methodArn string) bool {
+	for _, resource := range resources {
+		pattern := regexp.QuoteMeta(resource)
+		pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+		pattern = strings.ReplaceAll(pattern, `\?`, ".")
+
+		if regexp.MustCompile("^" + pattern + "$").MatchString(methodArn) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var _ = Describe("Scopes", func() {
+	apiArn := "arn:aws:execute-api:eu-central-1:123456789012:api-id"
+	bookID := "0b4f3c2e-8a51-4c7e-9d1f-2a6b8e4c5d71"
+	pickID := "6f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9"
+
+	It("should allow only the routes of the scopes", func() {
+		// Act
+		result := auth.ScopeResources(apiArn, []string{domain.ScopeRead, domain.ScopeExport})
+
+		// Assert
+		Expect(result).To(ConsistOf(
+			apiArn+"/*/GET/v1/books",
+			apiArn+"/*/GET/v1/books/picks",
+			apiArn+"/*/GET/v1/books/topics",
+			apiArn+"/*/GET/v1/books/????????-????-????-????-????????????",
+			apiArn+"/*/GET/v1/search",
+			apiArn+"/*/GET/v1/export",
+		))
+	})
+
+	It("should match the read routes but not the cards of the picks", func() {
+		// Act
+		result := auth.ScopeResources(apiArn, []string{domain.ScopeRead})
+
+		// Assert
+		Expect(isAllowed(result, apiArn+"/Dev/GET/v1/books")).To(BeTrue())
+		Expect(isAllowed(result, apiArn+"/Dev/GET/v1/books/picks")).To(BeTrue())
+		Expect(isAllowed(result, apiArn+"/Dev/GET/v1/books/"+bookID)).To(BeTrue())
+		Expect(isAllowed(result, apiArn+"/Dev/GET/v1/books/picks/cards")).To(BeFalse())
+		Expect(isAllowed(result, apiArn+"/Dev/POST/v1/books/"+bookID+"/digest")).To(BeFalse())
+		Expect(isAllowed(result, apiArn+"/Dev/GET/v1/export")).To(BeFalse())
+	})
+
+	It("should match the deletion of a pick but not of a book", func() {
+		// Act
+		result := auth.ScopeResources(apiArn, []string{domain.ScopePicksWrite})
+
+		// Assert
+		Expect(isAllowed(result, apiArn+"/Dev/DELETE/v1/books/"+bookID+"/picks/"+pickID)).To(BeTrue())
+		Expect(isAllowed(result, apiArn+"/Dev/DELETE/v1/books/"+bookID)).To(BeFalse())
+		Expect(isAllowed(result, apiArn+"/Dev/PUT/v1/books/picks/cards")).To(BeFalse())
+	})
+
+	It("should allow nothing for unknown scopes", func() {
+		// Act
+		result := auth.ScopeResources(apiArn, []string{"admin"})
+
+		// Assert
+		Expect(result).To(BeEmpty())
+	})
+})
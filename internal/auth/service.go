@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -18,38 +20,41 @@ var _ Service = (*serviceImpl)(nil)
 //
 //go:generate mockgen -source=service.go -destination=./service_mock.go -package=auth
 type Service interface {
-	GenerateToken(user *domain.User) (*domain.AuthTokenDto, error)
+	GenerateToken(user *domain.User, sessionID uuid.UUID) (*domain.AuthTokenDto, error)
 	RefreshAccessToken(refreshToken string) (*domain.AuthTokenDto, error)
 	CreateUserIfNotExists(user *domain.ThirdPartyUser) (*domain.User, uuid.UUID, error)
 }
 
 // serviceImpl represents the authentication service implementation.
 type serviceImpl struct {
-	jwtSecret    []byte
-	jwtExpiresIn int
-	userService  user.Service
+	jwtConfig           *config.AuthJwt
+	keyStore            KeyStore
+	jwtExpiresIn        int
+	jwtRefreshExpiresIn int
+	userService         user.Service
 }
 
-// NewService creates a new authentication service.
-func NewService(jwtConfig *config.AuthJwt, userService user.Service) Service {
+// NewService creates a new authentication service, the tokens are signed with the signing key of the store.
+func NewService(jwtConfig *config.AuthJwt, keyStore KeyStore, userService user.Service) Service {
 	return &serviceImpl{
-		userService:  userService,
-		jwtSecret:    []byte(jwtConfig.Secret),
-		jwtExpiresIn: jwtConfig.AccessTokenDuration,
+		userService:         userService,
+		jwtConfig:           jwtConfig,
+		keyStore:            keyStore,
+		jwtExpiresIn:        jwtConfig.AccessTokenDuration,
+		jwtRefreshExpiresIn: jwtConfig.RefreshTokenDuration,
 	}
 }
 
-// generateAccessToken generates a new access token.
-func (s *serviceImpl) generateAccessToken(user *domain.User, expirationDate *time.Time) (string, error) {
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// generateAccessToken generates a new access token for the session.
+func (s *serviceImpl) generateAccessToken(user *domain.User, sessionID uuid.UUID, expirationDate *time.Time) (string, error) {
+	accessTokenString, err := SignToken(s.keyStore, s.jwtConfig, jwt.MapClaims{
 		"sub":   user.Guid,
 		"email": user.Email,
+		"sid":   sessionID,
 		"exp":   expirationDate.Unix(),
 		"iat":   time.Now().Unix(),
-		"iss":   "panta.srvless-api",
-		"kind":  "access",
+		"kind":  TokenKindAccess,
 	})
-	accessTokenString, err := accessToken.SignedString(s.jwtSecret)
 	if err != nil {
 		return "", err
 	}
@@ -57,29 +62,51 @@ func (s *serviceImpl) generateAccessToken(user *domain.User, expirationDate *tim
 	return accessTokenString, nil
 }
 
-// GenerateToken generates a new token validating the token ID.
-func (s *serviceImpl) GenerateToken(user *domain.User) (*domain.AuthTokenDto, error) {
+// generateRefreshToken generates a new refresh token for the session, only the hash of its ID is stored.
+func (s *serviceImpl) generateRefreshToken(user *domain.User, sessionID uuid.UUID, tokenID uuid.UUID, expirationDate *time.Time) (string, error) {
+	refreshTokenString, err := SignToken(s.keyStore, s.jwtConfig, jwt.MapClaims{
+		"sub":   user.Guid,
+		"email": user.Email,
+		"sid":   sessionID,
+		"jti":   tokenID,
+		"exp":   expirationDate.Unix(),
+		"iat":   time.Now().Unix(),
+		"kind":  TokenKindRefresh,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return refreshTokenString, nil
+}
+
+// hashTokenID hashes the token ID, the plain ID is never stored.
+func hashTokenID(tokenID string) string {
+	hash := sha256.Sum256([]byte(tokenID))
+	return hex.EncodeToString(hash[:])
+}
+
+// GenerateToken generates a new access token and a new refresh token bound to the session.
+func (s *serviceImpl) GenerateToken(user *domain.User, sessionID uuid.UUID) (*domain.AuthTokenDto, error) {
 	// Generate access token
 	accessTokenExpiresAt := time.Now().Add(time.Duration(s.jwtExpiresIn) * time.Minute)
-	accessTokenString, err := s.generateAccessToken(user, &accessTokenExpiresAt)
+	accessTokenString, err := s.generateAccessToken(user, sessionID, &accessTokenExpiresAt)
 	if err != nil {
 		return nil, err
 	}
 
-	// Generate infinite refresh token
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":   user.Guid,
-		"email": user.Email,
-		"exp":   time.Now().AddDate(1000, 0, 0).Unix(),
-		"iat":   time.Now().Unix(),
-		"iss":   "panta.srvless-api",
-		"kind":  "refresh",
-	})
-	refreshTokenString, err := refreshToken.SignedString(s.jwtSecret)
+	// Generate refresh token and store the hash of its ID
+	refreshTokenID := uuid.New()
+	refreshTokenExpiresAt := time.Now().Add(time.Duration(s.jwtRefreshExpiresIn) * time.Minute)
+	refreshTokenString, err := s.generateRefreshToken(user, sessionID, refreshTokenID, &refreshTokenExpiresAt)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.userService.CreateRefreshToken(sessionID, hashTokenID(refreshTokenID.String()), refreshTokenExpiresAt); err != nil {
+		return nil, err
+	}
+
 	return &domain.AuthTokenDto{
 		AccessToken:          accessTokenString,
 		RefreshToken:         refreshTokenString,
@@ -87,19 +114,11 @@ func (s *serviceImpl) GenerateToken(user *domain.User) (*domain.AuthTokenDto, er
 	}, nil
 }
 
-// RefreshAccessToken generates a new access token using the refresh token.
+// RefreshAccessToken generates a new access token using the refresh token, the refresh token is rotated.
 func (s *serviceImpl) RefreshAccessToken(refreshToken string) (*domain.AuthTokenDto, error) {
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(refreshToken, claims, func(token *jwt.Token) (interface{}, error) {
-		return s.jwtSecret, nil
-	})
+	claims, err := ParseToken(s.keyStore, s.jwtConfig, refreshToken, TokenKindRefresh)
 	if err != nil {
-		return nil, errors.New("invalid token")
-	}
-
-	// Check if the token is a refresh token
-	if claims["kind"] != "refresh" {
-		return nil, errors.New("invalid token")
+		return nil, err
 	}
 
 	// Get user by GUID
@@ -114,22 +133,54 @@ func (s *serviceImpl) RefreshAccessToken(refreshToken string) (*domain.AuthToken
 		return nil, errors.New("invalid token subject")
 	}
 
-	// Get user
-	user, err := s.userService.GetUserByGuid(userGuid)
+	refreshTokenID := uuid.New()
+	refreshTokenExpiresAt := time.Now().Add(time.Duration(s.jwtRefreshExpiresIn) * time.Minute)
+
+	var user *domain.User
+	var sessionID uuid.UUID
+
+	if issuer, _ := claims.GetIssuer(); issuer == legacyIssuer {
+		// Refresh tokens issued before the sessions are exchanged once for a new session, the whole token is hashed
+		user, sessionID, err = s.userService.MigrateLegacyRefreshToken(userGuid, hashTokenID(refreshToken), hashTokenID(refreshTokenID.String()), refreshTokenExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		sessionIDStr, _ := claims["sid"].(string)
+		tokenID, _ := claims["jti"].(string)
+
+		sessionID, err = uuid.Parse(sessionIDStr)
+		if err != nil || tokenID == "" {
+			return nil, ErrInvalidToken
+		}
+
+		// Rotate the refresh token, it fails when the session has been revoked, the user deactivated or the token reused
+		user, err = s.userService.RotateRefreshToken(sessionID, hashTokenID(tokenID), hashTokenID(refreshTokenID.String()), refreshTokenExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if user.Guid != userGuid {
+		return nil, ErrInvalidToken
+	}
+
+	// Generate the next refresh token
+	refreshTokenString, err := s.generateRefreshToken(user, sessionID, refreshTokenID, &refreshTokenExpiresAt)
 	if err != nil {
 		return nil, err
 	}
 
 	// Generate access token
 	accessTokenExpiresAt := time.Now().Add(time.Duration(s.jwtExpiresIn) * time.Minute)
-	accessTokenString, err := s.generateAccessToken(user, &accessTokenExpiresAt)
+	accessTokenString, err := s.generateAccessToken(user, sessionID, &accessTokenExpiresAt)
 	if err != nil {
 		return nil, err
 	}
 
 	return &domain.AuthTokenDto{
 		AccessToken:          accessTokenString,
-		RefreshToken:         refreshToken,
+		RefreshToken:         refreshTokenString,
 		AccessTokenExpiresAt: accessTokenExpiresAt,
 	}, nil
 }
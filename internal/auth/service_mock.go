@@ -12,6 +12,7 @@ package auth
 import (
 	reflect "reflect"
 
+	uuid "github.com/google/uuid"
 	domain "github.com/pietro-putelli/feynman-backend/internal/domain"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -40,12 +41,13 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 }
 
 // CreateUserIfNotExists mocks base method.
-func (m *MockService) CreateUserIfNotExists(user *domain.ThirdPartyUser) (*domain.User, error) {
+func (m *MockService) CreateUserIfNotExists(user *domain.ThirdPartyUser) (*domain.User, uuid.UUID, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateUserIfNotExists", user)
 	ret0, _ := ret[0].(*domain.User)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(uuid.UUID)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // CreateUserIfNotExists indicates an expected call of CreateUserIfNotExists.
@@ -55,18 +57,18 @@ func (mr *MockServiceMockRecorder) CreateUserIfNotExists(user any) *gomock.Call
 }
 
 // GenerateToken mocks base method.
-func (m *MockService) GenerateToken(user *domain.User) (*domain.AuthTokenDto, error) {
+func (m *MockService) GenerateToken(user *domain.User, sessionID uuid.UUID) (*domain.AuthTokenDto, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateToken", user)
+	ret := m.ctrl.Call(m, "GenerateToken", user, sessionID)
 	ret0, _ := ret[0].(*domain.AuthTokenDto)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateToken indicates an expected call of GenerateToken.
-func (mr *MockServiceMockRecorder) GenerateToken(user any) *gomock.Call {
+func (mr *MockServiceMockRecorder) GenerateToken(user, sessionID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateToken", reflect.TypeOf((*MockService)(nil).GenerateToken), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateToken", reflect.TypeOf((*MockService)(nil).GenerateToken), user, sessionID)
 }
 
 // RefreshAccessToken mocks base method.
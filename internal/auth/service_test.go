@@ -21,19 +21,23 @@ var _ = Describe("Auth Service", func() {
 			service auth.Service
 			// sqlMock sqlmock.Sqlmock
 			userService *user.MockService
+			keyStore    auth.KeyStore
 			ctrl        *gomock.Controller
 		)
 
 		authConfig := &config.AuthJwt{
-			Secret:              "secret",
-			AccessTokenDuration: 3600,
+			Issuer:               "feynman-backend",
+			Audience:             "feynman-app",
+			AccessTokenDuration:  3600,
+			RefreshTokenDuration: 86400,
 		}
 
 		BeforeEach(func() {
 			ctrl = gomock.NewController(GinkgoT())
 			userService = user.NewMockService(ctrl)
+			keyStore = newTestKeyStore("current")
 			// database, _ := database.NewDB(conn)
-			service = auth.NewService(authConfig, userService)
+			service = auth.NewService(authConfig, keyStore, userService)
 		})
 
 		It("should return the new user", func() {
@@ -54,13 +58,16 @@ var _ = Describe("Auth Service", func() {
 				Settings:   &domain.UserSettings{},
 			}
 
-			userService.EXPECT().CreateUserIfNotExists(user).Return(userResult, nil)
+			sessionResult := uuid.Must(uuid.NewRandom())
+
+			userService.EXPECT().CreateUserIfNotExists(user).Return(userResult, sessionResult, nil)
 
 			// Act
-			createdUser, err := service.CreateUserIfNotExists(user)
+			createdUser, sessionID, err := service.CreateUserIfNotExists(user)
 
 			// Assert
 			Expect(err).To(BeNil())
+			Expect(sessionID).To(Equal(sessionResult))
 			Expect(createdUser).NotTo(BeNil())
 			Expect(createdUser.Email).To(Equal(user.Email))
 			Expect(createdUser.ExternalID).To(Equal(user.Sub))
@@ -75,17 +82,21 @@ var _ = Describe("Auth Service", func() {
 		var (
 			service     auth.Service
 			userService *user.MockService
+			keyStore    auth.KeyStore
 		)
 
 		authConfig := &config.AuthJwt{
-			Secret:              "secret",
-			AccessTokenDuration: 3600,
+			Issuer:               "feynman-backend",
+			Audience:             "feynman-app",
+			AccessTokenDuration:  3600,
+			RefreshTokenDuration: 86400,
 		}
 
 		BeforeEach(func() {
 			ctrl := gomock.NewController(GinkgoT())
 			userService = user.NewMockService(ctrl)
-			service = auth.NewService(authConfig, userService)
+			keyStore = newTestKeyStore("current")
+			service = auth.NewService(authConfig, keyStore, userService)
 		})
 
 		It("should generate a new token", func() {
@@ -97,8 +108,17 @@ var _ = Describe("Auth Service", func() {
 				FamilyName: "Family",
 			}
 
+			sessionID := uuid.Must(uuid.NewRandom())
+
+			var storedHash string
+			userService.EXPECT().CreateRefreshToken(sessionID, gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ uuid.UUID, tokenHash string, _ time.Time) error {
+					storedHash = tokenHash
+					return nil
+				})
+
 			// Act
-			token, err := service.GenerateToken(user)
+			token, err := service.GenerateToken(user, sessionID)
 
 			// Assert
 			Expect(err).To(BeNil())
@@ -107,28 +127,28 @@ var _ = Describe("Auth Service", func() {
 			Expect(token.RefreshToken).NotTo(BeEmpty())
 			Expect(token.AccessTokenExpiresAt).NotTo(BeNil())
 			// Parse the access token to check the claims
-			jwtToken, err := jwt.Parse(token.AccessToken, func(token *jwt.Token) (interface{}, error) {
-				return []byte(authConfig.Secret), nil
-			})
+			claims, err := auth.ParseToken(keyStore, authConfig, token.AccessToken, auth.TokenKindAccess)
 			Expect(err).To(BeNil())
-			claims, ok := jwtToken.Claims.(jwt.MapClaims)
-			Expect(ok).To(BeTrue())
 			Expect(claims["sub"]).To(Equal(user.Guid.String()))
 			Expect(claims["email"]).To(Equal(user.Email))
-			Expect(claims["iss"]).To(Equal("panta.srvless-api"))
+			Expect(claims["iss"]).To(Equal(authConfig.Issuer))
 			Expect(claims["kind"]).To(Equal("access"))
+			Expect(claims["sid"]).To(Equal(sessionID.String()))
 
 			// Parse the refresh token to check the claims
-			jwtToken, err = jwt.Parse(token.RefreshToken, func(token *jwt.Token) (interface{}, error) {
-				return []byte(authConfig.Secret), nil
-			})
+			claims, err = auth.ParseToken(keyStore, authConfig, token.RefreshToken, auth.TokenKindRefresh)
 			Expect(err).To(BeNil())
-			claims, ok = jwtToken.Claims.(jwt.MapClaims)
-			Expect(ok).To(BeTrue())
 			Expect(claims["sub"]).To(Equal(user.Guid.String()))
 			Expect(claims["email"]).To(Equal(user.Email))
-			Expect(claims["iss"]).To(Equal("panta.srvless-api"))
+			Expect(claims["iss"]).To(Equal(authConfig.Issuer))
 			Expect(claims["kind"]).To(Equal("refresh"))
+			Expect(claims["sid"]).To(Equal(sessionID.String()))
+
+			// Only the hash of the token ID is stored
+			tokenID, ok := claims["jti"].(string)
+			Expect(ok).To(BeTrue())
+			Expect(storedHash).NotTo(BeEmpty())
+			Expect(storedHash).NotTo(Equal(tokenID))
 		})
 	})
 
@@ -136,20 +156,39 @@ var _ = Describe("Auth Service", func() {
 		var (
 			service     auth.Service
 			userService *user.MockService
+			keyStore    auth.KeyStore
 		)
 
 		authConfig := &config.AuthJwt{
-			Secret:              "secret",
-			AccessTokenDuration: 3600,
+			Issuer:               "feynman-backend",
+			Audience:             "feynman-app",
+			AccessTokenDuration:  3600,
+			RefreshTokenDuration: 86400,
 		}
 
 		BeforeEach(func() {
 			ctrl := gomock.NewController(GinkgoT())
 			userService = user.NewMockService(ctrl)
-			service = auth.NewService(authConfig, userService)
+			keyStore = newTestKeyStore("current")
+			service = auth.NewService(authConfig, keyStore, userService)
 		})
 
-		It("should generate a new access token", func() {
+		/* Sign a refresh token bound to the session */
+		signRefreshToken := func(user *domain.User, sessionID uuid.UUID, tokenID uuid.UUID) string {
+			refreshTokenString, err := auth.SignToken(keyStore, authConfig, jwt.MapClaims{
+				"sub":   user.Guid,
+				"email": user.Email,
+				"sid":   sessionID,
+				"jti":   tokenID,
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"iat":   time.Now().Unix(),
+				"kind":  "refresh",
+			})
+			Expect(err).To(BeNil())
+			return refreshTokenString
+		}
+
+		It("should generate a new access token and rotate the refresh token", func() {
 			// Arrange
 			user := &domain.User{
 				Guid:       uuid.Must(uuid.NewRandom()),
@@ -157,20 +196,16 @@ var _ = Describe("Auth Service", func() {
 				GivenName:  "Given",
 				FamilyName: "Family",
 			}
+			sessionID := uuid.Must(uuid.NewRandom())
+			refreshTokenString := signRefreshToken(user, sessionID, uuid.Must(uuid.NewRandom()))
 
-			// Generate a refresh token
-			refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-				"sub":   user.Guid,
-				"email": user.Email,
-				"exp":   time.Now().AddDate(1000, 0, 0).Unix(),
-				"iat":   time.Now().Unix(),
-				"iss":   "panta.srvless-api",
-				"kind":  "refresh",
-			})
-			refreshTokenString, err := refreshToken.SignedString([]byte(authConfig.Secret))
-			Expect(err).To(BeNil())
-
-			userService.EXPECT().GetUserByGuid(user.Guid).Return(user, nil)
+			var previousHash, nextHash string
+			userService.EXPECT().RotateRefreshToken(sessionID, gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ uuid.UUID, tokenHash string, nextTokenHash string, _ time.Time) (*domain.User, error) {
+					previousHash = tokenHash
+					nextHash = nextTokenHash
+					return user, nil
+				})
 
 			// Act
 			token, err := service.RefreshAccessToken(refreshTokenString)
@@ -180,18 +215,87 @@ var _ = Describe("Auth Service", func() {
 			Expect(token).NotTo(BeNil())
 			Expect(token.AccessToken).NotTo(BeEmpty())
 			Expect(token.RefreshToken).NotTo(BeEmpty())
+			Expect(token.RefreshToken).NotTo(Equal(refreshTokenString))
 			Expect(token.AccessTokenExpiresAt).NotTo(BeNil())
+			Expect(nextHash).NotTo(Equal(previousHash))
 			// Parse the access token to check the claims
-			jwtToken, err := jwt.Parse(token.AccessToken, func(token *jwt.Token) (interface{}, error) {
-				return []byte(authConfig.Secret), nil
-			})
+			claims, err := auth.ParseToken(keyStore, authConfig, token.AccessToken, auth.TokenKindAccess)
 			Expect(err).To(BeNil())
-			claims, ok := jwtToken.Claims.(jwt.MapClaims)
-			Expect(ok).To(BeTrue())
 			Expect(claims["sub"]).To(Equal(user.Guid.String()))
 			Expect(claims["email"]).To(Equal(user.Email))
-			Expect(claims["iss"]).To(Equal("panta.srvless-api"))
+			Expect(claims["iss"]).To(Equal(authConfig.Issuer))
 			Expect(claims["kind"]).To(Equal("access"))
 		})
+
+		It("should return the reuse error when the refresh token has already been rotated", func() {
+			// Arrange
+			sessionUser := &domain.User{
+				Guid:  uuid.Must(uuid.NewRandom()),
+				Email: "test@test.com",
+			}
+			sessionID := uuid.Must(uuid.NewRandom())
+			refreshTokenString := signRefreshToken(sessionUser, sessionID, uuid.Must(uuid.NewRandom()))
+
+			userService.EXPECT().RotateRefreshToken(sessionID, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, user.ErrRefreshTokenReused)
+
+			// Act
+			token, err := service.RefreshAccessToken(refreshTokenString)
+
+			// Assert
+			Expect(token).To(BeNil())
+			Expect(err).To(MatchError(user.ErrRefreshTokenReused))
+		})
+
+		It("should exchange a legacy refresh token for a new session", func() {
+			// Arrange
+			legacyConfig := *authConfig
+			legacyConfig.Secret = "secret"
+			service = auth.NewService(&legacyConfig, keyStore, userService)
+
+			sessionUser := &domain.User{
+				Guid:  uuid.Must(uuid.NewRandom()),
+				Email: "test@test.com",
+			}
+			sessionID := uuid.Must(uuid.NewRandom())
+			refreshTokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+				"sub":  sessionUser.Guid,
+				"exp":  time.Now().AddDate(1000, 0, 0).Unix(),
+				"iat":  time.Now().Unix(),
+				"iss":  "panta.srvless-api",
+				"kind": "refresh",
+			}).SignedString([]byte("secret"))
+			Expect(err).To(BeNil())
+
+			userService.EXPECT().MigrateLegacyRefreshToken(sessionUser.Guid, gomock.Any(), gomock.Any(), gomock.Any()).Return(sessionUser, sessionID, nil)
+
+			// Act
+			token, err := service.RefreshAccessToken(refreshTokenString)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(token.RefreshToken).NotTo(Equal(refreshTokenString))
+			claims, err := auth.ParseToken(keyStore, &legacyConfig, token.RefreshToken, auth.TokenKindRefresh)
+			Expect(err).To(BeNil())
+			Expect(claims["sid"]).To(Equal(sessionID.String()))
+			Expect(claims["jti"]).NotTo(BeEmpty())
+		})
+
+		It("should reject refresh tokens without session", func() {
+			// Arrange
+			refreshTokenString, err := auth.SignToken(keyStore, authConfig, jwt.MapClaims{
+				"sub":  uuid.Must(uuid.NewRandom()),
+				"exp":  time.Now().Add(time.Hour).Unix(),
+				"iat":  time.Now().Unix(),
+				"kind": "refresh",
+			})
+			Expect(err).To(BeNil())
+
+			// Act
+			token, err := service.RefreshAccessToken(refreshTokenString)
+
+			// Assert
+			Expect(token).To(BeNil())
+			Expect(err).To(MatchError(auth.ErrInvalidToken))
+		})
 	})
 })
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+)
+
+/* Bound of the cached sessions, the cache starts over when the sessions checked within a TTL exceed it */
+const maxSessionCacheEntries = 10000
+
+// SessionCache keeps the validity of the sessions checked by the authorizer for a short time,
+// it lives as long as the Lambda container so that warm invocations skip the database.
+// The expired entries are swept at most once per TTL.
+type SessionCache struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	entries   map[sessionCacheKey]sessionCacheEntry
+	lastSweep time.Time
+}
+
+type sessionCacheKey struct {
+	userID    uuid.UUID
+	sessionID uuid.UUID
+}
+
+type sessionCacheEntry struct {
+	isActive  bool
+	expiresAt time.Time
+}
+
+// NewSessionCache creates a new session cache, a zero TTL disables the cache.
+func NewSessionCache(ttl time.Duration) *SessionCache {
+	return &SessionCache{
+		ttl:     ttl,
+		entries: map[sessionCacheKey]sessionCacheEntry{},
+	}
+}
+
+// IsSessionActive checks the session is not expired and the user is active, using the cached result when still fresh.
+func (c *SessionCache) IsSessionActive(userService user.Service, userID uuid.UUID, sessionID uuid.UUID) (bool, error) {
+	key := sessionCacheKey{userID: userID, sessionID: sessionID}
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && !now.Before(entry.expiresAt) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if ok {
+		return entry.isActive, nil
+	}
+
+	isActive, err := userService.IsSessionActive(userID, sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	if c.ttl > 0 {
+		c.mu.Lock()
+		c.sweep(now)
+		c.entries[key] = sessionCacheEntry{isActive: isActive, expiresAt: now.Add(c.ttl)}
+		c.mu.Unlock()
+	}
+
+	return isActive, nil
+}
+
+// Len returns the number of cached sessions, the expired ones not swept yet included.
+func (c *SessionCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+/* Delete the expired entries once per TTL, or all of them past the bound. Called with the lock held */
+func (c *SessionCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) >= c.ttl {
+		for key, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	if len(c.entries) >= maxSessionCacheEntries {
+		c.entries = map[sessionCacheKey]sessionCacheEntry{}
+	}
+}
@@ -0,0 +1,105 @@
+package auth_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+
+	"github.com/pietro-putelli/feynman-backend/internal/auth"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+)
+
+var _ = Describe("Session Cache", func() {
+	var (
+		userService *user.MockService
+		userID      uuid.UUID
+		sessionID   uuid.UUID
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		userService = user.NewMockService(ctrl)
+		userID = uuid.Must(uuid.NewRandom())
+		sessionID = uuid.Must(uuid.NewRandom())
+	})
+
+	It("should check the session only once within the TTL", func() {
+		// Arrange
+		cache := auth.NewSessionCache(time.Minute)
+		userService.EXPECT().IsSessionActive(userID, sessionID).Return(true, nil).Times(1)
+
+		// Act
+		first, err := cache.IsSessionActive(userService, userID, sessionID)
+		Expect(err).To(BeNil())
+		second, err := cache.IsSessionActive(userService, userID, sessionID)
+		Expect(err).To(BeNil())
+
+		// Assert
+		Expect(first).To(BeTrue())
+		Expect(second).To(BeTrue())
+	})
+
+	It("should cache revoked sessions too", func() {
+		// Arrange
+		cache := auth.NewSessionCache(time.Minute)
+		userService.EXPECT().IsSessionActive(userID, sessionID).Return(false, nil).Times(1)
+
+		// Act
+		first, _ := cache.IsSessionActive(userService, userID, sessionID)
+		second, _ := cache.IsSessionActive(userService, userID, sessionID)
+
+		// Assert
+		Expect(first).To(BeFalse())
+		Expect(second).To(BeFalse())
+	})
+
+	It("should check the session every time when the cache is disabled", func() {
+		// Arrange
+		cache := auth.NewSessionCache(0)
+		userService.EXPECT().IsSessionActive(userID, sessionID).Return(true, nil).Times(2)
+
+		// Act
+		_, _ = cache.IsSessionActive(userService, userID, sessionID)
+		_, _ = cache.IsSessionActive(userService, userID, sessionID)
+	})
+
+	It("should not cache lookup errors", func() {
+		// Arrange
+		cache := auth.NewSessionCache(time.Minute)
+		gomock.InOrder(
+			userService.EXPECT().IsSessionActive(userID, sessionID).Return(false, errors.New("connection refused")),
+			userService.EXPECT().IsSessionActive(userID, sessionID).Return(true, nil),
+		)
+
+		// Act
+		_, err := cache.IsSessionActive(userService, userID, sessionID)
+		isActive, retryErr := cache.IsSessionActive(userService, userID, sessionID)
+
+		// Assert
+		Expect(err).To(HaveOccurred())
+		Expect(retryErr).To(BeNil())
+		Expect(isActive).To(BeTrue())
+	})
+
+	It("should evict the expired sessions", func() {
+		// Arrange
+		cache := auth.NewSessionCache(10 * time.Millisecond)
+		otherSessionID := uuid.Must(uuid.NewRandom())
+		userService.EXPECT().IsSessionActive(userID, gomock.Any()).Return(true, nil).Times(3)
+
+		_, _ = cache.IsSessionActive(userService, userID, sessionID)
+		_, _ = cache.IsSessionActive(userService, userID, otherSessionID)
+		Expect(cache.Len()).To(Equal(2))
+
+		// Act
+		time.Sleep(20 * time.Millisecond)
+		_, _ = cache.IsSessionActive(userService, userID, sessionID)
+
+		// Assert
+		Expect(cache.Len()).To(Equal(1))
+	})
+})
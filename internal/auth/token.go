@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pietro-putelli/feynman-backend/config"
+)
+
+const (
+	TokenKindAccess  = "access"
+	TokenKindRefresh = "refresh"
+)
+
+// legacyIssuer is the issuer of the HS256 tokens signed with the shared secret, before the asymmetric keys.
+const legacyIssuer = "panta.srvless-api"
+
+// ErrInvalidToken is returned when the token cannot be verified, has expired or is not of the expected kind.
+var ErrInvalidToken = errors.New("invalid token")
+
+// SignToken signs the claims with the signing key of the store, the issuer and the audience are set from the configuration.
+func SignToken(keyStore KeyStore, jwtConfig *config.AuthJwt, claims jwt.MapClaims) (string, error) {
+	key, err := keyStore.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	claims["iss"] = jwtConfig.Issuer
+	claims["aud"] = jwtConfig.Audience
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.PrivateKey)
+}
+
+// ParseToken verifies the token with the key of its kid header and checks its issuer, audience and kind.
+//
+// Tokens without kid are the legacy HS256 ones, accepted only while the shared secret is configured.
+func ParseToken(keyStore KeyStore, jwtConfig *config.AuthJwt, tokenString string, kind string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		if kid == "" {
+			if jwtConfig.Secret != "" && token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+				return []byte(jwtConfig.Secret), nil
+			}
+			return nil, ErrInvalidToken
+		}
+
+		keys, err := keyStore.VerificationKeys()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			if key.ID == kid && key.Algorithm == token.Method.Alg() {
+				return key.PublicKey, nil
+			}
+		}
+
+		return nil, ErrInvalidToken
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	issuer, _ := claims.GetIssuer()
+
+	if _, ok := token.Header["kid"]; !ok {
+		if issuer != legacyIssuer {
+			return nil, ErrInvalidToken
+		}
+	} else {
+		audience, _ := claims.GetAudience()
+		if issuer != jwtConfig.Issuer || !slices.Contains(audience, jwtConfig.Audience) {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	if claims["kind"] != kind {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/pietro-putelli/feynman-backend/config"
 	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/entitlement"
 	"github.com/pietro-putelli/feynman-backend/internal/user"
 	"gorm.io/gorm"
 )
@@ -28,8 +29,9 @@ func NewContext() (*Context, error) {
 	}
 
 	userService := user.NewService(database)
+	entitlementService := entitlement.NewService(database, userService, &config.Entitlements)
 
-	service := NewService(database, userService)
+	service := NewService(database, userService, entitlementService)
 
 	return &Context{
 		Service:  service,
@@ -0,0 +1,318 @@
+package book
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/sqs"
+	"github.com/pietro-putelli/feynman-backend/langchain"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* Maximum size of the picks sent in a single summarization request, roughly 4 characters per token */
+const digestChunkTokens = 3000
+
+/* A digest queued for longer has failed, it is queued again on the next request */
+const digestGenerationTimeout = 15 * time.Minute
+
+// PicksHash identifies a pick set, it changes whenever a pick is added, removed or edited.
+func PicksHash(picks []domain.BookPick) string {
+	hash := sha256.New()
+	for _, pick := range picks {
+		hash.Write([]byte(pick.Guid.String()))
+		hash.Write([]byte{0})
+		hash.Write([]byte(pick.ContentText))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ChunkPicks splits the picks in chunks that fit the token budget, a pick is never split.
+func ChunkPicks(picks []domain.BookPick, maxTokens int) [][]domain.BookPick {
+	chunks := [][]domain.BookPick{}
+	current := []domain.BookPick{}
+	currentTokens := 0
+
+	for _, pick := range picks {
+		tokens := (len([]rune(pick.ContentText)) + 3) / 4
+
+		if len(current) > 0 && currentTokens+tokens > maxTokens {
+			chunks = append(chunks, current)
+			current = []domain.BookPick{}
+			currentTokens = 0
+		}
+
+		current = append(current, pick)
+		currentTokens += tokens
+	}
+
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+/* Get the digest of the book, it is stale when the picks have changed since and no new digest is queued */
+func (service *serviceImpl) GetBookDigest(userID, bookID uuid.UUID) (*domain.BookDigestResponse, error) {
+	digest, picksHash, err := service.currentBookDigest(userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.BookDigestResponseFromModel(digest, digestStatus(digest, picksHash, time.Now())), nil
+}
+
+/* Queue the digest of the current picks of the book, the AI call is counted only when it is queued */
+func (service *serviceImpl) RequestBookDigest(userID, bookID uuid.UUID) (*domain.BookDigestResponse, error) {
+	digest, picksHash, err := service.currentBookDigest(userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	/* Up to date or already queued, queued again only when the generation seems lost */
+	if status := digestStatus(digest, picksHash, time.Now()); status != domain.BookDigestStatusStale {
+		return domain.BookDigestResponseFromModel(digest, status), nil
+	}
+
+	if err := service.entitlements.UseAICall(userID); err != nil {
+		return nil, err
+	}
+
+	if err := sqs.SendMessage(sqs.QueueNames.BookDigests, domain.BookDigestMessage{BookID: digest.BookID, UserGuid: userID}); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	digest.PendingHash = &picksHash
+	digest.RequestedAt = &now
+
+	/* Concurrent first requests insert the same book, the latest request wins. The generation time is kept */
+	pending := domain.BookDigest{
+		BookID:      digest.BookID,
+		UserID:      digest.UserID,
+		KeyIdeas:    []domain.BookDigestKeyIdea{},
+		PendingHash: digest.PendingHash,
+		RequestedAt: digest.RequestedAt,
+	}
+
+	err = service.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "book_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"pending_hash", "requested_at"}),
+	}).Create(&pending).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.BookDigestResponseFromModel(digest, domain.BookDigestStatusPending), nil
+}
+
+/* The stored digest of the book, empty when none has been requested yet, with the hash of the current picks */
+func (service *serviceImpl) currentBookDigest(userID, bookID uuid.UUID) (*domain.BookDigest, string, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	book := domain.Book{}
+	if err := service.db.Model(&domain.Book{}).Where("guid = ? AND user_id = ?", bookID, user.ID).First(&book).Error; err != nil {
+		return nil, "", err
+	}
+
+	picks := []domain.BookPick{}
+	if err := service.db.Model(&domain.BookPick{}).Where("book_id = ?", book.ID).Order("index ASC").Find(&picks).Error; err != nil {
+		return nil, "", err
+	}
+
+	if len(picks) == 0 {
+		return nil, "", gorm.ErrRecordNotFound
+	}
+
+	digest := domain.BookDigest{BookID: book.ID, UserID: user.ID, KeyIdeas: []domain.BookDigestKeyIdea{}}
+	err = service.db.Where("book_id = ?", book.ID).First(&digest).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", err
+	}
+
+	return &digest, PicksHash(picks), nil
+}
+
+/* Ready when generated from the current picks, pending while they are queued, stale otherwise */
+func digestStatus(digest *domain.BookDigest, picksHash string, now time.Time) string {
+	if digest.PicksHash == picksHash {
+		return domain.BookDigestStatusReady
+	}
+
+	if isDigestQueued(digest, picksHash, now) {
+		return domain.BookDigestStatusPending
+	}
+
+	return domain.BookDigestStatusStale
+}
+
+/* Whether the digest of the pick set has been queued within the timeout of the generation */
+func isDigestQueued(digest *domain.BookDigest, picksHash string, now time.Time) bool {
+	if digest.PendingHash == nil || *digest.PendingHash != picksHash || digest.RequestedAt == nil {
+		return false
+	}
+
+	return now.Sub(*digest.RequestedAt) < digestGenerationTimeout
+}
+
+/* Generate the digest of the current picks of the book, called by the consumer of the digests queue */
+func (service *serviceImpl) GenerateBookDigest(bookID uint) error {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	book := domain.Book{}
+	if err := service.db.Model(&domain.Book{}).Where("id = ?", bookID).First(&book).Error; err != nil {
+		return err
+	}
+
+	picks := []domain.BookPick{}
+	if err := service.db.Model(&domain.BookPick{}).Where("book_id = ?", book.ID).Order("index ASC").Find(&picks).Error; err != nil {
+		return err
+	}
+
+	if len(picks) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	picksHash := PicksHash(picks)
+
+	digest := domain.BookDigest{BookID: book.ID, UserID: book.UserID}
+	err := service.db.Where("book_id = ?", book.ID).First(&digest).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	/* A duplicate message of a digest already generated */
+	if digest.PicksHash == picksHash {
+		return nil
+	}
+
+	summary, keyIdeas, err := generateDigest(book.Title, picks)
+	if err != nil {
+		logger.Error("Failed to generate book digest", zap.Error(err))
+		return err
+	}
+
+	generated := domain.BookDigest{
+		BookID:    book.ID,
+		UserID:    book.UserID,
+		PicksHash: picksHash,
+		Summary:   summary,
+		KeyIdeas:  keyIdeas,
+	}
+
+	return service.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "book_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"picks_hash", "summary", "key_ideas", "pending_hash", "requested_at", "updated_at"}),
+	}).Create(&generated).Error
+}
+
+/* Map: summarize each chunk of picks. Reduce: merge the ideas of all chunks when there is more than one */
+func generateDigest(bookTitle string, picks []domain.BookPick) (string, []domain.BookDigestKeyIdea, error) {
+	chunks := ChunkPicks(picks, digestChunkTokens)
+
+	summaries := []string{}
+	ideas := []domain.BookDigestKeyIdea{}
+
+	for _, chunk := range chunks {
+		lines := make([]string, len(chunk))
+		for i, pick := range chunk {
+			lines[i] = fmt.Sprintf("[%d] %s", i+1, strings.TrimSpace(pick.ContentText))
+		}
+
+		response, err := langchain.SummarizeBookPicks(bookTitle, strings.Join(lines, "\n"))
+		if err != nil {
+			return "", nil, err
+		}
+
+		summary, chunkIdeas, err := parseDigestIdeas(response, "picks", func(index int) []uuid.UUID {
+			if index < 1 || index > len(chunk) {
+				return nil
+			}
+			return []uuid.UUID{chunk[index-1].Guid}
+		})
+		if err != nil {
+			return "", nil, err
+		}
+
+		summaries = append(summaries, summary)
+		ideas = append(ideas, chunkIdeas...)
+	}
+
+	if len(chunks) == 1 {
+		return summaries[0], ideas, nil
+	}
+
+	lines := make([]string, len(ideas))
+	for i, idea := range ideas {
+		lines[i] = fmt.Sprintf("[%d] %s: %s", i+1, idea.Title, idea.Description)
+	}
+
+	response, err := langchain.MergeBookIdeas(bookTitle, strings.Join(lines, "\n"))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return parseDigestIdeas(response, "sources", func(index int) []uuid.UUID {
+		if index < 1 || index > len(ideas) {
+			return nil
+		}
+		return ideas[index-1].PickIDs
+	})
+}
+
+/* Parse the LLM response, the numbers referenced by each idea are resolved to pick GUIDs */
+func parseDigestIdeas(response map[string]interface{}, referencesKey string, resolve func(int) []uuid.UUID) (string, []domain.BookDigestKeyIdea, error) {
+	summary, _ := response["summary"].(string)
+
+	rawIdeas, ok := response["ideas"].([]interface{})
+	if !ok {
+		return "", nil, errors.New("unable to parse response")
+	}
+
+	ideas := []domain.BookDigestKeyIdea{}
+
+	for _, rawIdea := range rawIdeas {
+		values, ok := rawIdea.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		idea := domain.BookDigestKeyIdea{PickIDs: []uuid.UUID{}}
+		idea.Title, _ = values["title"].(string)
+		idea.Description, _ = values["description"].(string)
+
+		seen := map[uuid.UUID]bool{}
+		references, _ := values[referencesKey].([]interface{})
+		for _, reference := range references {
+			number, ok := reference.(float64)
+			if !ok {
+				continue
+			}
+			for _, pickID := range resolve(int(number)) {
+				if !seen[pickID] {
+					seen[pickID] = true
+					idea.PickIDs = append(idea.PickIDs, pickID)
+				}
+			}
+		}
+
+		if idea.Title != "" {
+			ideas = append(ideas, idea)
+		}
+	}
+
+	return summary, ideas, nil
+}
@@ -0,0 +1,204 @@
+package book_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"gorm.io/driver/postgres"
+
+	"github.com/pietro-putelli/feynman-backend/internal/book"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/entitlement"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+)
+
+var _ = Describe("Digest", func() {
+	newPick := func(content string) domain.BookPick {
+		return domain.BookPick{
+			Guid:        uuid.Must(uuid.NewRandom()),
+			ContentText: content,
+		}
+	}
+
+	Describe("PicksHash", func() {
+		It("should be stable for the same picks", func() {
+			// Arrange
+			picks := []domain.BookPick{newPick("first"), newPick("second")}
+
+			// Act
+			first := book.PicksHash(picks)
+			second := book.PicksHash(picks)
+
+			// Assert
+			Expect(first).To(Equal(second))
+			Expect(first).To(HaveLen(64))
+		})
+
+		It("should change when a pick is edited", func() {
+			// Arrange
+			picks := []domain.BookPick{newPick("first"), newPick("second")}
+			previous := book.PicksHash(picks)
+
+			// Act
+			picks[1].ContentText = "second, edited"
+
+			// Assert
+			Expect(book.PicksHash(picks)).NotTo(Equal(previous))
+		})
+
+		It("should change when a pick is removed", func() {
+			// Arrange
+			picks := []domain.BookPick{newPick("first"), newPick("second")}
+
+			// Act
+			result := book.PicksHash(picks[:1])
+
+			// Assert
+			Expect(result).NotTo(Equal(book.PicksHash(picks)))
+		})
+	})
+
+	Describe("ChunkPicks", func() {
+		It("should keep all picks in a single chunk when they fit", func() {
+			// Arrange
+			picks := []domain.BookPick{newPick("first"), newPick("second")}
+
+			// Act
+			chunks := book.ChunkPicks(picks, 100)
+
+			// Assert
+			Expect(chunks).To(HaveLen(1))
+			Expect(chunks[0]).To(HaveLen(2))
+		})
+
+		It("should split the picks exceeding the budget without splitting a pick", func() {
+			// Arrange
+			picks := []domain.BookPick{
+				newPick(strings.Repeat("a", 200)),
+				newPick(strings.Repeat("b", 200)),
+				newPick(strings.Repeat("c", 1000)),
+			}
+
+			// Act
+			chunks := book.ChunkPicks(picks, 100)
+
+			// Assert
+			Expect(chunks).To(HaveLen(2))
+			Expect(chunks[0]).To(HaveLen(2))
+			Expect(chunks[1]).To(HaveLen(1))
+			Expect(chunks[1][0].Guid).To(Equal(picks[2].Guid))
+		})
+	})
+
+	Describe("GetBookDigest", func() {
+		var (
+			service      book.Service
+			sqlMock      sqlmock.Sqlmock
+			entitlements *entitlement.MockService
+			userID       uuid.UUID
+			picksHash    string
+		)
+
+		digestColumns := []string{"id", "book_id", "user_id", "picks_hash", "summary", "key_ideas", "pending_hash", "requested_at"}
+
+		BeforeEach(func() {
+			db, sqlMockGen, _ := sqlmock.New()
+			sqlMock = sqlMockGen
+
+			conn := postgres.New(postgres.Config{
+				Conn: db,
+			})
+
+			database, _ := database.NewDB(conn)
+
+			ctrl := gomock.NewController(GinkgoT())
+			userID = uuid.New()
+
+			userService := user.NewMockService(ctrl)
+			userService.EXPECT().GetUserByGuid(userID).Return(&domain.User{ID: 7, Guid: userID}, nil)
+
+			/* The quota is only used when a digest is queued, never by the specs below */
+			entitlements = entitlement.NewMockService(ctrl)
+			entitlements.EXPECT().UseAICall(gomock.Any()).Times(0)
+
+			service = book.NewService(database, userService, entitlements)
+
+			pick := newPick("first")
+			picksHash = book.PicksHash([]domain.BookPick{pick})
+
+			sqlMock.ExpectQuery(`SELECT \* FROM "books" WHERE guid = \$1 AND user_id = \$2`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(3, "Title"))
+			sqlMock.ExpectQuery(`SELECT \* FROM "book_picks" WHERE book_id = \$1`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "guid", "content_text"}).AddRow(1, pick.Guid, pick.ContentText))
+		})
+
+		It("should return the cached digest of the same picks", func() {
+			// Arrange
+			sqlMock.ExpectQuery(`SELECT \* FROM "book_digests" WHERE book_id = \$1`).
+				WillReturnRows(sqlmock.NewRows(digestColumns).
+					AddRow(1, 3, 7, picksHash, "summary", []byte(`[]`), nil, nil))
+
+			// Act
+			digest, err := service.GetBookDigest(userID, uuid.New())
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(digest.Status).To(Equal(domain.BookDigestStatusReady))
+			Expect(digest.Summary).To(Equal("summary"))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should return the previous digest as pending while the new one is generated", func() {
+			// Arrange
+			sqlMock.ExpectQuery(`SELECT \* FROM "book_digests" WHERE book_id = \$1`).
+				WillReturnRows(sqlmock.NewRows(digestColumns).
+					AddRow(1, 3, 7, "previous", "summary", []byte(`[]`), picksHash, time.Now().Add(-time.Minute)))
+
+			// Act
+			digest, err := service.GetBookDigest(userID, uuid.New())
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(digest.Status).To(Equal(domain.BookDigestStatusPending))
+			Expect(digest.Summary).To(Equal("summary"))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should return the previous digest as stale without queueing a new one", func() {
+			// Arrange
+			sqlMock.ExpectQuery(`SELECT \* FROM "book_digests" WHERE book_id = \$1`).
+				WillReturnRows(sqlmock.NewRows(digestColumns).
+					AddRow(1, 3, 7, "previous", "summary", []byte(`[]`), nil, nil))
+
+			// Act
+			digest, err := service.GetBookDigest(userID, uuid.New())
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(digest.Status).To(Equal(domain.BookDigestStatusStale))
+			Expect(digest.Summary).To(Equal("summary"))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should not queue again a digest already requested", func() {
+			// Arrange
+			sqlMock.ExpectQuery(`SELECT \* FROM "book_digests" WHERE book_id = \$1`).
+				WillReturnRows(sqlmock.NewRows(digestColumns).
+					AddRow(1, 3, 7, "previous", "summary", []byte(`[]`), picksHash, time.Now().Add(-time.Minute)))
+
+			// Act
+			digest, err := service.RequestBookDigest(userID, uuid.New())
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(digest.Status).To(Equal(domain.BookDigestStatusPending))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,107 @@
+package book
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+// ExportBooks returns the whole library of the user, every book with its topics and picks.
+// The plan of the user must allow the format, see ExportMarkdown for the Markdown one.
+func (service *serviceImpl) ExportBooks(userID uuid.UUID, format string) ([]domain.BookExport, error) {
+	if err := service.entitlements.CheckExportFormat(userID, format); err != nil {
+		return nil, err
+	}
+
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	books := []domain.Book{}
+	if err := service.db.Where("user_id = ?", user.ID).Order("created_at ASC").Find(&books).Error; err != nil {
+		return nil, err
+	}
+
+	var topics []struct {
+		BookID uint
+		Topic  string
+	}
+	err = service.db.Table("book_topics").
+		Select("book_topics.book_id, topics.topic").
+		Joins("JOIN topics ON topics.id = book_topics.topic_id").
+		Where("topics.user_id = ?", user.ID).
+		Scan(&topics).Error
+	if err != nil {
+		return nil, err
+	}
+
+	picks := []domain.BookPick{}
+	if err := service.db.Where("user_id = ?", user.ID).Order("book_id ASC, index ASC").Find(&picks).Error; err != nil {
+		return nil, err
+	}
+
+	exports := make([]domain.BookExport, len(books))
+	indexes := map[uint]int{}
+
+	for i, book := range books {
+		indexes[book.ID] = i
+		exports[i] = domain.BookExport{
+			Guid:      book.Guid,
+			Title:     book.Title,
+			Author:    book.Author,
+			CreatedAt: book.CreatedAt,
+			Topics:    []string{},
+			Picks:     []domain.BookPickExport{},
+		}
+	}
+
+	for _, topic := range topics {
+		if i, ok := indexes[topic.BookID]; ok {
+			exports[i].Topics = append(exports[i].Topics, topic.Topic)
+		}
+	}
+
+	for _, pick := range picks {
+		i, ok := indexes[pick.BookID]
+		if !ok {
+			continue
+		}
+
+		exports[i].Picks = append(exports[i].Picks, domain.BookPickExport{
+			Guid:        pick.Guid,
+			Title:       pick.Title,
+			Content:     json.RawMessage(pick.Content),
+			ContentText: pick.ContentText,
+			Index:       pick.Index,
+			CreatedAt:   pick.CreatedAt,
+			UpdatedAt:   pick.UpdatedAt,
+		})
+	}
+
+	return exports, nil
+}
+
+// ExportMarkdown renders the library export as a Markdown document, a section for each book with the text of its picks.
+func ExportMarkdown(books []domain.BookExport) string {
+	var builder strings.Builder
+
+	for _, book := range books {
+		fmt.Fprintf(&builder, "# %s\n\n", book.Title)
+		if book.Author != "" {
+			fmt.Fprintf(&builder, "_%s_\n\n", book.Author)
+		}
+		if len(book.Topics) > 0 {
+			fmt.Fprintf(&builder, "Topics: %s\n\n", strings.Join(book.Topics, ", "))
+		}
+
+		for _, pick := range book.Picks {
+			fmt.Fprintf(&builder, "## %s\n\n%s\n\n", pick.Title, strings.TrimSpace(pick.ContentText))
+		}
+	}
+
+	return strings.TrimSpace(builder.String()) + "\n"
+}
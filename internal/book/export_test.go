@@ -0,0 +1,38 @@
+package book_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pietro-putelli/feynman-backend/internal/book"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+var _ = Describe("Export", func() {
+	Describe("ExportMarkdown", func() {
+		It("should render a section for each book with the text of its picks", func() {
+			// Arrange
+			books := []domain.BookExport{
+				{
+					Title:  "Thinking, Fast and Slow",
+					Author: "Daniel Kahneman",
+					Topics: []string{"psychology", "decisions"},
+					Picks: []domain.BookPickExport{
+						{Title: "Two systems", ContentText: "System 1 is fast. "},
+					},
+				},
+				{Title: "Notes", Picks: []domain.BookPickExport{}},
+			}
+
+			// Act
+			result := book.ExportMarkdown(books)
+
+			// Assert
+			Expect(result).To(Equal("# Thinking, Fast and Slow\n\n" +
+				"_Daniel Kahneman_\n\n" +
+				"Topics: psychology, decisions\n\n" +
+				"## Two systems\n\nSystem 1 is fast.\n\n" +
+				"# Notes\n"))
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package book
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/entitlement"
 	"github.com/pietro-putelli/feynman-backend/internal/sqs"
 	"github.com/pietro-putelli/feynman-backend/internal/user"
 	"github.com/pietro-putelli/feynman-backend/internal/utility"
@@ -22,8 +24,16 @@ type Service interface {
 	// GetBookByGuid Get only book entry by guid
 	GetBookByGuid(guid uuid.UUID) (*domain.Book, error)
 
-	// GetCompleteBookByGuid Get complete book by guid (with the initial picks) and user
-	GetCompleteBookByGuid(userID, bookID uuid.UUID) (*domain.BookResponse, error)
+	// GetCompleteBookByGuid Get complete book by guid (with the initial picks) and user, the digest is added when withDigest is set
+	GetCompleteBookByGuid(userID, bookID uuid.UUID, withDigest bool) (*domain.BookResponse, error)
+
+	// GetBookDigest Get the key ideas digest of the book, generated from its picks and cached until they change.
+	// It is pending while a digest of the current picks is generated and stale until one is requested
+	GetBookDigest(userID, bookID uuid.UUID) (*domain.BookDigestResponse, error)
+	// RequestBookDigest Queue the digest of the current picks of the book, unless it is ready or already queued
+	RequestBookDigest(userID, bookID uuid.UUID) (*domain.BookDigestResponse, error)
+	// GenerateBookDigest Generate the digest of the current picks of the book, queued by RequestBookDigest
+	GenerateBookDigest(bookID uint) error
 
 	DeleteBook(userID, bookID uuid.UUID) error
 
@@ -60,18 +70,27 @@ type Service interface {
 
 	// SaveBook Save book to userID's account with book's guid
 	SaveBook(userID uuid.UUID, book *domain.SaveBookBody) (*domain.BookResponse, error)
+
+	// TranslateBookPick Translate a whole pick into the requested language and store it
+	TranslateBookPick(userID uuid.UUID, params *domain.TranslatePickParams) (*domain.PickTranslationResponse, error)
+
+	// ExportBooks Export the whole library of the user in the format, books with their topics and picks
+	ExportBooks(userID uuid.UUID, format string) ([]domain.BookExport, error)
 }
 
 type serviceImpl struct {
-	db          *gorm.DB
-	userService user.Service
+	db           *gorm.DB
+	userService  user.Service
+	entitlements entitlement.Service
 }
 
-// NewService creates a new book service
-func NewService(db *gorm.DB, userService user.Service) Service {
+// NewService creates a new book service, the capabilities limited by the plan of the user are checked
+// with the entitlements service.
+func NewService(db *gorm.DB, userService user.Service, entitlements entitlement.Service) Service {
 	return &serviceImpl{
-		db:          db,
-		userService: userService,
+		db:           db,
+		userService:  userService,
+		entitlements: entitlements,
 	}
 }
 
@@ -89,7 +108,7 @@ func (service *serviceImpl) GetBookByGuid(guid uuid.UUID) (*domain.Book, error)
 	return &book, nil
 }
 
-func (service *serviceImpl) GetCompleteBookByGuid(userID, bookID uuid.UUID) (*domain.BookResponse, error) {
+func (service *serviceImpl) GetCompleteBookByGuid(userID, bookID uuid.UUID, withDigest bool) (*domain.BookResponse, error) {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
@@ -140,6 +159,16 @@ func (service *serviceImpl) GetCompleteBookByGuid(userID, bookID uuid.UUID) (*do
 		return nil, err
 	}
 
+	/* The digest is optional, the book is returned even if it cannot be read */
+	if withDigest {
+		digest, err := service.GetBookDigest(userID, bookID)
+		if err != nil {
+			logger.Error("Failed to get book digest", zap.Error(err))
+		} else {
+			completeBook.Digest = digest
+		}
+	}
+
 	return &completeBook, nil
 }
 
@@ -198,7 +227,18 @@ func (service *serviceImpl) CreateBookPick(userID uuid.UUID, data *domain.Create
 
 	user, _ := service.userService.GetUserByGuid(userID)
 
+	var createdPick *domain.BookPick
+
 	err := service.db.Transaction(func(tx *gorm.DB) error {
+		/* A pick without book creates a new one, the limits are counted in the transaction */
+		if data.BookID == uuid.Nil {
+			if err := service.entitlements.CheckBookLimit(tx, userID); err != nil {
+				return err
+			}
+		} else if err := service.entitlements.CheckPickLimit(tx, userID, data.BookID); err != nil {
+			return err
+		}
+
 		/* 1. Check if BookID is empty, if so create a new book to which associate the pick to */
 
 		if data.BookID == uuid.Nil {
@@ -234,16 +274,7 @@ func (service *serviceImpl) CreateBookPick(userID uuid.UUID, data *domain.Create
 				return err
 			}
 
-			message := domain.BookPickSearchKeywordMessage{
-				PickID:      newPick.ID,
-				PickContent: newPick.ContentText,
-				UserGuid:    userID,
-			}
-
-			err := sqs.SendMessage(sqs.QueueNames.PickKeywords, message)
-			if err != nil {
-				return err
-			}
+			createdPick = &newPick
 
 			topics, err := langchain.GenerateBookTopics(newPick.ContentText)
 			if err != nil {
@@ -345,17 +376,6 @@ func (service *serviceImpl) CreateBookPick(userID uuid.UUID, data *domain.Create
 				UserID: user.ID,
 			}
 
-			message := domain.BookPickSearchKeywordMessage{
-				PickID:      newPick.ID,
-				PickContent: newPick.ContentText,
-				UserGuid:    user.Guid,
-			}
-
-			err := sqs.SendMessage(sqs.QueueNames.PickKeywords, message)
-			if err != nil {
-				return err
-			}
-
 			// /* If the index is not the last one, we need to update the indexes of the following picks to keep track of their order */
 
 			// if lastPickIndex != uint64(index) {}
@@ -366,6 +386,8 @@ func (service *serviceImpl) CreateBookPick(userID uuid.UUID, data *domain.Create
 				return err
 			}
 
+			createdPick = &newPick
+
 			var topics []domain.BookTopicResponse
 			tx.Model(&domain.Topic{}).
 				Select("topic, color").
@@ -388,9 +410,15 @@ func (service *serviceImpl) CreateBookPick(userID uuid.UUID, data *domain.Create
 
 	if err != nil {
 		logger.Error("Failed to create book or pick (1)", zap.Error(err))
+		return response, err
 	}
 
-	return response, err
+	/* The pick is committed, the consumers can find it. It is kept even if its keywords and cards cannot be queued */
+	if err := sendPickMessages(createdPick.ID, createdPick.ContentText, userID); err != nil {
+		logger.Error("Failed to queue pick keywords and cards", zap.Error(err))
+	}
+
+	return response, nil
 }
 
 func (service *serviceImpl) GetShortBooksList(userID uuid.UUID, params *domain.BookListParams) ([]domain.ShortBookResponse, error) {
@@ -550,10 +578,60 @@ func (service *serviceImpl) GetPicksByBook(params *domain.GetPicksParams) ([]dom
 
 		return err
 	})
+	if err != nil {
+		return picks, err
+	}
+
+	/* Attach the stored translations when a target language is requested */
+	if params.Lang != "" && len(picks) > 0 {
+		err = service.attachPickTranslations(picks, normaliseLanguage(params.Lang))
+	}
 
 	return picks, err
 }
 
+type pickTranslationRow struct {
+	PickGuid    uuid.UUID
+	Language    string
+	Content     *string
+	ContentText string
+}
+
+func (service *serviceImpl) attachPickTranslations(picks []domain.BookPickResponse, language string) error {
+	pickIDs := make([]uuid.UUID, len(picks))
+	for i, pick := range picks {
+		pickIDs[i] = pick.Guid
+	}
+
+	rows := []pickTranslationRow{}
+	err := service.db.Table("pick_translations").
+		Select("book_picks.guid AS pick_guid, pick_translations.language, pick_translations.content, pick_translations.content_text").
+		Joins("JOIN book_picks ON book_picks.id = pick_translations.pick_id").
+		Where("book_picks.guid IN ? AND pick_translations.language = ?", pickIDs, language).
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	translations := make(map[uuid.UUID]pickTranslationRow, len(rows))
+	for _, row := range rows {
+		translations[row.PickGuid] = row
+	}
+
+	for i, pick := range picks {
+		if row, ok := translations[pick.Guid]; ok {
+			picks[i].Translation = &domain.PickTranslationResponse{
+				PickID:      pick.Guid,
+				Lang:        row.Language,
+				Content:     row.Content,
+				ContentText: row.ContentText,
+			}
+		}
+	}
+
+	return nil
+}
+
 /* Get books' topics */
 
 func (service *serviceImpl) GetUserBooksTopics(userID uuid.UUID) ([]domain.BookTopicListResponse, error) {
@@ -689,8 +767,9 @@ func (service *serviceImpl) EditBookPick(userID uuid.UUID, body *domain.EditBook
 	}
 
 	pick := domain.BookPick{}
+	isContentChanged := false
 
-	return service.db.Transaction(func(tx *gorm.DB) error {
+	err := service.db.Transaction(func(tx *gorm.DB) error {
 
 		bookId := body.BookId
 
@@ -701,15 +780,13 @@ func (service *serviceImpl) EditBookPick(userID uuid.UUID, body *domain.EditBook
 			return err
 		}
 
-		/* If at least 20% of the content has changed, re-generate pick's search keywords */
-		if utility.AtLeast20PercentChanged(pick.ContentText, body.Text) {
-			message := domain.BookPickSearchKeywordMessage{
-				PickID:      pick.ID,
-				PickContent: body.Text,
-				UserGuid:    userID,
-			}
+		/* If at least 20% of the content has changed, re-generate pick's search keywords and flashcards. The edits
+		of the title or the rich content only have no text */
+		isContentChanged = body.Text != "" && utility.AtLeast20PercentChanged(pick.ContentText, body.Text)
 
-			err = sqs.SendMessage(sqs.QueueNames.PickKeywords, message)
+		if isContentChanged {
+			/* The stored translations no longer match the pick, they will be generated again on request */
+			err = tx.Where("pick_id = ?", pick.ID).Delete(&domain.PickTranslation{}).Error
 			if err != nil {
 				return err
 			}
@@ -722,6 +799,38 @@ func (service *serviceImpl) EditBookPick(userID uuid.UUID, body *domain.EditBook
 
 		return nil
 	})
+	if err != nil || !isContentChanged {
+		return err
+	}
+
+	/* Queued once the new content is committed */
+	return sendPickMessages(pick.ID, body.Text, userID)
+}
+
+/* Ask the CreatePickKeywordsFun and CreatePickCardsFun consumers to generate the pick's keywords and flashcards */
+func sendPickMessages(pickID uint, content string, userID uuid.UUID) error {
+	message := domain.BookPickSearchKeywordMessage{
+		PickID:      pickID,
+		PickContent: content,
+		UserGuid:    userID,
+	}
+
+	if err := sqs.SendMessage(sqs.QueueNames.PickKeywords, message); err != nil {
+		return err
+	}
+
+	return sendPickCardsMessage(pickID, content, userID)
+}
+
+/* Ask the CreatePickCardsFun consumer to generate the pick's flashcards */
+func sendPickCardsMessage(pickID uint, content string, userID uuid.UUID) error {
+	message := domain.BookPickCardsMessage{
+		PickID:      pickID,
+		PickContent: content,
+		UserGuid:    userID,
+	}
+
+	return sqs.SendMessage(sqs.QueueNames.PickCards, message)
 }
 
 /* Add pick's keywords in database */
@@ -753,6 +862,10 @@ func (service *serviceImpl) AddPickKeywords(userID uuid.UUID, pickID uint, keywo
 }
 
 func (service *serviceImpl) SemanticSearch(userID uuid.UUID, params *domain.SearchGetParams) ([]domain.SemanticSearchResponse, error) {
+	if err := service.entitlements.CheckSearchMode(userID, domain.SearchModeLibrary); err != nil {
+		return nil, err
+	}
+
 	user, err := service.userService.GetUserByGuid(userID)
 	if err != nil {
 		return nil, err
@@ -832,6 +945,10 @@ func (service *serviceImpl) SaveBook(userID uuid.UUID, body *domain.SaveBookBody
 	response := domain.BookResponse{}
 
 	err = service.db.Transaction(func(tx *gorm.DB) error {
+		if err := service.entitlements.CheckBookLimit(tx, userID); err != nil {
+			return err
+		}
+
 		newBook := domain.Book{
 			UserID: user.ID,
 			Title:  book.Title,
@@ -948,3 +1065,94 @@ func (service *serviceImpl) SaveBook(userID uuid.UUID, body *domain.SaveBookBody
 
 	return &response, err
 }
+
+/* Translate a pick, the stored translation is returned when it is already available */
+func (service *serviceImpl) TranslateBookPick(userID uuid.UUID, params *domain.TranslatePickParams) (*domain.PickTranslationResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pick := domain.BookPick{}
+	err = service.db.Model(&domain.BookPick{}).
+		Where("book_id = (SELECT id FROM books WHERE guid = ?) AND guid = ? AND user_id = ?", params.BookID, params.PickID, user.ID).
+		First(&pick).Error
+	if err != nil {
+		return nil, err
+	}
+
+	language := normaliseLanguage(params.Lang)
+
+	translation := domain.PickTranslation{}
+	err = service.db.Where("pick_id = ? AND language = ?", pick.ID, language).First(&translation).Error
+	if err == nil {
+		return domain.PickTranslationResponseFromModel(pick.Guid, &translation), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := service.entitlements.UseAICall(userID); err != nil {
+		return nil, err
+	}
+
+	result, err := langchain.TranslatePick(pick.ContentText, pick.Content, language)
+	if err != nil {
+		logger.Error("Failed to translate pick", zap.Error(err))
+		return nil, err
+	}
+
+	contentText, ok := result["contentText"].(string)
+	if !ok || contentText == "" {
+		logger.Error("Unable to parse pick translation", zap.Any("response", result))
+		return nil, errors.New("unable to parse response")
+	}
+
+	translation = domain.PickTranslation{
+		PickID:      pick.ID,
+		UserID:      user.ID,
+		Language:    language,
+		Content:     translatedRichContent(result["content"]),
+		ContentText: contentText,
+	}
+
+	err = service.db.Exec(`
+		INSERT INTO pick_translations (user_id, pick_id, language, content, content_text) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (pick_id, language) DO UPDATE SET content = EXCLUDED.content, content_text = EXCLUDED.content_text, updated_at = CURRENT_TIMESTAMP
+	`, translation.UserID, translation.PickID, translation.Language, translation.Content, translation.ContentText).Error
+	if err != nil {
+		logger.Error("Failed to store pick translation", zap.Error(err))
+		return nil, err
+	}
+
+	return domain.PickTranslationResponseFromModel(pick.Guid, &translation), nil
+}
+
+/* The LLM may return the rich content as an object or as a string, only valid JSON is kept */
+func translatedRichContent(value interface{}) *string {
+	if value == nil {
+		return nil
+	}
+
+	if content, ok := value.(string); ok {
+		if content == "" || !json.Valid([]byte(content)) {
+			return nil
+		}
+		return &content
+	}
+
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+
+	content := string(bytes)
+	return &content
+}
+
+func normaliseLanguage(language string) string {
+	return strings.ToLower(strings.TrimSpace(language))
+}
@@ -0,0 +1,336 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=service.go -destination=./service_mock.go -package=book
+//
+
+// Package book is a generated GoMock package.
+package book
+
+import (
+	reflect "reflect"
+
+	uuid "github.com/google/uuid"
+	domain "github.com/pietro-putelli/feynman-backend/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// AddPickKeywords mocks base method.
+func (m *MockService) AddPickKeywords(userID uuid.UUID, pickID uint, keywords []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPickKeywords", userID, pickID, keywords)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddPickKeywords indicates an expected call of AddPickKeywords.
+func (mr *MockServiceMockRecorder) AddPickKeywords(userID, pickID, keywords any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPickKeywords", reflect.TypeOf((*MockService)(nil).AddPickKeywords), userID, pickID, keywords)
+}
+
+// CreateBookPick mocks base method.
+func (m *MockService) CreateBookPick(userID uuid.UUID, data *domain.CreateBookBody) (any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBookPick", userID, data)
+	ret0, _ := ret[0].(any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateBookPick indicates an expected call of CreateBookPick.
+func (mr *MockServiceMockRecorder) CreateBookPick(userID, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBookPick", reflect.TypeOf((*MockService)(nil).CreateBookPick), userID, data)
+}
+
+// DeleteBook mocks base method.
+func (m *MockService) DeleteBook(userID, bookID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBook", userID, bookID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBook indicates an expected call of DeleteBook.
+func (mr *MockServiceMockRecorder) DeleteBook(userID, bookID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBook", reflect.TypeOf((*MockService)(nil).DeleteBook), userID, bookID)
+}
+
+// DeleteBookPick mocks base method.
+func (m *MockService) DeleteBookPick(userID uuid.UUID, params *domain.DeleteBookPickPath) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBookPick", userID, params)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteBookPick indicates an expected call of DeleteBookPick.
+func (mr *MockServiceMockRecorder) DeleteBookPick(userID, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBookPick", reflect.TypeOf((*MockService)(nil).DeleteBookPick), userID, params)
+}
+
+// EditBook mocks base method.
+func (m *MockService) EditBook(userID uuid.UUID, params *domain.EditBookBody) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EditBook", userID, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EditBook indicates an expected call of EditBook.
+func (mr *MockServiceMockRecorder) EditBook(userID, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EditBook", reflect.TypeOf((*MockService)(nil).EditBook), userID, params)
+}
+
+// EditBookPick mocks base method.
+func (m *MockService) EditBookPick(userID uuid.UUID, body *domain.EditBookPickBody) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EditBookPick", userID, body)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EditBookPick indicates an expected call of EditBookPick.
+func (mr *MockServiceMockRecorder) EditBookPick(userID, body any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EditBookPick", reflect.TypeOf((*MockService)(nil).EditBookPick), userID, body)
+}
+
+// ExportBooks mocks base method.
+func (m *MockService) ExportBooks(userID uuid.UUID, format string) ([]domain.BookExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportBooks", userID, format)
+	ret0, _ := ret[0].([]domain.BookExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportBooks indicates an expected call of ExportBooks.
+func (mr *MockServiceMockRecorder) ExportBooks(userID, format any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportBooks", reflect.TypeOf((*MockService)(nil).ExportBooks), userID, format)
+}
+
+// GenerateBookDigest mocks base method.
+func (m *MockService) GenerateBookDigest(bookID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateBookDigest", bookID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GenerateBookDigest indicates an expected call of GenerateBookDigest.
+func (mr *MockServiceMockRecorder) GenerateBookDigest(bookID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateBookDigest", reflect.TypeOf((*MockService)(nil).GenerateBookDigest), bookID)
+}
+
+// GetBookByGuid mocks base method.
+func (m *MockService) GetBookByGuid(guid uuid.UUID) (*domain.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBookByGuid", guid)
+	ret0, _ := ret[0].(*domain.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBookByGuid indicates an expected call of GetBookByGuid.
+func (mr *MockServiceMockRecorder) GetBookByGuid(guid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookByGuid", reflect.TypeOf((*MockService)(nil).GetBookByGuid), guid)
+}
+
+// GetBookDigest mocks base method.
+func (m *MockService) GetBookDigest(userID, bookID uuid.UUID) (*domain.BookDigestResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBookDigest", userID, bookID)
+	ret0, _ := ret[0].(*domain.BookDigestResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBookDigest indicates an expected call of GetBookDigest.
+func (mr *MockServiceMockRecorder) GetBookDigest(userID, bookID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookDigest", reflect.TypeOf((*MockService)(nil).GetBookDigest), userID, bookID)
+}
+
+// GetBooks mocks base method.
+func (m *MockService) GetBooks(userID uuid.UUID, params *domain.BookListParams) ([]domain.BookResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBooks", userID, params)
+	ret0, _ := ret[0].([]domain.BookResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBooks indicates an expected call of GetBooks.
+func (mr *MockServiceMockRecorder) GetBooks(userID, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBooks", reflect.TypeOf((*MockService)(nil).GetBooks), userID, params)
+}
+
+// GetCompleteBookByGuid mocks base method.
+func (m *MockService) GetCompleteBookByGuid(userID, bookID uuid.UUID, withDigest bool) (*domain.BookResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCompleteBookByGuid", userID, bookID, withDigest)
+	ret0, _ := ret[0].(*domain.BookResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCompleteBookByGuid indicates an expected call of GetCompleteBookByGuid.
+func (mr *MockServiceMockRecorder) GetCompleteBookByGuid(userID, bookID, withDigest any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompleteBookByGuid", reflect.TypeOf((*MockService)(nil).GetCompleteBookByGuid), userID, bookID, withDigest)
+}
+
+// GetPicksByBook mocks base method.
+func (m *MockService) GetPicksByBook(params *domain.GetPicksParams) ([]domain.BookPickResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPicksByBook", params)
+	ret0, _ := ret[0].([]domain.BookPickResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPicksByBook indicates an expected call of GetPicksByBook.
+func (mr *MockServiceMockRecorder) GetPicksByBook(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPicksByBook", reflect.TypeOf((*MockService)(nil).GetPicksByBook), params)
+}
+
+// GetShortBooksList mocks base method.
+func (m *MockService) GetShortBooksList(userID uuid.UUID, params *domain.BookListParams) ([]domain.ShortBookResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetShortBooksList", userID, params)
+	ret0, _ := ret[0].([]domain.ShortBookResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetShortBooksList indicates an expected call of GetShortBooksList.
+func (mr *MockServiceMockRecorder) GetShortBooksList(userID, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShortBooksList", reflect.TypeOf((*MockService)(nil).GetShortBooksList), userID, params)
+}
+
+// GetUserBooksTopics mocks base method.
+func (m *MockService) GetUserBooksTopics(userID uuid.UUID) ([]domain.BookTopicListResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserBooksTopics", userID)
+	ret0, _ := ret[0].([]domain.BookTopicListResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserBooksTopics indicates an expected call of GetUserBooksTopics.
+func (mr *MockServiceMockRecorder) GetUserBooksTopics(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserBooksTopics", reflect.TypeOf((*MockService)(nil).GetUserBooksTopics), userID)
+}
+
+// RequestBookDigest mocks base method.
+func (m *MockService) RequestBookDigest(userID, bookID uuid.UUID) (*domain.BookDigestResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestBookDigest", userID, bookID)
+	ret0, _ := ret[0].(*domain.BookDigestResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestBookDigest indicates an expected call of RequestBookDigest.
+func (mr *MockServiceMockRecorder) RequestBookDigest(userID, bookID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestBookDigest", reflect.TypeOf((*MockService)(nil).RequestBookDigest), userID, bookID)
+}
+
+// SaveBook mocks base method.
+func (m *MockService) SaveBook(userID uuid.UUID, book *domain.SaveBookBody) (*domain.BookResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveBook", userID, book)
+	ret0, _ := ret[0].(*domain.BookResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveBook indicates an expected call of SaveBook.
+func (mr *MockServiceMockRecorder) SaveBook(userID, book any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveBook", reflect.TypeOf((*MockService)(nil).SaveBook), userID, book)
+}
+
+// SearchPickInBook mocks base method.
+func (m *MockService) SearchPickInBook(params *domain.SearchGetParams) ([]domain.SearchPickInBookResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchPickInBook", params)
+	ret0, _ := ret[0].([]domain.SearchPickInBookResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchPickInBook indicates an expected call of SearchPickInBook.
+func (mr *MockServiceMockRecorder) SearchPickInBook(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchPickInBook", reflect.TypeOf((*MockService)(nil).SearchPickInBook), params)
+}
+
+// SemanticSearch mocks base method.
+func (m *MockService) SemanticSearch(userID uuid.UUID, params *domain.SearchGetParams) ([]domain.SemanticSearchResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SemanticSearch", userID, params)
+	ret0, _ := ret[0].([]domain.SemanticSearchResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SemanticSearch indicates an expected call of SemanticSearch.
+func (mr *MockServiceMockRecorder) SemanticSearch(userID, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SemanticSearch", reflect.TypeOf((*MockService)(nil).SemanticSearch), userID, params)
+}
+
+// TranslateBookPick mocks base method.
+func (m *MockService) TranslateBookPick(userID uuid.UUID, params *domain.TranslatePickParams) (*domain.PickTranslationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TranslateBookPick", userID, params)
+	ret0, _ := ret[0].(*domain.PickTranslationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TranslateBookPick indicates an expected call of TranslateBookPick.
+func (mr *MockServiceMockRecorder) TranslateBookPick(userID, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TranslateBookPick", reflect.TypeOf((*MockService)(nil).TranslateBookPick), userID, params)
+}
@@ -0,0 +1,191 @@
+package card
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+	"github.com/pietro-putelli/feynman-backend/internal/utility"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ Service = (*serviceImpl)(nil)
+
+type Service interface {
+	// ReplacePickCards Replace the generated cards of a pick with the ones generated from the content, the cards
+	// edited or rejected by the user are kept. Nothing is replaced when the pick has changed since the content
+	ReplacePickCards(userID uuid.UUID, pickID uint, content string, cards []domain.GeneratedPickCard) error
+
+	// GetPickCards Get the cards of a pick
+	GetPickCards(userID uuid.UUID, params *domain.GetPickCardsParams) ([]domain.PickCardResponse, error)
+
+	// EditPickCard Edit the question, the answer or the status of a card
+	EditPickCard(userID uuid.UUID, body *domain.EditPickCardBody) (*domain.PickCardResponse, error)
+}
+
+type serviceImpl struct {
+	db          *gorm.DB
+	userService user.Service
+}
+
+// NewService creates a new card service
+func NewService(db *gorm.DB, userService user.Service) Service {
+	return &serviceImpl{
+		db:          db,
+		userService: userService,
+	}
+}
+
+//---------------------------------------------------------------------
+// Service Implementation
+//---------------------------------------------------------------------
+
+func (service *serviceImpl) ReplacePickCards(userID uuid.UUID, pickID uint, content string, cards []domain.GeneratedPickCard) error {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return err
+	}
+
+	newCards := []domain.PickCard{}
+	for _, card := range cards {
+		kind := strings.ToLower(strings.TrimSpace(card.Kind))
+		if kind != domain.PickCardKindCloze && kind != domain.PickCardKindQuestion {
+			continue
+		}
+
+		if strings.TrimSpace(card.Question) == "" || strings.TrimSpace(card.Answer) == "" {
+			continue
+		}
+
+		newCards = append(newCards, domain.PickCard{
+			PickID:   pickID,
+			UserID:   user.ID,
+			Kind:     kind,
+			Question: strings.TrimSpace(card.Question),
+			Answer:   strings.TrimSpace(card.Answer),
+			Status:   domain.PickCardStatusActive,
+		})
+	}
+
+	return service.db.Transaction(func(tx *gorm.DB) error {
+		/* 1. The pick may have been deleted in the meanwhile, it is locked until the cards are replaced */
+		pick := domain.BookPick{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "content_text").Where("id = ? AND user_id = ?", pickID, user.ID).First(&pick).Error
+		if err != nil {
+			return err
+		}
+
+		/* 2. The text has changed enough to queue its own cards since, these ones are stale */
+		if utility.AtLeast20PercentChanged(content, pick.ContentText) {
+			return nil
+		}
+
+		/* 3. Delete the previously generated cards, keeping the ones edited or rejected by the user */
+		err = tx.Where("pick_id = ? AND is_edited = ? AND status = ?", pickID, false, domain.PickCardStatusActive).Delete(&domain.PickCard{}).Error
+		if err != nil {
+			return err
+		}
+
+		/* 4. The cards kept are not added again, so a rejected card does not come back */
+		kept := []string{}
+		if err := tx.Model(&domain.PickCard{}).Where("pick_id = ?", pickID).Pluck("question", &kept).Error; err != nil {
+			return err
+		}
+
+		cards := withoutQuestions(newCards, kept)
+		if len(cards) == 0 {
+			return nil
+		}
+
+		return tx.Create(&cards).Error
+	})
+}
+
+/* The cards whose question is not one of the questions, ignoring case and spaces */
+func withoutQuestions(cards []domain.PickCard, questions []string) []domain.PickCard {
+	excluded := map[string]bool{}
+	for _, question := range questions {
+		excluded[strings.ToLower(strings.TrimSpace(question))] = true
+	}
+
+	result := []domain.PickCard{}
+	for _, card := range cards {
+		if !excluded[strings.ToLower(card.Question)] {
+			result = append(result, card)
+		}
+	}
+
+	return result
+}
+
+func (service *serviceImpl) GetPickCards(userID uuid.UUID, params *domain.GetPickCardsParams) ([]domain.PickCardResponse, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := []domain.PickCard{}
+	err = service.db.Model(&domain.PickCard{}).
+		Where("pick_id = (SELECT id FROM book_picks WHERE guid = ? AND user_id = ?)", params.PickID, user.ID).
+		Order("created_at ASC").
+		Find(&cards).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]domain.PickCardResponse, len(cards))
+	for i, card := range cards {
+		response[i] = domain.PickCardResponseFromModel(&card)
+	}
+
+	return response, nil
+}
+
+func (service *serviceImpl) EditPickCard(userID uuid.UUID, body *domain.EditPickCardBody) (*domain.PickCardResponse, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	card := domain.PickCard{}
+
+	err = service.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("guid = ? AND user_id = ?", body.CardID, user.ID).First(&card).Error; err != nil {
+			return err
+		}
+
+		cardData := map[string]interface{}{}
+
+		if body.Question != "" {
+			cardData["question"] = body.Question
+			cardData["is_edited"] = true
+		}
+
+		if body.Answer != "" {
+			cardData["answer"] = body.Answer
+			cardData["is_edited"] = true
+		}
+
+		if body.Status != "" {
+			cardData["status"] = body.Status
+		}
+
+		if len(cardData) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&domain.PickCard{}).Where("id = ?", card.ID).Updates(cardData).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("id = ?", card.ID).First(&card).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := domain.PickCardResponseFromModel(&card)
+	return &response, nil
+}
@@ -0,0 +1,109 @@
+package card_test
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/pietro-putelli/feynman-backend/internal/card"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+)
+
+var _ = Describe("Service", func() {
+	var (
+		service card.Service
+		sqlMock sqlmock.Sqlmock
+		userID  uuid.UUID
+	)
+
+	BeforeEach(func() {
+		db, sqlMockGen, _ := sqlmock.New()
+		sqlMock = sqlMockGen
+
+		conn := postgres.New(postgres.Config{
+			Conn: db,
+		})
+
+		database, _ := database.NewDB(conn)
+
+		userID = uuid.New()
+
+		userService := user.NewMockService(gomock.NewController(GinkgoT()))
+		userService.EXPECT().GetUserByGuid(userID).Return(&domain.User{ID: 7, Guid: userID}, nil)
+
+		service = card.NewService(database, userService)
+	})
+
+	Describe("ReplacePickCards", func() {
+		pickColumns := []string{"id", "content_text"}
+
+		It("should keep the cards edited or rejected by the user", func() {
+			// Arrange
+			cards := []domain.GeneratedPickCard{
+				{Kind: "QA", Question: " What is it? ", Answer: "An answer"},
+				{Kind: "qa", Question: "Rejected question", Answer: "Back again"},
+				{Kind: "unknown", Question: "Dropped", Answer: "Dropped"},
+				{Kind: "cloze", Question: "", Answer: "Dropped"},
+			}
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT "id","content_text" FROM "book_picks" WHERE id = \$1 AND user_id = \$2 .* FOR UPDATE`).
+				WithArgs(3, 7, 1).
+				WillReturnRows(sqlmock.NewRows(pickColumns).AddRow(3, "The text of the pick"))
+			sqlMock.ExpectExec(`DELETE FROM "pick_cards" WHERE pick_id = \$1 AND is_edited = \$2 AND status = \$3`).
+				WithArgs(3, false, domain.PickCardStatusActive).
+				WillReturnResult(sqlmock.NewResult(0, 4))
+			sqlMock.ExpectQuery(`SELECT "question" FROM "pick_cards" WHERE pick_id = \$1`).
+				WithArgs(3).
+				WillReturnRows(sqlmock.NewRows([]string{"question"}).AddRow("rejected question "))
+			sqlMock.ExpectQuery(`INSERT INTO "pick_cards" .* VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9\) RETURNING`).
+				WillReturnRows(sqlmock.NewRows([]string{"guid", "id"}).AddRow(uuid.New(), 10))
+			sqlMock.ExpectCommit()
+
+			// Act
+			err := service.ReplacePickCards(userID, 3, "The text of the pick", cards)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should not replace the cards with the ones of a previous text", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT "id","content_text" FROM "book_picks"`).
+				WithArgs(3, 7, 1).
+				WillReturnRows(sqlmock.NewRows(pickColumns).AddRow(3, "A completely different text written later"))
+			sqlMock.ExpectCommit()
+
+			// Act
+			err := service.ReplacePickCards(userID, 3, "The text of the pick", []domain.GeneratedPickCard{{Kind: "qa", Question: "Q", Answer: "A"}})
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should return not found when the pick has been deleted", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT "id","content_text" FROM "book_picks"`).
+				WithArgs(3, 7, 1).
+				WillReturnRows(sqlmock.NewRows(pickColumns))
+			sqlMock.ExpectRollback()
+
+			// Act
+			err := service.ReplacePickCards(userID, 3, "The text of the pick", []domain.GeneratedPickCard{{Kind: "qa", Question: "Q", Answer: "A"}})
+
+			// Assert
+			Expect(err).To(MatchError(gorm.ErrRecordNotFound))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
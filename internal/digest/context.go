@@ -0,0 +1,48 @@
+package digest
+
+import (
+	"errors"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/mailer"
+	"gorm.io/gorm"
+)
+
+type Context struct {
+	Service  Service
+	Config   *config.Config
+	Database *gorm.DB
+	Mailer   mailer.Mailer
+}
+
+func NewContext() (*Context, error) {
+	config, err := config.NewConfig()
+	if err != nil {
+		return nil, errors.New("failed load digest context config: " + err.Error())
+	}
+
+	database, err := database.NewDB(database.NewConn(&config.Database))
+	if err != nil {
+		return nil, errors.New("failed load digest context database: " + err.Error())
+	}
+
+	service := NewService(database, &config.Digest)
+
+	context := &Context{
+		Service:  service,
+		Config:   config,
+		Database: database,
+	}
+
+	// mailer of the digests, only when a SMTP server is configured
+	if config.Smtp.Host != "" {
+		smtpMailer, err := mailer.NewSMTPMailer(config.Smtp)
+		if err != nil {
+			return nil, errors.New("failed load digest context mailer: " + err.Error())
+		}
+		context.Mailer = smtpMailer
+	}
+
+	return context, nil
+}
@@ -0,0 +1,217 @@
+package digest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/notification"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ Service = (*serviceImpl)(nil)
+
+// StrategyWeeklyDigest is the strategy of the notification history of the picks resurfaced by the digests.
+const StrategyWeeklyDigest = "weekly-digest"
+
+// Service represents the weekly email digest service, the digests are opt-in through UserSettings.WeeklyDigest.
+type Service interface {
+	// GetRecipients Get the users who opted in and were not sent the digest of the week, up to limit users
+	// after the cursor. It returns the cursor of the next page, 0 after the last one
+	GetRecipients(weekStart time.Time, after uint, limit int) ([]domain.User, uint, error)
+	// ClaimDigest Mark the digest of the week as sent to the user, false when it already was
+	ClaimDigest(userID uint, weekStart time.Time) (bool, error)
+	// ReleaseDigest Undo the claim of the digest when it could not be sent
+	ReleaseDigest(userID uint, weekStart time.Time) error
+	// BuildDigest Build the digest of the user from the library of the week before weekStart
+	BuildDigest(user *domain.User, weekStart time.Time) (*domain.Digest, error)
+	// RecordResurfacedPicks Record the resurfaced picks of the sent digest as surfaced on its week start
+	RecordResurfacedPicks(digest *domain.Digest) error
+	// Unsubscribe Stop the weekly digest of the user
+	Unsubscribe(userID uuid.UUID) error
+}
+
+type serviceImpl struct {
+	db     *gorm.DB
+	config *config.Digest
+}
+
+// NewService creates a new weekly digest service.
+func NewService(db *gorm.DB, config *config.Digest) Service {
+	return &serviceImpl{
+		db:     db,
+		config: config,
+	}
+}
+
+// WeekStart returns the Monday of the week of the time, in UTC.
+func WeekStart(t time.Time) time.Time {
+	day := t.UTC().Truncate(24 * time.Hour)
+	offset := (int(day.Weekday()) + 6) % 7
+
+	return day.AddDate(0, 0, -offset)
+}
+
+func (service *serviceImpl) GetRecipients(weekStart time.Time, after uint, limit int) ([]domain.User, uint, error) {
+	users := []domain.User{}
+
+	/* The addresses whose provider stopped forwarding the emails (Apple private relay) are left out */
+	err := service.db.Model(&domain.User{}).
+		Where("users.id > ?", after).
+		Where("users.is_active = ? AND users.email <> ''", true).
+		Where("(users.settings->>'weeklyDigest')::boolean IS TRUE").
+		Where("users.digest_sent_on IS NULL OR users.digest_sent_on < ?", weekStart.Format(time.DateOnly)).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM user_identities ui WHERE ui.user_id = users.id AND ui.email = users.email AND ui.email_enabled = false
+		)`).
+		Order("users.id").
+		Limit(limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	next := uint(0)
+	if len(users) == limit {
+		next = users[len(users)-1].ID
+	}
+
+	return users, next, nil
+}
+
+func (service *serviceImpl) ClaimDigest(userID uint, weekStart time.Time) (bool, error) {
+	week := weekStart.Format(time.DateOnly)
+
+	/* A single conditional update, so a retried run cannot send the digest twice */
+	result := service.db.Model(&domain.User{}).
+		Where("id = ? AND (digest_sent_on IS NULL OR digest_sent_on < ?)", userID, week).
+		Update("digest_sent_on", week)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (service *serviceImpl) ReleaseDigest(userID uint, weekStart time.Time) error {
+	return service.db.Model(&domain.User{}).
+		Where("id = ? AND digest_sent_on = ?", userID, weekStart.Format(time.DateOnly)).
+		Update("digest_sent_on", nil).Error
+}
+
+func (service *serviceImpl) BuildDigest(user *domain.User, weekStart time.Time) (*domain.Digest, error) {
+	from := weekStart.AddDate(0, 0, -7)
+	nextWeek := weekStart.AddDate(0, 0, 7)
+
+	digest := &domain.Digest{
+		UserID:    user.ID,
+		UserGuid:  user.Guid,
+		Email:     user.Email,
+		GivenName: user.GivenName,
+		WeekStart: weekStart,
+	}
+
+	/* The least recently surfaced picks older than the week, by a daily pick or a digest, so the users without push get them too */
+	resurfaced := service.db.Table("book_picks AS bp").
+		Select("bp.id AS pick_id, b.title AS book_title, bp.content_text AS content").
+		Joins("JOIN books b ON b.id = bp.book_id").
+		Where("bp.user_id = ? AND bp.created_at < ?", user.ID, from)
+
+	selection := &notification.Selection{UserID: user.ID, LocalDate: weekStart}
+	resurfaced = notification.Strategies[notification.StrategyLeastRecentlySurfaced].Apply(resurfaced, selection)
+
+	err := resurfaced.
+		Order(fmt.Sprintf("md5(bp.id::text || '-%d-%s')", user.ID, weekStart.Format(time.DateOnly))).
+		Limit(service.config.MaxItems).
+		Scan(&digest.ResurfacedPicks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	newPicks := service.db.Table("book_picks AS bp").
+		Joins("JOIN books b ON b.id = bp.book_id").
+		Where("bp.user_id = ? AND bp.created_at >= ? AND bp.created_at < ?", user.ID, from, weekStart)
+
+	if err := newPicks.Session(&gorm.Session{}).Count(&digest.NewPicksCount).Error; err != nil {
+		return nil, err
+	}
+
+	err = newPicks.Session(&gorm.Session{}).
+		Select("b.title AS book_title, bp.content_text AS content").
+		Order("bp.created_at DESC, bp.id DESC").
+		Limit(service.config.MaxItems).
+		Scan(&digest.NewPicks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.db.Table("book_picks AS bp").
+		Select("t.topic, COUNT(*) AS picks").
+		Joins("JOIN book_topics bt ON bt.book_id = bp.book_id").
+		Joins("JOIN topics t ON t.id = bt.topic_id").
+		Where("bp.user_id = ? AND bp.created_at >= ? AND bp.created_at < ?", user.ID, from, weekStart).
+		Group("t.topic").
+		Order("picks DESC, t.topic").
+		Limit(service.config.MaxItems).
+		Scan(&digest.ActiveTopics).Error
+	if err != nil {
+		return nil, err
+	}
+
+	reviews := service.db.Table("pick_review_states AS rs").
+		Joins("JOIN book_picks bp ON bp.id = rs.pick_id").
+		Joins("JOIN books b ON b.id = bp.book_id").
+		Where("rs.user_id = ? AND rs.due_at < ?", user.ID, nextWeek)
+
+	if err := reviews.Session(&gorm.Session{}).Count(&digest.UpcomingReviewsCount).Error; err != nil {
+		return nil, err
+	}
+
+	err = reviews.Session(&gorm.Session{}).
+		Select("b.title AS book_title, bp.content_text AS content").
+		Order("rs.due_at, rs.id").
+		Limit(service.config.MaxItems).
+		Scan(&digest.UpcomingReviews).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return digest, nil
+}
+
+func (service *serviceImpl) RecordResurfacedPicks(digest *domain.Digest) error {
+	if len(digest.ResurfacedPicks) == 0 {
+		return nil
+	}
+
+	history := make([]domain.NotificationHistory, len(digest.ResurfacedPicks))
+	for i, pick := range digest.ResurfacedPicks {
+		history[i] = domain.NotificationHistory{
+			UserID:    digest.UserID,
+			PickID:    pick.PickID,
+			Strategy:  StrategyWeeklyDigest,
+			LocalDate: digest.WeekStart,
+		}
+	}
+
+	/* A pick already surfaced by the daily pick of the same day is kept as it is */
+	return service.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&history).Error
+}
+
+func (service *serviceImpl) Unsubscribe(userID uuid.UUID) error {
+	result := service.db.Model(&domain.User{}).
+		Where("guid = ?", userID).
+		Update("settings", gorm.Expr("jsonb_set(COALESCE(settings, '{}'::jsonb), '{weeklyDigest}', 'false'::jsonb)"))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
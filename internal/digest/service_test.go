@@ -0,0 +1,101 @@
+package digest_test
+
+import (
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/postgres"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/digest"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+var _ = Describe("Service", func() {
+	var (
+		service digest.Service
+		sqlMock sqlmock.Sqlmock
+	)
+
+	weekStart := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		db, sqlMockGen, _ := sqlmock.New()
+		sqlMock = sqlMockGen
+
+		conn := postgres.New(postgres.Config{
+			Conn: db,
+		})
+
+		database, _ := database.NewDB(conn)
+		service = digest.NewService(database, &config.Digest{MaxItems: 5})
+	})
+
+	Describe("BuildDigest", func() {
+		It("should resurface the least recently surfaced picks without push notifications", func() {
+			// Arrange
+			user := &domain.User{ID: 7, Guid: uuid.New(), Email: "richard@example.com"}
+
+			sqlMock.ExpectQuery(`SELECT bp.id AS pick_id, b.title AS book_title, bp.content_text AS content FROM book_picks AS bp JOIN books b ON b.id = bp.book_id `+
+				`WHERE bp.user_id = \$1 AND bp.created_at < \$2 ORDER BY \(SELECT MAX\(nh.local_date\) FROM notification_history nh WHERE nh.pick_id = bp.id AND nh.local_date < '2024-03-04'\) ASC NULLS FIRST,md5\(.+\) LIMIT \$3`).
+				WithArgs(7, weekStart.AddDate(0, 0, -7), 5).
+				WillReturnRows(sqlmock.NewRows([]string{"pick_id", "book_title", "content"}).AddRow(11, "QED", "Nature is absurd"))
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM book_picks AS bp`).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			sqlMock.ExpectQuery(`SELECT b.title AS book_title, bp.content_text AS content FROM book_picks AS bp`).
+				WillReturnRows(sqlmock.NewRows([]string{"book_title", "content"}))
+			sqlMock.ExpectQuery(`SELECT t.topic, COUNT\(\*\) AS picks FROM book_picks AS bp`).
+				WillReturnRows(sqlmock.NewRows([]string{"topic", "picks"}))
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM pick_review_states AS rs`).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			sqlMock.ExpectQuery(`SELECT b.title AS book_title, bp.content_text AS content FROM pick_review_states AS rs`).
+				WillReturnRows(sqlmock.NewRows([]string{"book_title", "content"}))
+
+			// Act
+			result, err := service.BuildDigest(user, weekStart)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(result.ResurfacedPicks).To(Equal([]domain.DigestPick{{PickID: 11, BookTitle: "QED", Content: "Nature is absurd"}}))
+			Expect(result.IsEmpty()).To(BeFalse())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+
+	Describe("RecordResurfacedPicks", func() {
+		It("should record the resurfaced picks on the week start", func() {
+			// Arrange
+			userDigest := &domain.Digest{
+				UserID:          7,
+				WeekStart:       weekStart,
+				ResurfacedPicks: []domain.DigestPick{{PickID: 11}, {PickID: 12}},
+			}
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`INSERT INTO "notification_history" \("user_id","pick_id","strategy","local_date","created_at"\) VALUES \(\$1,\$2,\$3,\$4,\$5\),\(\$6,\$7,\$8,\$9,\$10\) ON CONFLICT DO NOTHING RETURNING "id"`).
+				WithArgs(7, 11, digest.StrategyWeeklyDigest, weekStart, sqlmock.AnyArg(), 7, 12, digest.StrategyWeeklyDigest, weekStart, sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+			sqlMock.ExpectCommit()
+
+			// Act
+			err := service.RecordResurfacedPicks(userDigest)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should record nothing without resurfaced picks", func() {
+			// Act
+			err := service.RecordResurfacedPicks(&domain.Digest{UserID: 7, WeekStart: weekStart})
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,134 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/mailer"
+	"github.com/pietro-putelli/feynman-backend/internal/notification"
+)
+
+// Subject is the subject of the digest emails.
+const Subject = "Your week in Feynman"
+
+/* Length of the picks in the emails, like the push notifications */
+const maxPickLength = 280
+
+// templateData represents the values available to the digest templates.
+type templateData struct {
+	*domain.Digest
+	UnsubscribeURL string
+}
+
+var templateFuncs = map[string]any{
+	"excerpt": func(text string) string {
+		return notification.Truncate(strings.TrimSpace(text), maxPickLength)
+	},
+	"more": func(count int64, shown int) int64 {
+		return count - int64(shown)
+	},
+}
+
+var textTemplate = texttemplate.Must(texttemplate.New("digest.txt").Funcs(templateFuncs).Parse(`Hi{{if .GivenName}} {{.GivenName}}{{end}},
+
+here is your week in Feynman.
+{{if .ResurfacedPicks}}
+RESURFACED THIS WEEK
+{{- range .ResurfacedPicks}}
+- {{excerpt .Content}} ({{.BookTitle}})
+{{- end}}
+{{end}}{{if .NewPicks}}
+NEW PICKS: {{.NewPicksCount}}
+{{- range .NewPicks}}
+- {{excerpt .Content}} ({{.BookTitle}})
+{{- end}}
+{{- with more .NewPicksCount (len .NewPicks)}}{{if gt . 0}}
+and {{.}} more{{end}}{{end}}
+{{end}}{{if .ActiveTopics}}
+MOST ACTIVE TOPICS
+{{- range .ActiveTopics}}
+- {{.Topic}}: {{.Picks}} picks
+{{- end}}
+{{end}}{{if .UpcomingReviews}}
+REVIEWS DUE THIS WEEK: {{.UpcomingReviewsCount}}
+{{- range .UpcomingReviews}}
+- {{excerpt .Content}} ({{.BookTitle}})
+{{- end}}
+{{end}}
+--
+You receive this email because you enabled the weekly digest.
+Unsubscribe: {{.UnsubscribeURL}}
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("digest.html").Funcs(templateFuncs).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: -apple-system, Helvetica, Arial, sans-serif; color: #1c1c1e; max-width: 600px; margin: 0 auto;">
+<p>Hi{{if .GivenName}} {{.GivenName}}{{end}},</p>
+<p>here is your week in Feynman.</p>
+{{if .ResurfacedPicks}}
+<h2>Resurfaced this week</h2>
+<ul>{{range .ResurfacedPicks}}<li>{{excerpt .Content}} <em>{{.BookTitle}}</em></li>{{end}}</ul>
+{{end}}
+{{if .NewPicks}}
+<h2>New picks: {{.NewPicksCount}}</h2>
+<ul>{{range .NewPicks}}<li>{{excerpt .Content}} <em>{{.BookTitle}}</em></li>{{end}}</ul>
+{{with more .NewPicksCount (len .NewPicks)}}{{if gt . 0}}<p>and {{.}} more</p>{{end}}{{end}}
+{{end}}
+{{if .ActiveTopics}}
+<h2>Most active topics</h2>
+<ul>{{range .ActiveTopics}}<li>{{.Topic}}: {{.Picks}} picks</li>{{end}}</ul>
+{{end}}
+{{if .UpcomingReviews}}
+<h2>Reviews due this week: {{.UpcomingReviewsCount}}</h2>
+<ul>{{range .UpcomingReviews}}<li>{{excerpt .Content}} <em>{{.BookTitle}}</em></li>{{end}}</ul>
+{{end}}
+<hr>
+<p style="font-size: 12px; color: #8e8e93;">You receive this email because you enabled the weekly digest.
+<a href="{{.UnsubscribeURL}}">Unsubscribe</a></p>
+</body>
+</html>
+`))
+
+// Render returns the email of the digest, in plain text and HTML, with the unsubscribe link in the body and
+// in the List-Unsubscribe headers so the mail clients offer a one-click unsubscribe.
+func Render(digest *domain.Digest, unsubscribeURL string) (*mailer.Message, error) {
+	data := templateData{Digest: digest, UnsubscribeURL: unsubscribeURL}
+
+	var text bytes.Buffer
+	if err := textTemplate.Execute(&text, data); err != nil {
+		return nil, err
+	}
+
+	var html bytes.Buffer
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	return &mailer.Message{
+		To:      digest.Email,
+		Subject: Subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}, nil
+}
+
+// Send renders the digest with the unsubscribe link of the user and sends it with the mailer.
+func Send(ctx context.Context, m mailer.Mailer, digestConfig *config.Digest, digest *domain.Digest) error {
+	unsubscribeURL := UnsubscribeLink(digestConfig.UnsubscribeURL, digestConfig.UnsubscribeSecret, digest.UserGuid)
+
+	message, err := Render(digest, unsubscribeURL)
+	if err != nil {
+		return err
+	}
+
+	return m.Send(ctx, message)
+}
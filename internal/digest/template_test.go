@@ -0,0 +1,92 @@
+package digest_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/digest"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/mailer"
+)
+
+var _ = Describe("Template", func() {
+	var userDigest *domain.Digest
+
+	BeforeEach(func() {
+		userDigest = &domain.Digest{
+			UserGuid:        uuid.MustParse("5b0e1f7a-2d6c-4f43-8a55-0c1d9e2f3a4b"),
+			Email:           "richard@example.com",
+			GivenName:       "Richard",
+			WeekStart:       time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+			ResurfacedPicks: []domain.DigestPick{{BookTitle: "QED", Content: "Nature <is> absurd"}},
+			NewPicks:        []domain.DigestPick{{BookTitle: "Six Easy Pieces", Content: "Everything is made of atoms"}},
+			NewPicksCount:   3,
+			ActiveTopics:    []domain.DigestTopic{{Topic: "Physics", Picks: 3}},
+		}
+	})
+
+	It("should render the sections in plain text and HTML", func() {
+		// Act
+		message, err := digest.Render(userDigest, "https://api.feynman.app/digest/unsubscribe?token=t")
+
+		// Assert
+		Expect(err).To(BeNil())
+		Expect(message.To).To(Equal("richard@example.com"))
+		Expect(message.Text).To(ContainSubstring("Hi Richard"))
+		Expect(message.Text).To(ContainSubstring("- Nature <is> absurd (QED)"))
+		Expect(message.Text).To(ContainSubstring("NEW PICKS: 3"))
+		Expect(message.Text).To(ContainSubstring("and 2 more"))
+		Expect(message.Text).To(ContainSubstring("- Physics: 3 picks"))
+		Expect(message.Text).NotTo(ContainSubstring("REVIEWS DUE"))
+		Expect(message.HTML).To(ContainSubstring("Nature &lt;is&gt; absurd"))
+		Expect(message.HTML).To(ContainSubstring(`href="https://api.feynman.app/digest/unsubscribe?token=t"`))
+		Expect(message.Headers).To(HaveKeyWithValue("List-Unsubscribe", "<https://api.feynman.app/digest/unsubscribe?token=t>"))
+	})
+
+	It("should send the digest with the signed unsubscribe link of the user", func() {
+		// Arrange
+		memoryMailer := mailer.NewMemoryMailer()
+		digestConfig := &config.Digest{UnsubscribeURL: "https://api.feynman.app/digest/unsubscribe", UnsubscribeSecret: "secret"}
+
+		// Act
+		err := digest.Send(context.Background(), memoryMailer, digestConfig, userDigest)
+
+		// Assert
+		Expect(err).To(BeNil())
+		Expect(memoryMailer.Messages()).To(HaveLen(1))
+		Expect(memoryMailer.Messages()[0].Text).To(ContainSubstring(
+			digest.UnsubscribeLink(digestConfig.UnsubscribeURL, "secret", userDigest.UserGuid),
+		))
+	})
+})
+
+var _ = Describe("UnsubscribeToken", func() {
+	userID := uuid.MustParse("5b0e1f7a-2d6c-4f43-8a55-0c1d9e2f3a4b")
+
+	It("should verify the token of the user", func() {
+		token := digest.UnsubscribeToken("secret", userID)
+		Expect(digest.VerifyUnsubscribeToken("secret", userID, token)).To(Succeed())
+	})
+
+	It("should refuse the token of another user or secret", func() {
+		token := digest.UnsubscribeToken("secret", userID)
+		Expect(digest.VerifyUnsubscribeToken("secret", uuid.New(), token)).To(MatchError(digest.ErrInvalidUnsubscribeToken))
+		Expect(digest.VerifyUnsubscribeToken("other", userID, token)).To(MatchError(digest.ErrInvalidUnsubscribeToken))
+	})
+
+	It("should refuse every token without a secret", func() {
+		Expect(digest.VerifyUnsubscribeToken("", userID, digest.UnsubscribeToken("", userID))).To(MatchError(digest.ErrInvalidUnsubscribeToken))
+	})
+})
+
+var _ = Describe("WeekStart", func() {
+	It("should return the Monday of the week", func() {
+		Expect(digest.WeekStart(time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC))).To(Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)))
+		Expect(digest.WeekStart(time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC))).To(Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)))
+	})
+})
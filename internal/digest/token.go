@@ -0,0 +1,45 @@
+package digest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidUnsubscribeToken is returned when the token of an unsubscribe link was not signed for the user.
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// UnsubscribeToken returns the token of the unsubscribe links of the user, signed with the secret. It never
+// expires, so the links of the old digests keep working.
+func UnsubscribeToken(secret string, userID uuid.UUID) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("digest-unsubscribe:" + userID.String()))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyUnsubscribeToken checks the token was signed for the user with the secret.
+func VerifyUnsubscribeToken(secret string, userID uuid.UUID, token string) error {
+	/* Without a secret anybody could sign the tokens */
+	if secret == "" {
+		return ErrInvalidUnsubscribeToken
+	}
+
+	if !hmac.Equal([]byte(UnsubscribeToken(secret, userID)), []byte(token)) {
+		return ErrInvalidUnsubscribeToken
+	}
+
+	return nil
+}
+
+// UnsubscribeLink returns the unsubscribe link of the user.
+func UnsubscribeLink(baseURL string, secret string, userID uuid.UUID) string {
+	return baseURL + "?" + url.Values{
+		"user":  {userID.String()},
+		"token": {UnsubscribeToken(secret, userID)},
+	}.Encode()
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scopes of the personal access tokens, the session tokens of the apps have them all.
+const (
+	// ScopeRead allows to read the books, their picks and topics, and to search them.
+	ScopeRead = "read"
+	// ScopePicksWrite allows to create, edit and delete picks.
+	ScopePicksWrite = "picks:write"
+	// ScopeExport allows to export the whole library.
+	ScopeExport = "export"
+)
+
+//----------------------------------------------
+// DB Models
+//----------------------------------------------
+
+// PersonalAccessToken is a long-lived token created by the user for scripts and integrations,
+// only the hash of the token is stored.
+type PersonalAccessToken struct {
+	TimestapModel
+
+	ID   uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	Guid uuid.UUID `gorm:"type:uuid;unique;not null;column:guid;default:uuid_generate_v4()"`
+
+	User   *User `gorm:"foreignKey:UserID;references:id;constraint:OnDelete:CASCADE"`
+	UserID uint  `gorm:"column:user_id;not null"`
+
+	Name      string     `gorm:"column:name;not null"`
+	TokenHash string     `gorm:"column:token_hash;unique;not null"`
+	Scopes    []string   `gorm:"column:scopes;type:jsonb;serializer:json"`
+	ExpiresAt *time.Time `gorm:"column:expires_at"`
+	LastUsed  *time.Time `gorm:"column:last_used_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+}
+
+func (PersonalAccessToken) TableName() string {
+	return "personal_access_tokens"
+}
+
+// AccessTokenPrincipal represents the user authenticated by a personal access token.
+type AccessTokenPrincipal struct {
+	UserID  uuid.UUID
+	TokenID uuid.UUID
+	Scopes  []string
+}
+
+//----------------------------------------------
+// Request DTOs
+//----------------------------------------------
+
+// CreateAccessTokenBody represents a new personal access token, it never expires when ExpiresInDays is not set.
+type CreateAccessTokenBody struct {
+	Name          string   `json:"name" validate:"required,max=64"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=read picks:write export"`
+	ExpiresInDays *int     `json:"expires_in_days" validate:"omitempty,gte=1,lte=365"`
+}
+
+//----------------------------------------------
+// Response DTOs
+//----------------------------------------------
+
+type AccessTokenResponse struct {
+	Guid       uuid.UUID  `json:"guid"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// CreatedAccessTokenResponse holds the plain token, it is only returned when the token is created.
+type CreatedAccessTokenResponse struct {
+	AccessTokenResponse
+	Token string `json:"token"`
+}
+
+func AccessTokenResponseFromModel(token *PersonalAccessToken) AccessTokenResponse {
+	return AccessTokenResponse{
+		Guid:       token.Guid,
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		CreatedAt:  token.CreatedAt,
+		LastUsedAt: token.LastUsed,
+		ExpiresAt:  token.ExpiresAt,
+	}
+}
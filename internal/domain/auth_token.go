@@ -27,6 +27,29 @@ type AuthTokenDevice struct {
 	ID string `json:"id"`
 	// Mobile Token to be used for push notifications
 	Token string `json:"token"`
+	// Platform of the device, ios when not given
+	Platform string `json:"platform" validate:"omitempty,oneof=ios android"`
+	// Environment of the push notifications of the token, production when not given
+	Environment string `json:"environment" validate:"omitempty,oneof=production sandbox"`
+}
+
+// JWK represents the public JSON Web Key used to verify the tokens.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet represents the JWKS document published at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
 }
 
 //-------------------------------------
@@ -34,9 +57,12 @@ type AuthTokenDevice struct {
 //-------------------------------------
 
 // AuthTokenBody represents the authentication token body.
+// For Apple the token is the authorization code and the user is read from the identity token.
 type AuthTokenBody struct {
 	Token    string           `json:"token" validate:"required"`
-	Provider string           `json:"provider" validate:"required,oneof=apple google" enum:"apple,google"`
+	IDToken  string           `json:"id_token" validate:"required_if=Provider apple"`
+	Provider string           `json:"provider" validate:"required,oneof=apple google email" enum:"apple,google,email"`
+	Email    string           `json:"email" validate:"required_if=Provider email,omitempty,email"`
 	Data     *AuthTokenData   `json:"data" validate:"required_if=Provider apple"`
 	Device   *AuthTokenDevice `json:"device" validate:"required"`
 }
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +19,31 @@ type Book struct {
 	Author string `gorm:"column:author;not null"`
 }
 
+// BookDigest stores the AI generated summary of a book's picks, PicksHash identifies the pick set it was generated from
+type BookDigest struct {
+	TimestapModel
+
+	ID uint `gorm:"primaryKey;autoIncrement;column:id"`
+
+	Book   *Book `gorm:"foreignKey:BookID;references:id;constraint:OnDelete:CASCADE"`
+	BookID uint  `gorm:"column:book_id;unique;not null"`
+
+	User   *User `gorm:"foreignKey:UserID;references:id;constraint:OnDelete:CASCADE"`
+	UserID uint  `gorm:"column:user_id;not null"`
+
+	PicksHash string              `gorm:"column:picks_hash;not null"`
+	Summary   string              `gorm:"column:summary;not null"`
+	KeyIdeas  []BookDigestKeyIdea `gorm:"column:key_ideas;type:jsonb;serializer:json"`
+
+	/* Hash of the pick set queued for generation and when, cleared once the digest is generated */
+	PendingHash *string    `gorm:"column:pending_hash"`
+	RequestedAt *time.Time `gorm:"column:requested_at"`
+}
+
+func (BookDigest) TableName() string {
+	return "book_digests"
+}
+
 type Topic struct {
 	Topic string `gorm:"column:topic;not null" json:"topic"`
 	Color string `gorm:"column:color;not null" json:"color"`
@@ -117,6 +143,57 @@ type BookResponse struct {
 
 	Topics *[]Topic            `json:"topics"`
 	Picks  *[]BookPickResponse `json:"picks"`
+
+	/* Only set when the digest is requested */
+	Digest *BookDigestResponse `json:"digest,omitempty"`
+}
+
+// BookDigestKeyIdea is a key idea of the book with the picks supporting it
+type BookDigestKeyIdea struct {
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	PickIDs     []uuid.UUID `json:"pickIds"`
+}
+
+// Statuses of the book digests, a pending digest is being generated and may hold the previous one.
+// A stale digest holds the previous one, or nothing, until a digest of the current picks is requested.
+const (
+	BookDigestStatusReady   = "ready"
+	BookDigestStatusPending = "pending"
+	BookDigestStatusStale   = "stale"
+)
+
+type BookDigestResponse struct {
+	Status      string              `json:"status"`
+	Summary     string              `json:"summary"`
+	KeyIdeas    []BookDigestKeyIdea `json:"keyIdeas"`
+	GeneratedAt *time.Time          `json:"generatedAt,omitempty"`
+}
+
+// BookDigestResponseFromModel converts a BookDigest to a BookDigestResponse, the summary is empty until the
+// first digest of the book is generated
+func BookDigestResponseFromModel(digest *BookDigest, status string) *BookDigestResponse {
+	response := &BookDigestResponse{
+		Status:   status,
+		Summary:  digest.Summary,
+		KeyIdeas: digest.KeyIdeas,
+	}
+
+	if response.KeyIdeas == nil {
+		response.KeyIdeas = []BookDigestKeyIdea{}
+	}
+
+	if digest.PicksHash != "" {
+		response.GeneratedAt = &digest.UpdatedAt
+	}
+
+	return response
+}
+
+// BookDigestMessage is the message of the queue generating the digests
+type BookDigestMessage struct {
+	BookID   uint      `json:"book_id"`
+	UserGuid uuid.UUID `json:"user_guid"`
 }
 
 type CreateBookResponse interface {
@@ -130,6 +207,9 @@ type GetPicksParams struct {
 	OrderBy string `json:"orderBy"`
 
 	UntilPickID string `json:"untilPickId" validate:"omitempty,uuid4"`
+
+	/* Optional target language, when set the stored translations are returned alongside the picks */
+	Lang string `json:"lang"`
 }
 
 type EditBookTopicParams struct {
@@ -215,7 +295,22 @@ type TranslateWordParams struct {
 	Lang string `json:"lang" validate:"required"`
 }
 
-type BookPickPushNotification struct {
-	BookID  uuid.UUID `json:"book_id"`
-	Content string    `json:"content"`
+// BookExport represents a book of the library export, with all its picks.
+type BookExport struct {
+	Guid      uuid.UUID        `json:"guid"`
+	Title     string           `json:"title"`
+	Author    string           `json:"author"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Topics    []string         `json:"topics"`
+	Picks     []BookPickExport `json:"picks"`
+}
+
+type BookPickExport struct {
+	Guid        uuid.UUID       `json:"guid"`
+	Title       string          `json:"title"`
+	Content     json.RawMessage `json:"content"`
+	ContentText string          `json:"contentText"`
+	Index       uint            `json:"index"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
 }
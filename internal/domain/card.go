@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+)
+
+const (
+	PickCardKindCloze    = "cloze"
+	PickCardKindQuestion = "qa"
+
+	PickCardStatusActive   = "active"
+	PickCardStatusRejected = "rejected"
+)
+
+//----------------------------------------------
+// DB Models
+//----------------------------------------------
+
+// PickCard is an active-recall card generated from a pick
+type PickCard struct {
+	TimestapModel
+
+	ID   uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	Guid uuid.UUID `gorm:"type:uuid;unique;not null;column:guid;default:uuid_generate_v4()"`
+
+	Pick   *BookPick `gorm:"foreignKey:PickID;references:id;constraint:OnDelete:CASCADE"`
+	PickID uint      `gorm:"column:pick_id;not null"`
+
+	User   *User `gorm:"foreignKey:UserID;references:id;constraint:OnDelete:CASCADE"`
+	UserID uint  `gorm:"column:user_id;not null"`
+
+	/* cloze, qa */
+	Kind string `gorm:"column:kind;not null"`
+	/* For cloze cards the question is the text with the deletion replaced by [...] */
+	Question string `gorm:"column:question;not null"`
+	Answer   string `gorm:"column:answer;not null"`
+	/* active, rejected */
+	Status string `gorm:"column:status;not null;default:active"`
+	/* Edited cards are kept when the cards are generated again */
+	IsEdited bool `gorm:"column:is_edited;not null;default:false"`
+}
+
+func (PickCard) TableName() string {
+	return "pick_cards"
+}
+
+// BookPickCardsMessage used as a model when send messages through SQS for generating the pick's cards
+type BookPickCardsMessage struct {
+	PickID      uint      `json:"pick_id"`
+	PickContent string    `json:"content"`
+	UserGuid    uuid.UUID `json:"user_guid"`
+}
+
+// GeneratedPickCard is a card as returned by the LLM
+type GeneratedPickCard struct {
+	Kind     string `mapstructure:"kind"`
+	Question string `mapstructure:"question"`
+	Answer   string `mapstructure:"answer"`
+}
+
+//----------------------------------------------
+// Request DTOs
+//----------------------------------------------
+
+type GetPickCardsParams struct {
+	PickID string `json:"pickId" validate:"required,uuid4"`
+}
+
+type EditPickCardBody struct {
+	CardID   string `json:"cardId" validate:"required,uuid4"`
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	Status   string `json:"status" validate:"omitempty,oneof=active rejected"`
+}
+
+//----------------------------------------------
+// Response DTOs
+//----------------------------------------------
+
+type PickCardResponse struct {
+	Guid     uuid.UUID `json:"guid"`
+	Kind     string    `json:"kind"`
+	Question string    `json:"question"`
+	Answer   string    `json:"answer"`
+	Status   string    `json:"status"`
+	IsEdited bool      `json:"isEdited"`
+}
+
+// PickCardResponseFromModel converts a PickCard to a PickCardResponse
+func PickCardResponseFromModel(card *PickCard) PickCardResponse {
+	return PickCardResponse{
+		Guid:     card.Guid,
+		Kind:     card.Kind,
+		Question: card.Question,
+		Answer:   card.Answer,
+		Status:   card.Status,
+		IsEdited: card.IsEdited,
+	}
+}
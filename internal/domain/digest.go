@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Digest represents the weekly email digest of a user, built from the library of the week before WeekStart.
+type Digest struct {
+	UserID    uint
+	UserGuid  uuid.UUID
+	Email     string
+	GivenName string
+	WeekStart time.Time
+
+	// ResurfacedPicks are the least recently surfaced picks of the library, by the daily picks or the former digests
+	ResurfacedPicks []DigestPick
+	// NewPicks are the last picks added during the week, NewPicksCount counts all of them
+	NewPicks      []DigestPick
+	NewPicksCount int64
+	// ActiveTopics are the topics of the books with the most picks added during the week
+	ActiveTopics []DigestTopic
+	// UpcomingReviews are the first reviews due during the next week, UpcomingReviewsCount counts all of them
+	UpcomingReviews      []DigestPick
+	UpcomingReviewsCount int64
+}
+
+// DigestPick represents a pick of the digest.
+type DigestPick struct {
+	PickID    uint
+	BookTitle string
+	Content   string
+}
+
+// DigestTopic represents a topic of the digest with the number of picks added during the week.
+type DigestTopic struct {
+	Topic string
+	Picks int64
+}
+
+// IsEmpty tells whether nothing happened in the library, the empty digests are not sent.
+func (d *Digest) IsEmpty() bool {
+	return len(d.ResurfacedPicks) == 0 && d.NewPicksCount == 0 && d.UpcomingReviewsCount == 0
+}
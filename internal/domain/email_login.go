@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+//----------------------------------------------
+// DB Models
+//----------------------------------------------
+
+// EmailLoginCode is a single-use sign in code sent by email together with a sign in link,
+// only the hashes of the code and of the link token are stored.
+type EmailLoginCode struct {
+	ID uint `gorm:"primaryKey;autoIncrement;column:id"`
+
+	Email         string     `gorm:"column:email;not null"`
+	IP            string     `gorm:"column:ip"`
+	CodeHash      string     `gorm:"column:code_hash;not null"`
+	LinkTokenHash string     `gorm:"column:link_token_hash;unique;not null"`
+	Attempts      int        `gorm:"column:attempts;not null;default:0"`
+	ExpiresAt     time.Time  `gorm:"column:expires_at;not null"`
+	UsedAt        *time.Time `gorm:"column:used_at"`
+
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (EmailLoginCode) TableName() string {
+	return "email_login_codes"
+}
+
+//----------------------------------------------
+// Request DTOs
+//----------------------------------------------
+
+// EmailLoginBody represents the request of a sign in code for the email.
+type EmailLoginBody struct {
+	Email string `json:"email" validate:"required,email"`
+}
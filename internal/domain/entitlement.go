@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Plans of the users, the trial gives the premium capabilities to the new users.
+const (
+	PlanFree    = "free"
+	PlanTrial   = "trial"
+	PlanPremium = "premium"
+)
+
+// Capabilities limited by the plans.
+const (
+	CapabilityBooks        = "books"
+	CapabilityPicksPerBook = "picks_per_book"
+	CapabilityAICalls      = "ai_calls"
+	CapabilityExport       = "export"
+	CapabilitySearch       = "search"
+)
+
+// Search modes, within a single book or across the whole library.
+const (
+	SearchModeBook    = "book"
+	SearchModeLibrary = "library"
+)
+
+// Formats of the library export, JSON keeps every detail of the picks and Markdown only their text.
+const (
+	ExportFormatJSON     = "json"
+	ExportFormatMarkdown = "markdown"
+)
+
+// Unlimited is the limit of the capabilities without a limit.
+const Unlimited = -1
+
+//----------------------------------------------
+// DB Models
+//----------------------------------------------
+
+// AIUsage represents the AI calls of a user in a day (UTC).
+type AIUsage struct {
+	UserID uint      `gorm:"primaryKey;column:user_id"`
+	Day    time.Time `gorm:"primaryKey;type:date;column:day"`
+	Calls  int       `gorm:"column:calls;not null"`
+}
+
+func (AIUsage) TableName() string {
+	return "ai_usages"
+}
+
+//----------------------------------------------
+// Entitlements
+//----------------------------------------------
+
+// Entitlements represents the capabilities of the plan of a user, a negative limit is unlimited.
+type Entitlements struct {
+	Plan             string     `json:"plan"`
+	MaxBooks         int        `json:"max_books"`
+	MaxPicksPerBook  int        `json:"max_picks_per_book"`
+	MaxAICallsPerDay int        `json:"max_ai_calls_per_day"`
+	ExportFormats    []string   `json:"export_formats"`
+	SearchModes      []string   `json:"search_modes"`
+	TrialEndsAt      *time.Time `json:"trial_ends_at"`
+}
+
+// AllowsExport tells whether the plan can export the library in the format.
+func (e *Entitlements) AllowsExport(format string) bool {
+	return slices.Contains(e.ExportFormats, format)
+}
+
+// AllowsSearch tells whether the plan can search with the mode.
+func (e *Entitlements) AllowsSearch(mode string) bool {
+	return slices.Contains(e.SearchModes, mode)
+}
+
+// IsWithinLimit tells whether a count is below the limit, so that one more item is allowed.
+func IsWithinLimit(count int64, limit int) bool {
+	return limit < 0 || count < int64(limit)
+}
+
+//----------------------------------------------
+// Response DTOs
+//----------------------------------------------
+
+// EntitlementsUsage represents the current usage of the limited capabilities.
+type EntitlementsUsage struct {
+	Books        int64 `json:"books"`
+	AICallsToday int   `json:"ai_calls_today"`
+}
+
+type EntitlementsResponse struct {
+	Entitlements
+	Usage EntitlementsUsage `json:"usage"`
+}
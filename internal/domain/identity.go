@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//----------------------------------------------
+// DB Models
+//----------------------------------------------
+
+// UserIdentity is a sign in provider account linked to a user, users are found by provider subject.
+type UserIdentity struct {
+	TimestapModel
+
+	ID   uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	Guid uuid.UUID `gorm:"type:uuid;unique;not null;column:guid;default:uuid_generate_v4()"`
+
+	User   *User `gorm:"foreignKey:UserID;references:id;constraint:OnDelete:CASCADE"`
+	UserID uint  `gorm:"column:user_id;not null"`
+
+	/* apple, google */
+	Provider string `gorm:"column:provider;not null"`
+	Subject  string `gorm:"column:subject;not null"`
+	/* Email given by the provider, e.g. an Apple private relay address */
+	Email string `gorm:"column:email"`
+	/* False when the user stopped the forwarding of the Apple private relay address */
+	EmailEnabled bool `gorm:"column:email_enabled;default:true"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+//----------------------------------------------
+// Request DTOs
+//----------------------------------------------
+
+// LinkIdentityBody represents the sign in token of the provider account to link.
+type LinkIdentityBody struct {
+	Token    string         `json:"token" validate:"required"`
+	IDToken  string         `json:"id_token" validate:"required_if=Provider apple"`
+	Provider string         `json:"provider" validate:"required,oneof=apple google email" enum:"apple,google,email"`
+	Email    string         `json:"email" validate:"required_if=Provider email,omitempty,email"`
+	Data     *AuthTokenData `json:"data" validate:"required_if=Provider apple"`
+}
+
+//----------------------------------------------
+// Response DTOs
+//----------------------------------------------
+
+type UserIdentityResponse struct {
+	Provider     string    `json:"provider"`
+	Email        string    `json:"email"`
+	EmailEnabled bool      `json:"email_enabled"`
+	LinkedAt     time.Time `json:"linked_at"`
+}
+
+func UserIdentityResponseFromModel(identity *UserIdentity) UserIdentityResponse {
+	return UserIdentityResponse{
+		Provider:     identity.Provider,
+		Email:        identity.Email,
+		EmailEnabled: identity.EmailEnabled,
+		LinkedAt:     identity.CreatedAt,
+	}
+}
+
+//----------------------------------------------
+// Apple Server-to-Server Notifications
+//----------------------------------------------
+
+// AppleNotificationBody represents the body of the Sign in with Apple server-to-server notifications.
+type AppleNotificationBody struct {
+	Payload string `json:"payload" validate:"required"`
+}
+
+// AppleNotificationEvent represents the event signed in the payload of the notification.
+type AppleNotificationEvent struct {
+	Type  string `json:"type"`
+	Sub   string `json:"sub"`
+	Email string `json:"email"`
+}
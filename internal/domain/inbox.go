@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//-------------------------------------
+// Inbox DB Model
+//-------------------------------------
+
+// Kinds of the inbox notifications.
+const (
+	InboxKindDailyPick    = "daily_pick"
+	InboxKindSubscription = "subscription"
+)
+
+// InboxNotification represents a notification kept in the in-app inbox of the user, the push notifications
+// disappear once dismissed. DedupeKey keeps a single notification per event, e.g. one daily pick per day.
+type InboxNotification struct {
+	TimestapModel
+
+	ID   uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	Guid uuid.UUID `gorm:"type:uuid;unique;not null;column:guid;default:uuid_generate_v4()"`
+
+	User   *User `gorm:"foreignKey:UserID;references:id;constraint:OnDelete:CASCADE"`
+	UserID uint  `gorm:"column:user_id;not null"`
+
+	Kind      string     `gorm:"column:kind;not null"`
+	Title     string     `gorm:"column:title;not null"`
+	Body      string     `gorm:"column:body;not null"`
+	BookID    *uuid.UUID `gorm:"type:uuid;column:book_id"`
+	PickID    *uuid.UUID `gorm:"type:uuid;column:pick_id"`
+	DedupeKey *string    `gorm:"column:dedupe_key"`
+	ReadAt    *time.Time `gorm:"column:read_at"`
+}
+
+func (InboxNotification) TableName() string {
+	return "inbox_notifications"
+}
+
+//----------------------------------------------
+// Request DTOs
+//----------------------------------------------
+
+// InboxListParams used as model for get params of the inbox, the cursor is the nextCursor of the previous page
+type InboxListParams struct {
+	Cursor string `json:"cursor" query:"cursor"`
+	Limit  int    `json:"limit" validate:"gte=0,lte=100" query:"limit"`
+}
+
+//----------------------------------------------
+// Response DTOs
+//----------------------------------------------
+
+type InboxNotificationResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Kind      string     `json:"kind"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	BookID    *uuid.UUID `json:"bookId,omitempty"`
+	PickID    *uuid.UUID `json:"pickId,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ReadAt    *time.Time `json:"readAt"`
+}
+
+// InboxResponse represents a page of the inbox, the newest notifications first. NextCursor is empty on the last page.
+type InboxResponse struct {
+	Notifications []InboxNotificationResponse `json:"notifications"`
+	NextCursor    string                      `json:"nextCursor,omitempty"`
+	Unread        int64                       `json:"unread"`
+}
+
+type InboxUnreadResponse struct {
+	Unread int64 `json:"unread"`
+}
+
+type InboxReadAllResponse struct {
+	Read int64 `json:"read"`
+}
+
+// InboxNotificationResponseFromModel creates a new inbox notification response from the model.
+func InboxNotificationResponseFromModel(notification *InboxNotification) InboxNotificationResponse {
+	return InboxNotificationResponse{
+		ID:        notification.Guid,
+		Kind:      notification.Kind,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		BookID:    notification.BookID,
+		PickID:    notification.PickID,
+		CreatedAt: notification.CreatedAt,
+		ReadAt:    notification.ReadAt,
+	}
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	LibraryMessageRoleUser      = "user"
+	LibraryMessageRoleAssistant = "assistant"
+)
+
+//----------------------------------------------
+// DB Models
+//----------------------------------------------
+
+// LibraryConversation groups the questions asked to the user's library, used for follow-ups
+type LibraryConversation struct {
+	TimestapModel
+
+	ID   uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	Guid uuid.UUID `gorm:"type:uuid;unique;not null;column:guid;default:uuid_generate_v4()"`
+
+	User   *User `gorm:"foreignKey:UserID;references:id;constraint:OnDelete:CASCADE"`
+	UserID uint  `gorm:"column:user_id;not null"`
+}
+
+func (LibraryConversation) TableName() string {
+	return "library_conversations"
+}
+
+type LibraryMessage struct {
+	ID uint `gorm:"primaryKey;autoIncrement;column:id"`
+
+	Conversation   *LibraryConversation `gorm:"foreignKey:ConversationID;references:id;constraint:OnDelete:CASCADE"`
+	ConversationID uint                 `gorm:"column:conversation_id;not null"`
+
+	/* user, assistant */
+	Role      string            `gorm:"column:role;not null"`
+	Content   string            `gorm:"column:content;not null"`
+	Citations []LibraryCitation `gorm:"column:citations;type:jsonb;serializer:json"`
+
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (LibraryMessage) TableName() string {
+	return "library_messages"
+}
+
+//----------------------------------------------
+// Request DTOs
+//----------------------------------------------
+
+type AskLibraryBody struct {
+	Question string `json:"question" validate:"required,max=1000"`
+
+	/* Set to continue a previous conversation */
+	ConversationID string `json:"conversationId" validate:"omitempty,uuid4"`
+}
+
+//----------------------------------------------
+// Response DTOs
+//----------------------------------------------
+
+// LibraryCitation links an inline marker of the answer, e.g. [1], to the pick it comes from
+type LibraryCitation struct {
+	Index     int       `json:"index"`
+	PickID    uuid.UUID `json:"pickId"`
+	BookID    uuid.UUID `json:"bookId"`
+	BookTitle string    `json:"bookTitle"`
+}
+
+type AskLibraryResponse struct {
+	ConversationID uuid.UUID         `json:"conversationId"`
+	Answer         string            `json:"answer"`
+	Citations      []LibraryCitation `json:"citations"`
+
+	/* True when no relevant pick has been found to answer the question */
+	Refused bool `json:"refused"`
+}
@@ -30,6 +30,28 @@ type BookPickSearchKeyword struct {
 	Keywod string    `gorm:"column:keyword;not null"`
 }
 
+// PickTranslation stores a translated version of a pick for a given target language.
+type PickTranslation struct {
+	TimestapModel
+
+	ID uint `gorm:"primaryKey;autoIncrement;column:id"`
+
+	Pick   *BookPick `gorm:"foreignKey:PickID;references:id;constraint:OnDelete:CASCADE"`
+	PickID uint      `gorm:"column:pick_id;not null"`
+
+	User   *User `gorm:"foreignKey:UserID;references:id;constraint:OnDelete:CASCADE"`
+	UserID uint  `gorm:"column:user_id;not null"`
+
+	Language string `gorm:"column:language;not null"`
+	/* Translated rich content as JSON, empty when the formatting could not be preserved */
+	Content     *string `gorm:"column:content"`
+	ContentText string  `gorm:"column:content_text;not null"`
+}
+
+func (PickTranslation) TableName() string {
+	return "pick_translations"
+}
+
 //----------------------------------------------
 // Request DTOs
 //----------------------------------------------
@@ -39,6 +61,13 @@ type DeleteBookPickPath struct {
 	PickID string `path:"pickId" validate:"required,uuid4"`
 }
 
+// TranslatePickParams used as model for get params when translating a whole pick
+type TranslatePickParams struct {
+	BookID string `json:"bookId" validate:"required,uuid4"`
+	PickID string `json:"pickId" validate:"required,uuid4"`
+	Lang   string `json:"lang" validate:"required"`
+}
+
 //----------------------------------------------
 // Response DTOs
 //----------------------------------------------
@@ -48,6 +77,26 @@ type BookPickResponse struct {
 	Content string    `json:"content"`
 	Index   uint      `json:"index"`
 	Title   string    `json:"title"`
+
+	/* Side-by-side translation, only set when requested with GetPicksParams.Lang */
+	Translation *PickTranslationResponse `json:"translation,omitempty" gorm:"-"`
+}
+
+type PickTranslationResponse struct {
+	PickID      uuid.UUID `json:"pickId"`
+	Lang        string    `json:"lang"`
+	Content     *string   `json:"content"`
+	ContentText string    `json:"contentText"`
+}
+
+// PickTranslationResponseFromModel converts a PickTranslation to a PickTranslationResponse
+func PickTranslationResponseFromModel(pickID uuid.UUID, translation *PickTranslation) *PickTranslationResponse {
+	return &PickTranslationResponse{
+		PickID:      pickID,
+		Lang:        translation.Language,
+		Content:     translation.Content,
+		ContentText: translation.ContentText,
+	}
 }
 
 type BookPickPreviewResponse struct {
@@ -1,10 +1,18 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/* Kind of the notification, the daily pick has no kind */
+const PushNotificationKindReview = "review"
 
 type PushNotificationAlert struct {
-	Title string `json:"title"`
-	Body  string `json:"body"`
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Body     string `json:"body"`
 }
 
 type PusNotificationAps struct {
@@ -14,9 +22,36 @@ type PusNotificationAps struct {
 
 type PushNotificationPayloadData struct {
 	BookID uuid.UUID `json:"bookId"`
+	// PickID lets the app open the pick itself
+	PickID uuid.UUID `json:"pickId"`
+	Kind   string    `json:"kind,omitempty"`
 }
 
 type PushNotificationPayload struct {
 	Aps  PusNotificationAps          `json:"aps"`
 	Data PushNotificationPayloadData `json:"data"`
 }
+
+// NotificationHistory represents a pick surfaced by the daily push notification or the weekly digest, once per user and local date.
+type NotificationHistory struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	UserID    uint      `gorm:"column:user_id;not null"`
+	PickID    uint      `gorm:"column:pick_id;not null"`
+	Strategy  string    `gorm:"column:strategy;not null"`
+	LocalDate time.Time `gorm:"column:local_date;type:date;not null"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (NotificationHistory) TableName() string {
+	return "notification_history"
+}
+
+// DailyPick represents the pick chosen for the daily push notification and the strategy which chose it.
+type DailyPick struct {
+	PickID    uint      `json:"pick_id"`
+	PickGuid  uuid.UUID `json:"pick_guid"`
+	BookID    uuid.UUID `json:"book_id"`
+	BookTitle string    `json:"book_title"`
+	Content   string    `json:"content"`
+	Strategy  string    `json:"strategy"`
+}
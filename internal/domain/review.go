@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//----------------------------------------------
+// DB Models
+//----------------------------------------------
+
+// PickReviewState is the spaced repetition scheduling state of a pick, picks without a state are new
+type PickReviewState struct {
+	TimestapModel
+
+	ID uint `gorm:"primaryKey;autoIncrement;column:id"`
+
+	Pick   *BookPick `gorm:"foreignKey:PickID;references:id;constraint:OnDelete:CASCADE"`
+	PickID uint      `gorm:"column:pick_id;unique;not null"`
+
+	User   *User `gorm:"foreignKey:UserID;references:id;constraint:OnDelete:CASCADE"`
+	UserID uint  `gorm:"column:user_id;not null"`
+
+	Ease           float64    `gorm:"column:ease;not null"`
+	IntervalDays   int        `gorm:"column:interval_days;not null"`
+	Repetitions    int        `gorm:"column:repetitions;not null"`
+	Lapses         int        `gorm:"column:lapses;not null"`
+	DueAt          time.Time  `gorm:"column:due_at;not null"`
+	LastReviewedAt *time.Time `gorm:"column:last_reviewed_at"`
+}
+
+func (PickReviewState) TableName() string {
+	return "pick_review_states"
+}
+
+// PickReviewLog is the history of the reviews, used for statistics
+type PickReviewLog struct {
+	ID uint `gorm:"primaryKey;autoIncrement;column:id"`
+
+	Pick   *BookPick `gorm:"foreignKey:PickID;references:id;constraint:OnDelete:CASCADE"`
+	PickID uint      `gorm:"column:pick_id;not null"`
+
+	User   *User `gorm:"foreignKey:UserID;references:id;constraint:OnDelete:CASCADE"`
+	UserID uint  `gorm:"column:user_id;not null"`
+
+	Grade                int       `gorm:"column:grade;not null"`
+	WasNew               bool      `gorm:"column:was_new;not null"`
+	Ease                 float64   `gorm:"column:ease;not null"`
+	IntervalDays         int       `gorm:"column:interval_days;not null"`
+	PreviousIntervalDays int       `gorm:"column:previous_interval_days;not null"`
+	ReviewedAt           time.Time `gorm:"column:reviewed_at"`
+}
+
+func (PickReviewLog) TableName() string {
+	return "pick_review_logs"
+}
+
+// ReviewLimit stores the daily review limits of the user for a book or a topic, or for all the picks when
+// neither is set. The limits of a scope count only the reviews of its picks
+type ReviewLimit struct {
+	TimestapModel
+
+	ID uint `gorm:"primaryKey;autoIncrement;column:id"`
+
+	User   *User `gorm:"foreignKey:UserID;references:id;constraint:OnDelete:CASCADE"`
+	UserID uint  `gorm:"column:user_id;not null"`
+
+	Book    *Book `gorm:"foreignKey:BookID;references:id;constraint:OnDelete:CASCADE"`
+	BookID  *uint `gorm:"column:book_id"`
+	TopicID *uint `gorm:"column:topic_id"`
+
+	DailyLimit    int `gorm:"column:daily_limit;not null"`
+	NewDailyLimit int `gorm:"column:new_daily_limit;not null"`
+}
+
+func (ReviewLimit) TableName() string {
+	return "review_limits"
+}
+
+//----------------------------------------------
+// Request DTOs
+//----------------------------------------------
+
+// ReviewDueParams used as model for get params of the due reviews, the daily limits are the ones stored for the book
+// or the topic, then the ones of the user, then the configured ones
+type ReviewDueParams struct {
+	BookID string `json:"bookId" validate:"omitempty,uuid4"`
+	Topic  string `json:"topic"`
+}
+
+// ReviewLimitBody sets the daily limits of a book or a topic, or of all the picks when neither is set
+type ReviewLimitBody struct {
+	BookID        string `json:"bookId" validate:"omitempty,uuid4,excluded_with=Topic"`
+	Topic         string `json:"topic"`
+	DailyLimit    *int   `json:"dailyLimit" validate:"required,gte=0"`
+	NewDailyLimit *int   `json:"newDailyLimit" validate:"required,gte=0"`
+}
+
+type GradeReviewBody struct {
+	PickID string `json:"pickId" validate:"required,uuid4"`
+	/* 0 (blackout) to 5 (perfect recall) */
+	Grade *int `json:"grade" validate:"required,gte=0,lte=5"`
+}
+
+//----------------------------------------------
+// Response DTOs
+//----------------------------------------------
+
+type ReviewPickResponse struct {
+	PickID          uuid.UUID  `json:"pickId"`
+	BookID          uuid.UUID  `json:"bookId"`
+	BookTitle       string     `json:"bookTitle"`
+	PickTitle       string     `json:"pickTitle"`
+	PickContent     string     `json:"pickContent"`
+	PickContentText string     `json:"pickContentText"`
+	IsNew           bool       `json:"isNew"`
+	DueAt           *time.Time `json:"dueAt"`
+	IntervalDays    int        `json:"intervalDays"`
+}
+
+type ReviewDueResponse struct {
+	Picks []ReviewPickResponse `json:"picks"`
+	/* Reviews still allowed today, the returned picks included */
+	RemainingReviews int `json:"remainingReviews"`
+	RemainingNew     int `json:"remainingNew"`
+}
+
+type ReviewLimitResponse struct {
+	BookID        *uuid.UUID `json:"bookId,omitempty"`
+	Topic         string     `json:"topic,omitempty"`
+	DailyLimit    int        `json:"dailyLimit"`
+	NewDailyLimit int        `json:"newDailyLimit"`
+}
+
+type GradeReviewResponse struct {
+	PickID       uuid.UUID `json:"pickId"`
+	Ease         float64   `json:"ease"`
+	IntervalDays int       `json:"intervalDays"`
+	Repetitions  int       `json:"repetitions"`
+	Lapses       int       `json:"lapses"`
+	DueAt        time.Time `json:"dueAt"`
+}
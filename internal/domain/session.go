@@ -15,28 +15,152 @@ import (
 type Session struct {
 	TimestapModel
 
+	ID          uint      `gorm:"primaryKey;autoIncrement;column:id"`
 	Guid        uuid.UUID `gorm:"type:uuid;unique;not null;column:guid;default:uuid_generate_v4()"`
 	User        User      `gorm:"foreignKey:UserID;references:id;constraint:OnDelete:CASCADE"`
 	UserID      uint      `gorm:"column:user_id;not null"`
 	DeviceID    string    `gorm:"column:device_id;not null"`
 	DeviceToken string    `gorm:"column:device_token"`
 	ExpiredAt   time.Time `gorm:"column:expired_at"`
+
+	// Platform and PushEnvironment tell where the push notifications of the device token are delivered
+	Platform        string `gorm:"column:platform;not null;default:ios"`
+	PushEnvironment string `gorm:"column:push_environment;not null;default:production"`
+
+	// DailyPickSentOn is the local date of the last daily pick sent to the device
+	DailyPickSentOn *time.Time `gorm:"column:daily_pick_sent_on;type:date"`
+
+	// The outcome of the last push notification, PushFailures counts the failed ones in a row and
+	// PushDisabledAt is set once they reach the limit
+	LastPushOutcome string     `gorm:"column:last_push_outcome"`
+	LastPushReason  string     `gorm:"column:last_push_reason"`
+	LastPushAt      *time.Time `gorm:"column:last_push_at"`
+	PushFailures    int        `gorm:"column:push_failures;not null;default:0"`
+	PushDisabledAt  *time.Time `gorm:"column:push_disabled_at"`
 }
 
 type ShortSession struct {
-	Guid        uuid.UUID       `json:"guid"`
-	UserID      uint            `json:"user_id"`
-	DeviceToken string          `json:"device_token"`
-	Settings    json.RawMessage `json:"settings"`
+	ID              uint            `json:"-"`
+	Guid            uuid.UUID       `json:"guid"`
+	UserID          uint            `json:"user_id"`
+	DeviceToken     string          `json:"device_token"`
+	Platform        string          `json:"platform"`
+	PushEnvironment string          `json:"push_environment"`
+	Settings        json.RawMessage `json:"settings"`
+	// LocalDate is the date of the daily pick in the timezone of the user
+	LocalDate string `json:"local_date,omitempty" gorm:"-"`
 }
 
 func (Session) TableName() string {
 	return "sessions"
 }
 
+// Platforms of the devices.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+)
+
+// Environments of the push notifications, the development builds of the app (Xcode, TestFlight) get the
+// device tokens of the APNs sandbox.
+const (
+	PushEnvironmentProduction = "production"
+	PushEnvironmentSandbox    = "sandbox"
+)
+
+// Outcomes of the push notifications sent to a device.
+const (
+	PushOutcomeDelivered = "delivered"
+	// the provider does not know the device token anymore, e.g. the app was uninstalled
+	PushOutcomeInvalidToken = "invalid_token"
+	// the provider could not take the push notification, it may succeed later
+	PushOutcomeTransient = "transient"
+	// the provider refused the push notification
+	PushOutcomeFailed = "failed"
+)
+
+// SessionDevice represents the device of a session and where its push notifications are delivered.
+type SessionDevice struct {
+	ID          string
+	Token       string
+	Platform    string
+	Environment string
+}
+
+// NewSessionDevice creates the device of a session, the devices of the former app versions are iOS production ones.
+func NewSessionDevice(id string, token string, platform string, environment string) *SessionDevice {
+	if platform == "" {
+		platform = PlatformIOS
+	}
+
+	if environment == "" {
+		environment = PushEnvironmentProduction
+	}
+
+	return &SessionDevice{
+		ID:          id,
+		Token:       token,
+		Platform:    platform,
+		Environment: environment,
+	}
+}
+
+// RefreshToken is a refresh token issued for a session, only the hash of its ID is stored.
+// The tokens of a session form a family: each refresh rotates the token and reusing a rotated one revokes them all.
+type RefreshToken struct {
+	ID uint `gorm:"primaryKey;autoIncrement;column:id"`
+
+	Session   *Session `gorm:"foreignKey:SessionID;references:id;constraint:OnDelete:CASCADE"`
+	SessionID uint     `gorm:"column:session_id;not null"`
+
+	TokenHash string     `gorm:"column:token_hash;unique;not null"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null"`
+	RotatedAt *time.Time `gorm:"column:rotated_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
 // Session Update Request
 
 type PatchSessionBody struct {
 	Guid        string `json:"guid" validate:"required"`
 	DeviceToken string `json:"device_token" validate:"required"`
+	Platform    string `json:"platform" validate:"omitempty,oneof=ios android"`
+	Environment string `json:"environment" validate:"omitempty,oneof=production sandbox"`
+}
+
+// Session Response
+
+// SessionResponse represents an active session of the user, one per device.
+type SessionResponse struct {
+	Guid       uuid.UUID `json:"guid"`
+	DeviceID   string    `json:"device_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// Whether the device has granted the permission for push notifications
+	IsPushEnabled bool `json:"is_push_enabled"`
+	// Whether it is the session of the access token used for the request
+	IsCurrent bool `json:"is_current"`
+}
+
+// LogoutOtherSessionsResponse represents the number of sessions logged out.
+type LogoutOtherSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// SessionResponseFromModel creates a new session response from the session model.
+func SessionResponseFromModel(session *Session, currentSessionID uuid.UUID) SessionResponse {
+	return SessionResponse{
+		Guid:          session.Guid,
+		DeviceID:      session.DeviceID,
+		CreatedAt:     session.CreatedAt,
+		LastSeenAt:    session.UpdatedAt,
+		IsPushEnabled: session.DeviceToken != "" && session.PushDisabledAt == nil,
+		IsCurrent:     session.Guid == currentSessionID,
+	}
 }
@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Environments of the App Store.
+const (
+	AppStoreEnvironmentProduction = "Production"
+	AppStoreEnvironmentSandbox    = "Sandbox"
+)
+
+// AppStoreNotificationTest is the type of the notifications requested from App Store Connect to test the endpoint.
+const AppStoreNotificationTest = "TEST"
+
+//----------------------------------------------
+// DB Models
+//----------------------------------------------
+
+// Subscription represents the state of an auto-renewable subscription, identified by its original transaction.
+// SignedAt is the date Apple signed the data last applied, older notifications are ignored.
+type Subscription struct {
+	TimestapModel
+
+	ID   uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	Guid uuid.UUID `gorm:"type:uuid;unique;not null;column:guid;default:uuid_generate_v4()"`
+
+	User   *User `gorm:"foreignKey:UserID;references:id;constraint:OnDelete:CASCADE"`
+	UserID uint  `gorm:"column:user_id;not null"`
+
+	OriginalTransactionID string     `gorm:"column:original_transaction_id;unique;not null"`
+	TransactionID         string     `gorm:"column:transaction_id;not null"`
+	ProductID             string     `gorm:"column:product_id;not null"`
+	Environment           string     `gorm:"column:environment;not null"`
+	PurchasedAt           time.Time  `gorm:"column:purchased_at;not null"`
+	ExpiresAt             time.Time  `gorm:"column:expires_at;not null"`
+	GracePeriodExpiresAt  *time.Time `gorm:"column:grace_period_expires_at"`
+	IsAutoRenewEnabled    bool       `gorm:"column:auto_renew_enabled;not null"`
+	AutoRenewProductID    string     `gorm:"column:auto_renew_product_id;not null"`
+	IsInBillingRetry      bool       `gorm:"column:billing_retry;not null"`
+	RevokedAt             *time.Time `gorm:"column:revoked_at"`
+	RevocationReason      *int       `gorm:"column:revocation_reason"`
+	SignedAt              time.Time  `gorm:"column:signed_at;not null"`
+}
+
+func (Subscription) TableName() string {
+	return "subscriptions"
+}
+
+// PremiumExpiresAt returns the end of the premium access given by the subscription, the grace period included.
+// It is nil when the subscription has been refunded or revoked.
+func (s *Subscription) PremiumExpiresAt() *time.Time {
+	if s.RevokedAt != nil {
+		return nil
+	}
+
+	expiresAt := s.ExpiresAt
+	if s.GracePeriodExpiresAt != nil && s.GracePeriodExpiresAt.After(expiresAt) {
+		expiresAt = *s.GracePeriodExpiresAt
+	}
+
+	return &expiresAt
+}
+
+// IsPremium tells whether the subscription gives the premium access at the given time.
+func (s *Subscription) IsPremium(now time.Time) bool {
+	expiresAt := s.PremiumExpiresAt()
+	return expiresAt != nil && expiresAt.After(now)
+}
+
+//----------------------------------------------
+// App Store signed data, dates are in milliseconds
+//----------------------------------------------
+
+// AppStoreTransaction represents the payload of a signed transaction (JWSTransactionDecodedPayload).
+type AppStoreTransaction struct {
+	TransactionID         string `json:"transactionId"`
+	OriginalTransactionID string `json:"originalTransactionId"`
+	BundleID              string `json:"bundleId"`
+	ProductID             string `json:"productId"`
+	PurchaseDate          int64  `json:"purchaseDate"`
+	ExpiresDate           int64  `json:"expiresDate"`
+	RevocationDate        int64  `json:"revocationDate,omitempty"`
+	RevocationReason      *int   `json:"revocationReason,omitempty"`
+	// AppAccountToken is the GUID of the user, set by the app when purchasing
+	AppAccountToken string `json:"appAccountToken,omitempty"`
+	Environment     string `json:"environment"`
+	SignedDate      int64  `json:"signedDate"`
+}
+
+// AppStoreRenewalInfo represents the payload of a signed renewal info (JWSRenewalInfoDecodedPayload).
+type AppStoreRenewalInfo struct {
+	OriginalTransactionID  string `json:"originalTransactionId"`
+	AutoRenewProductID     string `json:"autoRenewProductId"`
+	AutoRenewStatus        int    `json:"autoRenewStatus"`
+	IsInBillingRetryPeriod bool   `json:"isInBillingRetryPeriod"`
+	GracePeriodExpiresDate int64  `json:"gracePeriodExpiresDate,omitempty"`
+	Environment            string `json:"environment"`
+	SignedDate             int64  `json:"signedDate"`
+}
+
+// AppStoreNotification represents the payload of an App Store Server Notification V2 (responseBodyV2DecodedPayload).
+type AppStoreNotification struct {
+	NotificationType string                   `json:"notificationType"`
+	Subtype          string                   `json:"subtype,omitempty"`
+	NotificationUUID string                   `json:"notificationUUID"`
+	Data             AppStoreNotificationData `json:"data"`
+	Version          string                   `json:"version"`
+	SignedDate       int64                    `json:"signedDate"`
+}
+
+// AppStoreNotificationData represents the app and the signed subscription data of a notification.
+type AppStoreNotificationData struct {
+	BundleID              string `json:"bundleId"`
+	Environment           string `json:"environment"`
+	SignedTransactionInfo string `json:"signedTransactionInfo,omitempty"`
+	SignedRenewalInfo     string `json:"signedRenewalInfo,omitempty"`
+}
+
+// AppStoreLastTransaction represents the signed last transaction and renewal info of a subscription.
+type AppStoreLastTransaction struct {
+	OriginalTransactionID string `json:"originalTransactionId"`
+	Status                int    `json:"status"`
+	SignedTransactionInfo string `json:"signedTransactionInfo"`
+	SignedRenewalInfo     string `json:"signedRenewalInfo"`
+}
+
+//----------------------------------------------
+// Request DTOs
+//----------------------------------------------
+
+// VerifySubscriptionBody represents the transaction of a purchase made by the app.
+type VerifySubscriptionBody struct {
+	TransactionID string `json:"transaction_id" validate:"required,numeric,max=64"`
+}
+
+// AppStoreNotificationBody represents the body of an App Store Server Notification V2.
+type AppStoreNotificationBody struct {
+	SignedPayload string `json:"signedPayload" validate:"required"`
+}
+
+//----------------------------------------------
+// Response DTOs
+//----------------------------------------------
+
+type SubscriptionResponse struct {
+	ProductID            string     `json:"product_id"`
+	ExpiresAt            time.Time  `json:"expires_at"`
+	GracePeriodExpiresAt *time.Time `json:"grace_period_expires_at"`
+	IsAutoRenewEnabled   bool       `json:"is_auto_renew_enabled"`
+	IsRevoked            bool       `json:"is_revoked"`
+	IsPremium            bool       `json:"is_premium"`
+}
+
+func SubscriptionResponseFromModel(subscription *Subscription, now time.Time) *SubscriptionResponse {
+	return &SubscriptionResponse{
+		ProductID:            subscription.ProductID,
+		ExpiresAt:            subscription.ExpiresAt,
+		GracePeriodExpiresAt: subscription.GracePeriodExpiresAt,
+		IsAutoRenewEnabled:   subscription.IsAutoRenewEnabled,
+		IsRevoked:            subscription.RevokedAt != nil,
+		IsPremium:            subscription.IsPremium(now),
+	}
+}
@@ -1,6 +1,11 @@
 package domain
 
 import (
+	"encoding/json"
+	"time"
+	/* Lambda has no timezone database, the timezones of the settings need the embedded one */
+	_ "time/tzdata"
+
 	"github.com/google/uuid"
 )
 
@@ -21,7 +26,11 @@ type User struct {
 	Provider   string        `gorm:"column:provider;not null"`
 	Settings   *UserSettings `gorm:"column:settings;type:jsonb;serializer:json"`
 
-	SubscriptionReceiptID string `gorm:"column:subscription_receipt_id"`
+	// PremiumExpiresAt is the end of the premium access of the subscriptions, kept in sync by the subscription service
+	PremiumExpiresAt *time.Time `gorm:"column:premium_expires_at"`
+
+	// DigestSentOn is the first day of the week of the last weekly digest sent to the user
+	DigestSentOn *time.Time `gorm:"column:digest_sent_on;type:date"`
 
 	IsNotificationEnabled bool `gorm:"column:is_notification_enabled;default:false"`
 	IsActive              bool `gorm:"column:is_active;default:true"`
@@ -34,20 +43,55 @@ func (User) TableName() string {
 	return "users"
 }
 
-// UserSettings represents the user settings domain.
+// IsPremium tells whether the user has the premium access at the given time.
+func (u *User) IsPremium(now time.Time) bool {
+	return u.PremiumExpiresAt != nil && u.PremiumExpiresAt.After(now)
+}
+
+// UserSettings represents the user settings domain, stored as JSON. The blobs of the previous versions are
+// upgraded when they are read, see UserSettingsVersion.
 type UserSettings struct {
+	/* Version of the schema of the blob, set by the server */
+	Version int `json:"version"`
+
 	DarkMode       bool   `json:"darkMode"`
-	AppLanguage    string `json:"appLanguage"`
-	SecondLanguage string `json:"secondLanguage"`
+	AppLanguage    string `json:"appLanguage" validate:"omitempty,bcp47_language_tag"`
+	SecondLanguage string `json:"secondLanguage" validate:"omitempty,bcp47_language_tag"`
+
+	NotificationEnabled bool   `json:"notificationEnabled"`
+	NotificationMode    string `json:"notificationMode" validate:"oneof=all last-edit reviews"`
+
+	/* IANA timezone and local time (HH:MM) of the daily pick, UTC and 13:00 when not set */
+	Timezone         string `json:"timezone" validate:"omitempty,timezone"`
+	NotificationTime string `json:"notificationTime" validate:"omitempty,datetime=15:04"`
+	/* No notification between the start and the end (HH:MM), they may span midnight */
+	QuietHoursStart string `json:"quietHoursStart" validate:"omitempty,datetime=15:04"`
+	QuietHoursEnd   string `json:"quietHoursEnd" validate:"omitempty,datetime=15:04"`
+	/* Days of the week of the daily pick, 0 is Sunday, every day when empty */
+	NotificationDays []int `json:"notificationDays" validate:"omitempty,max=7,unique,dive,min=0,max=6"`
+
+	/* Opt-in weekly email digest of the library */
+	WeeklyDigest bool `json:"weeklyDigest"`
+}
+
+// Location returns the timezone of the user, UTC when it is not set or unknown.
+func (s *UserSettings) Location() *time.Location {
+	if s == nil || s.Timezone == "" {
+		return time.UTC
+	}
+
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
 
-	NotificationEnabled bool `json:"notificationEnabled"`
-	/* all, last-edit */
-	NotificationMode string `json:"notificationMode"`
+	return location
 }
 
 // NewUserSettings creates a new user settings.
 func NewUserSettings() *UserSettings {
 	return &UserSettings{
+		Version:             UserSettingsVersion,
 		DarkMode:            true,
 		AppLanguage:         "",
 		SecondLanguage:      "",
@@ -81,7 +125,7 @@ func UserDtoFromModel(user User, sessionID uuid.UUID) *UserDto {
 		FamilyName: user.FamilyName,
 		Settings:   user.Settings,
 		IsCreated:  user.IsCreated,
-		IsPremium:  user.SubscriptionReceiptID != "",
+		IsPremium:  user.IsPremium(time.Now()),
 		SessionID:  sessionID,
 	}
 }
@@ -99,8 +143,10 @@ type ThirdPartyUser struct {
 	FamilyName string `mapstructure:"family_name"`
 	Provider   string
 
-	DeviceID    string `mapstructure:"device_id"`
-	DeviceToken string `mapstructure:"device_token"`
+	DeviceID          string `mapstructure:"device_id"`
+	DeviceToken       string `mapstructure:"device_token"`
+	DevicePlatform    string `mapstructure:"device_platform"`
+	DeviceEnvironment string `mapstructure:"device_environment"`
 }
 
 // NewGoogleThirdPartyUser creates a new Google third party user.
@@ -117,14 +163,21 @@ func NewAppleThirdPartyUser() *ThirdPartyUser {
 	}
 }
 
-// UserProfileUpdate represents the user profile update domain.
+// NewEmailThirdPartyUser creates a new email third party user.
+func NewEmailThirdPartyUser() *ThirdPartyUser {
+	return &ThirdPartyUser{
+		Provider: "email",
+	}
+}
+
+// UserProfileUpdate represents the user profile update domain, the settings are a JSON merge patch.
 type UserProfileUpdate struct {
-	SubscriptionReceiptID string       `json:"subscription_receipt_id"`
-	Settings              UserSettings `json:"settings"`
+	Settings json.RawMessage `json:"settings"`
 }
 
 // UserHealth body response
 type UserHealth struct {
-	IsHealthy bool `json:"is_healthy"`
-	IsPremium bool `json:"is_premium"`
+	IsHealthy    bool                  `json:"is_healthy"`
+	IsPremium    bool                  `json:"is_premium"`
+	Entitlements *EntitlementsResponse `json:"entitlements"`
 }
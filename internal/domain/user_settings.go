@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"encoding/json"
+)
+
+// UserSettingsVersion is the version of the schema of the user settings written by the server.
+const UserSettingsVersion = 1
+
+/* The migrations of the settings blobs, the one at index i upgrades version i to i+1, the unversioned blobs are version 0 */
+var userSettingsMigrations = []func(settings map[string]any){
+	upgradeUserSettingsV0,
+}
+
+/* The unversioned blobs may hold nulls and the modes of the older apps, they fall back to the defaults */
+func upgradeUserSettingsV0(settings map[string]any) {
+	for key, value := range settings {
+		if value == nil {
+			delete(settings, key)
+		}
+	}
+
+	switch settings["notificationMode"] {
+	case "all", "last-edit", "reviews":
+	default:
+		delete(settings, "notificationMode")
+	}
+}
+
+// UpgradeUserSettings upgrades the settings blob to UserSettingsVersion, the blobs of a newer version are left as they are.
+func UpgradeUserSettings(settings map[string]any) {
+	version := 0
+	if value, ok := settings["version"].(float64); ok && value > 0 {
+		version = int(value)
+	}
+
+	if version >= UserSettingsVersion {
+		return
+	}
+
+	for ; version < UserSettingsVersion; version++ {
+		userSettingsMigrations[version](settings)
+	}
+
+	settings["version"] = UserSettingsVersion
+}
+
+// UnmarshalJSON reads the settings blob upgraded to the current version, the missing fields get the defaults
+// of NewUserSettings.
+func (s *UserSettings) UnmarshalJSON(data []byte) error {
+	settings := map[string]any{}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+
+	/* A null blob leaves the settings as they are, like the other types */
+	if settings == nil {
+		return nil
+	}
+
+	UpgradeUserSettings(settings)
+
+	upgraded, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	/* The plain type has no UnmarshalJSON, decoding it does not recurse */
+	type plainUserSettings UserSettings
+	effective := plainUserSettings(*NewUserSettings())
+	if err := json.Unmarshal(upgraded, &effective); err != nil {
+		return err
+	}
+
+	*s = UserSettings(effective)
+	return nil
+}
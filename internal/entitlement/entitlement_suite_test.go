@@ -0,0 +1,13 @@
+package entitlement_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestEntitlement(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Entitlement Suite")
+}
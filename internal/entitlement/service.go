@@ -0,0 +1,237 @@
+package entitlement
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ Service = (*serviceImpl)(nil)
+
+// Service represents the entitlements service, the capabilities of the users follow their plan.
+// The checks return a *failure.UpgradeRequiredErr when the plan does not allow the capability.
+//
+//go:generate mockgen -source=service.go -destination=./service_mock.go -package=entitlement
+type Service interface {
+	// GetEntitlements Get the entitlements of the plan of the user with their current usage
+	GetEntitlements(userID uuid.UUID) (*domain.EntitlementsResponse, error)
+	// CheckBookLimit Check the user can add one more book, in the transaction tx that creates it
+	CheckBookLimit(tx *gorm.DB, userID uuid.UUID) error
+	// CheckPickLimit Check the user can add one more pick to the book, in the transaction tx that creates it
+	CheckPickLimit(tx *gorm.DB, userID, bookID uuid.UUID) error
+	// CheckExportFormat Check the user can export the library in the format
+	CheckExportFormat(userID uuid.UUID, format string) error
+	// CheckSearchMode Check the user can search with the mode
+	CheckSearchMode(userID uuid.UUID, mode string) error
+	// UseAICall Count an AI call of the user for today, it fails once the daily limit is reached
+	UseAICall(userID uuid.UUID) error
+}
+
+type serviceImpl struct {
+	db          *gorm.DB
+	userService user.Service
+	config      *config.Entitlements
+}
+
+// NewService creates a new entitlements service.
+func NewService(db *gorm.DB, userService user.Service, config *config.Entitlements) Service {
+	return &serviceImpl{
+		db:          db,
+		userService: userService,
+		config:      config,
+	}
+}
+
+// ResolveEntitlements returns the entitlements of the plan of the user at the given time. The subscription
+// decides the premium plan, otherwise the user is in trial for the configured days after signing up.
+// Every plan can export its library as JSON, it is the user's own data, the Markdown export is premium.
+func ResolveEntitlements(user *domain.User, config *config.Entitlements, now time.Time) *domain.Entitlements {
+	premium := &domain.Entitlements{
+		Plan:             domain.PlanPremium,
+		MaxBooks:         domain.Unlimited,
+		MaxPicksPerBook:  domain.Unlimited,
+		MaxAICallsPerDay: config.PremiumMaxAICallsPerDay,
+		ExportFormats:    []string{domain.ExportFormatJSON, domain.ExportFormatMarkdown},
+		SearchModes:      []string{domain.SearchModeBook, domain.SearchModeLibrary},
+	}
+
+	if user.IsPremium(now) {
+		return premium
+	}
+
+	if config.TrialDays > 0 {
+		trialEndsAt := user.CreatedAt.AddDate(0, 0, config.TrialDays)
+		if now.Before(trialEndsAt) {
+			premium.Plan = domain.PlanTrial
+			premium.TrialEndsAt = &trialEndsAt
+			return premium
+		}
+	}
+
+	return &domain.Entitlements{
+		Plan:             domain.PlanFree,
+		MaxBooks:         config.FreeMaxBooks,
+		MaxPicksPerBook:  config.FreeMaxPicksPerBook,
+		MaxAICallsPerDay: config.FreeMaxAICallsPerDay,
+		ExportFormats:    []string{domain.ExportFormatJSON},
+		SearchModes:      []string{domain.SearchModeBook},
+	}
+}
+
+func (service *serviceImpl) GetEntitlements(userID uuid.UUID) (*domain.EntitlementsResponse, error) {
+	user, entitlements, err := service.entitlementsOf(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domain.EntitlementsResponse{Entitlements: *entitlements}
+
+	if err := service.db.Model(&domain.Book{}).Where("user_id = ?", user.ID).Count(&response.Usage.Books).Error; err != nil {
+		return nil, err
+	}
+
+	err = service.db.Model(&domain.AIUsage{}).
+		Where("user_id = ? AND day = ?", user.ID, today()).
+		Pluck("calls", &response.Usage.AICallsToday).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (service *serviceImpl) CheckBookLimit(tx *gorm.DB, userID uuid.UUID) error {
+	user, entitlements, err := service.lockedEntitlementsOf(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	if entitlements.MaxBooks < 0 {
+		return nil
+	}
+
+	var books int64
+	if err := tx.Model(&domain.Book{}).Where("user_id = ?", user.ID).Count(&books).Error; err != nil {
+		return err
+	}
+
+	if !domain.IsWithinLimit(books, entitlements.MaxBooks) {
+		return failure.NewUpgradeRequiredErr(domain.CapabilityBooks, entitlements.Plan, entitlements.MaxBooks)
+	}
+
+	return nil
+}
+
+func (service *serviceImpl) CheckPickLimit(tx *gorm.DB, userID, bookID uuid.UUID) error {
+	user, entitlements, err := service.lockedEntitlementsOf(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	if entitlements.MaxPicksPerBook < 0 {
+		return nil
+	}
+
+	var picks int64
+	err = tx.Model(&domain.BookPick{}).
+		Joins("JOIN books ON books.id = book_picks.book_id").
+		Where("books.guid = ? AND books.user_id = ?", bookID, user.ID).
+		Count(&picks).Error
+	if err != nil {
+		return err
+	}
+
+	if !domain.IsWithinLimit(picks, entitlements.MaxPicksPerBook) {
+		return failure.NewUpgradeRequiredErr(domain.CapabilityPicksPerBook, entitlements.Plan, entitlements.MaxPicksPerBook)
+	}
+
+	return nil
+}
+
+func (service *serviceImpl) CheckExportFormat(userID uuid.UUID, format string) error {
+	_, entitlements, err := service.entitlementsOf(userID)
+	if err != nil {
+		return err
+	}
+
+	if !entitlements.AllowsExport(format) {
+		return failure.NewUpgradeRequiredErr(domain.CapabilityExport, entitlements.Plan, 0)
+	}
+
+	return nil
+}
+
+func (service *serviceImpl) CheckSearchMode(userID uuid.UUID, mode string) error {
+	_, entitlements, err := service.entitlementsOf(userID)
+	if err != nil {
+		return err
+	}
+
+	if !entitlements.AllowsSearch(mode) {
+		return failure.NewUpgradeRequiredErr(domain.CapabilitySearch, entitlements.Plan, 0)
+	}
+
+	return nil
+}
+
+func (service *serviceImpl) UseAICall(userID uuid.UUID) error {
+	user, entitlements, err := service.entitlementsOf(userID)
+	if err != nil {
+		return err
+	}
+
+	limit := entitlements.MaxAICallsPerDay
+	if limit == 0 {
+		return failure.NewUpgradeRequiredErr(domain.CapabilityAICalls, entitlements.Plan, limit)
+	}
+
+	/* Count the call only while below the limit, in a single statement so concurrent calls cannot exceed it */
+	var calls []int
+	err = service.db.Raw(`
+		INSERT INTO ai_usages (user_id, day, calls) VALUES (?, ?, 1)
+		ON CONFLICT (user_id, day) DO UPDATE SET calls = ai_usages.calls + 1
+		WHERE ? < 0 OR ai_usages.calls < ?
+		RETURNING calls
+	`, user.ID, today(), limit, limit).Scan(&calls).Error
+	if err != nil {
+		return err
+	}
+
+	if len(calls) == 0 {
+		return failure.NewUpgradeRequiredErr(domain.CapabilityAICalls, entitlements.Plan, limit)
+	}
+
+	return nil
+}
+
+// entitlementsOf returns the user with the entitlements of its plan.
+func (service *serviceImpl) entitlementsOf(userID uuid.UUID) (*domain.User, *domain.Entitlements, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, ResolveEntitlements(user, service.config, time.Now()), nil
+}
+
+// lockedEntitlementsOf returns the user with the entitlements of its plan, the user row stays locked until the
+// end of the transaction so that the concurrent creations of the user are counted one after the other.
+func (service *serviceImpl) lockedEntitlementsOf(tx *gorm.DB, userID uuid.UUID) (*domain.User, *domain.Entitlements, error) {
+	user := domain.User{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("guid = ?", userID).First(&user).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return &user, ResolveEntitlements(&user, service.config, time.Now()), nil
+}
+
+/* The AI calls are counted per UTC day */
+func today() string {
+	return time.Now().UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,127 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=service.go -destination=./service_mock.go -package=entitlement
+//
+
+// Package entitlement is a generated GoMock package.
+package entitlement
+
+import (
+	reflect "reflect"
+
+	uuid "github.com/google/uuid"
+	domain "github.com/pietro-putelli/feynman-backend/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// CheckBookLimit mocks base method.
+func (m *MockService) CheckBookLimit(tx *gorm.DB, userID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckBookLimit", tx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckBookLimit indicates an expected call of CheckBookLimit.
+func (mr *MockServiceMockRecorder) CheckBookLimit(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckBookLimit", reflect.TypeOf((*MockService)(nil).CheckBookLimit), tx, userID)
+}
+
+// CheckExportFormat mocks base method.
+func (m *MockService) CheckExportFormat(userID uuid.UUID, format string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckExportFormat", userID, format)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckExportFormat indicates an expected call of CheckExportFormat.
+func (mr *MockServiceMockRecorder) CheckExportFormat(userID, format any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckExportFormat", reflect.TypeOf((*MockService)(nil).CheckExportFormat), userID, format)
+}
+
+// CheckPickLimit mocks base method.
+func (m *MockService) CheckPickLimit(tx *gorm.DB, userID, bookID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckPickLimit", tx, userID, bookID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckPickLimit indicates an expected call of CheckPickLimit.
+func (mr *MockServiceMockRecorder) CheckPickLimit(tx, userID, bookID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckPickLimit", reflect.TypeOf((*MockService)(nil).CheckPickLimit), tx, userID, bookID)
+}
+
+// CheckSearchMode mocks base method.
+func (m *MockService) CheckSearchMode(userID uuid.UUID, mode string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckSearchMode", userID, mode)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckSearchMode indicates an expected call of CheckSearchMode.
+func (mr *MockServiceMockRecorder) CheckSearchMode(userID, mode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSearchMode", reflect.TypeOf((*MockService)(nil).CheckSearchMode), userID, mode)
+}
+
+// GetEntitlements mocks base method.
+func (m *MockService) GetEntitlements(userID uuid.UUID) (*domain.EntitlementsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEntitlements", userID)
+	ret0, _ := ret[0].(*domain.EntitlementsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEntitlements indicates an expected call of GetEntitlements.
+func (mr *MockServiceMockRecorder) GetEntitlements(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEntitlements", reflect.TypeOf((*MockService)(nil).GetEntitlements), userID)
+}
+
+// UseAICall mocks base method.
+func (m *MockService) UseAICall(userID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseAICall", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseAICall indicates an expected call of UseAICall.
+func (mr *MockServiceMockRecorder) UseAICall(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseAICall", reflect.TypeOf((*MockService)(nil).UseAICall), userID)
+}
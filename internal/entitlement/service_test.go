@@ -0,0 +1,223 @@
+package entitlement_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/entitlement"
+	"github.com/pietro-putelli/feynman-backend/internal/failure"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+)
+
+var _ = Describe("Service", func() {
+	var entitlementsConfig *config.Entitlements
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		entitlementsConfig = &config.Entitlements{
+			TrialDays:               7,
+			FreeMaxBooks:            5,
+			FreeMaxPicksPerBook:     20,
+			FreeMaxAICallsPerDay:    10,
+			PremiumMaxAICallsPerDay: 500,
+		}
+	})
+
+	Describe("ResolveEntitlements", func() {
+		It("should give the free limits once the trial is over", func() {
+			// Arrange
+			user := &domain.User{TimestapModel: domain.TimestapModel{CreatedAt: now.AddDate(0, 0, -30)}}
+
+			// Act
+			entitlements := entitlement.ResolveEntitlements(user, entitlementsConfig, now)
+
+			// Assert
+			Expect(entitlements.Plan).To(Equal(domain.PlanFree))
+			Expect(entitlements.MaxBooks).To(Equal(5))
+			Expect(entitlements.MaxPicksPerBook).To(Equal(20))
+			Expect(entitlements.MaxAICallsPerDay).To(Equal(10))
+			Expect(entitlements.AllowsExport(domain.ExportFormatJSON)).To(BeTrue())
+			Expect(entitlements.AllowsExport(domain.ExportFormatMarkdown)).To(BeFalse())
+			Expect(entitlements.AllowsSearch(domain.SearchModeBook)).To(BeTrue())
+			Expect(entitlements.AllowsSearch(domain.SearchModeLibrary)).To(BeFalse())
+			Expect(entitlements.TrialEndsAt).To(BeNil())
+		})
+
+		It("should give the premium capabilities during the trial", func() {
+			// Arrange
+			user := &domain.User{TimestapModel: domain.TimestapModel{CreatedAt: now.AddDate(0, 0, -2)}}
+
+			// Act
+			entitlements := entitlement.ResolveEntitlements(user, entitlementsConfig, now)
+
+			// Assert
+			Expect(entitlements.Plan).To(Equal(domain.PlanTrial))
+			Expect(entitlements.MaxBooks).To(Equal(domain.Unlimited))
+			Expect(entitlements.AllowsSearch(domain.SearchModeLibrary)).To(BeTrue())
+			Expect(*entitlements.TrialEndsAt).To(Equal(now.AddDate(0, 0, 5)))
+		})
+
+		It("should not give a trial when it is disabled", func() {
+			// Arrange
+			entitlementsConfig.TrialDays = 0
+			user := &domain.User{TimestapModel: domain.TimestapModel{CreatedAt: now}}
+
+			// Act
+			entitlements := entitlement.ResolveEntitlements(user, entitlementsConfig, now)
+
+			// Assert
+			Expect(entitlements.Plan).To(Equal(domain.PlanFree))
+		})
+
+		It("should give the premium plan to the subscribers", func() {
+			// Arrange
+			premiumExpiresAt := now.Add(time.Hour)
+			user := &domain.User{
+				TimestapModel:    domain.TimestapModel{CreatedAt: now},
+				PremiumExpiresAt: &premiumExpiresAt,
+			}
+
+			// Act
+			entitlements := entitlement.ResolveEntitlements(user, entitlementsConfig, now)
+
+			// Assert
+			Expect(entitlements.Plan).To(Equal(domain.PlanPremium))
+			Expect(entitlements.MaxPicksPerBook).To(Equal(domain.Unlimited))
+			Expect(entitlements.MaxAICallsPerDay).To(Equal(500))
+			Expect(entitlements.AllowsExport(domain.ExportFormatJSON)).To(BeTrue())
+			Expect(entitlements.AllowsExport(domain.ExportFormatMarkdown)).To(BeTrue())
+			Expect(entitlements.TrialEndsAt).To(BeNil())
+		})
+	})
+
+	Describe("Checks", func() {
+		var (
+			userService *user.MockService
+			service     entitlement.Service
+			userID      uuid.UUID
+		)
+
+		BeforeEach(func() {
+			ctrl := gomock.NewController(GinkgoT())
+			userService = user.NewMockService(ctrl)
+			service = entitlement.NewService(nil, userService, entitlementsConfig)
+			userID = uuid.Must(uuid.NewRandom())
+
+			userService.EXPECT().GetUserByGuid(userID).Return(&domain.User{
+				TimestapModel: domain.TimestapModel{CreatedAt: time.Now().AddDate(0, -1, 0)},
+			}, nil)
+		})
+
+		It("should require an upgrade to search the whole library", func() {
+			// Act
+			err := service.CheckSearchMode(userID, domain.SearchModeLibrary)
+
+			// Assert
+			var upgradeErr *failure.UpgradeRequiredErr
+			Expect(errors.As(err, &upgradeErr)).To(BeTrue())
+			Expect(upgradeErr.Capability).To(Equal(domain.CapabilitySearch))
+			Expect(upgradeErr.Plan).To(Equal(domain.PlanFree))
+		})
+
+		It("should require an upgrade for the formats the plan does not export", func() {
+			// Act
+			err := service.CheckExportFormat(userID, domain.ExportFormatMarkdown)
+
+			// Assert
+			var upgradeErr *failure.UpgradeRequiredErr
+			Expect(errors.As(err, &upgradeErr)).To(BeTrue())
+			Expect(upgradeErr.Capability).To(Equal(domain.CapabilityExport))
+		})
+
+		It("should refuse the AI calls when the plan has none", func() {
+			// Arrange
+			entitlementsConfig.FreeMaxAICallsPerDay = 0
+
+			// Act
+			err := service.UseAICall(userID)
+
+			// Assert
+			var upgradeErr *failure.UpgradeRequiredErr
+			Expect(errors.As(err, &upgradeErr)).To(BeTrue())
+			Expect(upgradeErr.Capability).To(Equal(domain.CapabilityAICalls))
+			Expect(upgradeErr.Limit).To(Equal(0))
+		})
+
+		It("should allow searching within a book", func() {
+			// Act
+			err := service.CheckSearchMode(userID, domain.SearchModeBook)
+
+			// Assert
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Describe("Limits", func() {
+		var (
+			service entitlement.Service
+			sqlMock sqlmock.Sqlmock
+			db      *gorm.DB
+			userID  uuid.UUID
+		)
+
+		BeforeEach(func() {
+			sqlDB, sqlMockGen, _ := sqlmock.New()
+			sqlMock = sqlMockGen
+
+			conn := postgres.New(postgres.Config{
+				Conn: sqlDB,
+			})
+
+			db, _ = database.NewDB(conn)
+			service = entitlement.NewService(db, nil, entitlementsConfig)
+			userID = uuid.Must(uuid.NewRandom())
+
+			sqlMock.ExpectQuery(`SELECT \* FROM "users" WHERE guid = \$1 ORDER BY "users"."id" LIMIT \$2 FOR UPDATE`).
+				WithArgs(userID, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "guid", "created_at"}).AddRow(7, userID, time.Now().AddDate(0, -1, 0)))
+		})
+
+		It("should count the books of the user with the user locked", func() {
+			// Arrange
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "books" WHERE user_id = \$1`).
+				WithArgs(7).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+
+			// Act
+			err := service.CheckBookLimit(db, userID)
+
+			// Assert
+			var upgradeErr *failure.UpgradeRequiredErr
+			Expect(errors.As(err, &upgradeErr)).To(BeTrue())
+			Expect(upgradeErr.Capability).To(Equal(domain.CapabilityBooks))
+			Expect(upgradeErr.Limit).To(Equal(5))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should allow one more pick below the limit", func() {
+			// Arrange
+			bookID := uuid.Must(uuid.NewRandom())
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "book_picks" JOIN books ON books.id = book_picks.book_id WHERE books.guid = \$1 AND books.user_id = \$2`).
+				WithArgs(bookID, 7).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(19))
+
+			// Act
+			err := service.CheckPickLimit(db, userID, bookID)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
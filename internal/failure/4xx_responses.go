@@ -33,3 +33,79 @@ func NewNotFound(message string) *events.APIGatewayProxyResponse {
 		},
 	}
 }
+
+// NewUnauthorized creates a new unauthorized response.
+func NewUnauthorized(message string) *events.APIGatewayProxyResponse {
+	err := NewError(401, message)
+
+	errMessage, _ := json.Marshal(err)
+	return &events.APIGatewayProxyResponse{
+		StatusCode: 401,
+		Body:       string(errMessage),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+}
+
+// NewForbidden creates a new forbidden response.
+func NewForbidden(message string) *events.APIGatewayProxyResponse {
+	err := NewError(403, message)
+
+	errMessage, _ := json.Marshal(err)
+	return &events.APIGatewayProxyResponse{
+		StatusCode: 403,
+		Body:       string(errMessage),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+}
+
+// NewConflict creates a new conflict response.
+func NewConflict(message string) *events.APIGatewayProxyResponse {
+	err := NewError(409, message)
+
+	errMessage, _ := json.Marshal(err)
+	return &events.APIGatewayProxyResponse{
+		StatusCode: 409,
+		Body:       string(errMessage),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+}
+
+// NewTooManyRequests creates a new too many requests response.
+func NewTooManyRequests(message string) *events.APIGatewayProxyResponse {
+	err := NewError(429, message)
+
+	errMessage, _ := json.Marshal(err)
+	return &events.APIGatewayProxyResponse{
+		StatusCode: 429,
+		Body:       string(errMessage),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+}
+
+// NewUpgradeRequired creates a new payment required response, with the capability the plan does not allow.
+func NewUpgradeRequired(upgradeErr *UpgradeRequiredErr) *events.APIGatewayProxyResponse {
+	err := struct {
+		*Error
+		*UpgradeRequiredErr
+	}{
+		Error:              NewError(402, "Upgrade required"),
+		UpgradeRequiredErr: upgradeErr,
+	}
+
+	errMessage, _ := json.Marshal(err)
+	return &events.APIGatewayProxyResponse{
+		StatusCode: 402,
+		Body:       string(errMessage),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+}
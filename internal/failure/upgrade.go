@@ -0,0 +1,25 @@
+package failure
+
+import "fmt"
+
+//-------------------------------------
+// Upgrade Required
+//-------------------------------------
+
+// UpgradeRequiredErr represents a capability the plan of the user does not allow, Limit is the reached limit
+// or 0 when the capability is not included at all.
+type UpgradeRequiredErr struct {
+	Capability string `json:"capability"`
+	Plan       string `json:"plan"`
+	Limit      int    `json:"limit"`
+}
+
+// NewUpgradeRequiredErr creates a new upgrade required error.
+func NewUpgradeRequiredErr(capability string, plan string, limit int) *UpgradeRequiredErr {
+	return &UpgradeRequiredErr{Capability: capability, Plan: plan, Limit: limit}
+}
+
+// Error returns the error message.
+func (e *UpgradeRequiredErr) Error() string {
+	return fmt.Sprintf("upgrade required: %s not allowed by the %s plan (limit %d)", e.Capability, e.Plan, e.Limit)
+}
@@ -0,0 +1,211 @@
+package inbox
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ Service = (*serviceImpl)(nil)
+
+var (
+	// ErrNotificationNotFound is returned when the user has no notification with the id.
+	ErrNotificationNotFound = errors.New("notification not found")
+	// ErrInvalidCursor is returned when the cursor was not returned by a previous page.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// DefaultLimit is the number of notifications of a page when the request sets none.
+const DefaultLimit = 20
+
+// Service represents the in-app inbox service, the notifications of the users are kept until they are read.
+//
+//go:generate mockgen -source=service.go -destination=./service_mock.go -package=inbox
+type Service interface {
+	// GetNotifications Get a page of the notifications of the user, the newest first
+	GetNotifications(userID uuid.UUID, params *domain.InboxListParams) (*domain.InboxResponse, error)
+	// GetUnreadCount Get the number of unread notifications of the user
+	GetUnreadCount(userID uuid.UUID) (*domain.InboxUnreadResponse, error)
+	// MarkRead Mark a notification of the user as read
+	MarkRead(userID uuid.UUID, notificationID uuid.UUID) error
+	// MarkAllRead Mark every notification of the user as read
+	MarkAllRead(userID uuid.UUID) (*domain.InboxReadAllResponse, error)
+	// Notify Add a notification to the inbox of the user, once per dedupe key
+	Notify(notification *domain.InboxNotification) error
+	// CountUnread Count the unread notifications of the user, the badge of the push notifications
+	CountUnread(userID uint) (int64, error)
+}
+
+type serviceImpl struct {
+	db          *gorm.DB
+	userService user.Service
+}
+
+// NewService creates a new inbox service.
+func NewService(db *gorm.DB, userService user.Service) Service {
+	return &serviceImpl{
+		db:          db,
+		userService: userService,
+	}
+}
+
+// Add adds the notification to the inbox with the database, so the other services can add one within their
+// transaction. The notifications of an existing dedupe key are ignored.
+func Add(db *gorm.DB, notification *domain.InboxNotification) error {
+	if notification.DedupeKey == nil {
+		return db.Create(notification).Error
+	}
+
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "dedupe_key"}},
+		DoNothing: true,
+	}).Create(notification).Error
+}
+
+// DedupeKey returns the dedupe key of the parts, e.g. the kind and the date of a daily pick.
+func DedupeKey(parts ...string) *string {
+	key := strings.Join(parts, ":")
+	return &key
+}
+
+func (service *serviceImpl) GetNotifications(userID uuid.UUID, params *domain.InboxListParams) (*domain.InboxResponse, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := DefaultLimit
+	if params.Limit > 0 {
+		limit = params.Limit
+	}
+
+	/* Keyset pagination on the id, one more row tells whether there is a next page */
+	query := service.db.Where("user_id = ?", user.ID)
+	if params.Cursor != "" {
+		before, err := decodeCursor(params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("id < ?", before)
+	}
+
+	notifications := []domain.InboxNotification{}
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+
+	response := &domain.InboxResponse{Notifications: []domain.InboxNotificationResponse{}}
+
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		response.NextCursor = encodeCursor(notifications[limit-1].ID)
+	}
+
+	for i := range notifications {
+		response.Notifications = append(response.Notifications, domain.InboxNotificationResponseFromModel(&notifications[i]))
+	}
+
+	if response.Unread, err = service.CountUnread(user.ID); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (service *serviceImpl) GetUnreadCount(userID uuid.UUID) (*domain.InboxUnreadResponse, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := service.CountUnread(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.InboxUnreadResponse{Unread: unread}, nil
+}
+
+func (service *serviceImpl) MarkRead(userID uuid.UUID, notificationID uuid.UUID) error {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return err
+	}
+
+	/* Reading it again keeps the first read time */
+	result := service.db.Model(&domain.InboxNotification{}).
+		Where("user_id = ? AND guid = ?", user.ID, notificationID).
+		Updates(map[string]interface{}{
+			"read_at":    gorm.Expr("COALESCE(read_at, ?)", time.Now()),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
+
+func (service *serviceImpl) MarkAllRead(userID uuid.UUID) (*domain.InboxReadAllResponse, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := service.db.Model(&domain.InboxNotification{}).
+		Where("user_id = ? AND read_at IS NULL", user.ID).
+		Updates(map[string]interface{}{
+			"read_at":    now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &domain.InboxReadAllResponse{Read: result.RowsAffected}, nil
+}
+
+func (service *serviceImpl) Notify(notification *domain.InboxNotification) error {
+	return Add(service.db, notification)
+}
+
+func (service *serviceImpl) CountUnread(userID uint) (int64, error) {
+	var unread int64
+	err := service.db.Model(&domain.InboxNotification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&unread).Error
+
+	return unread, err
+}
+
+/* The cursor is opaque to the clients, it is the id of the last notification of the page */
+func encodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeCursor(cursor string) (uint, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseUint(string(decoded), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	return uint(id), nil
+}
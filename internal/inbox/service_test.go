@@ -0,0 +1,132 @@
+package inbox_test
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"gorm.io/driver/postgres"
+
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/inbox"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+)
+
+var _ = Describe("Service", func() {
+	var (
+		service     inbox.Service
+		sqlMock     sqlmock.Sqlmock
+		userService *user.MockService
+		userID      uuid.UUID
+	)
+
+	BeforeEach(func() {
+		db, sqlMockGen, _ := sqlmock.New()
+		sqlMock = sqlMockGen
+
+		conn := postgres.New(postgres.Config{
+			Conn: db,
+		})
+
+		database, _ := database.NewDB(conn)
+
+		userID = uuid.New()
+		userService = user.NewMockService(gomock.NewController(GinkgoT()))
+		userService.EXPECT().GetUserByGuid(userID).Return(&domain.User{ID: 7, Guid: userID}, nil).AnyTimes()
+
+		service = inbox.NewService(database, userService)
+	})
+
+	Describe("GetNotifications", func() {
+		columns := []string{"id", "guid", "user_id", "kind", "title", "body"}
+
+		It("should return the cursor of the next page when there are more notifications", func() {
+			// Arrange
+			sqlMock.ExpectQuery(`FROM "inbox_notifications" WHERE user_id = \$1 ORDER BY id DESC LIMIT \$2`).
+				WithArgs(7, 3).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(30, uuid.New(), 7, domain.InboxKindDailyPick, "title", "body").
+					AddRow(20, uuid.New(), 7, domain.InboxKindDailyPick, "title", "body").
+					AddRow(10, uuid.New(), 7, domain.InboxKindSubscription, "title", "body"))
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "inbox_notifications" WHERE user_id = \$1 AND read_at IS NULL`).
+				WithArgs(7).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+			// Act
+			page, err := service.GetNotifications(userID, &domain.InboxListParams{Limit: 2})
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(page.Notifications).To(HaveLen(2))
+			Expect(page.NextCursor).NotTo(BeEmpty())
+			Expect(page.Unread).To(Equal(int64(3)))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+
+			// The next page starts after the last notification
+			sqlMock.ExpectQuery(`user_id = \$1 AND id < \$2 ORDER BY id DESC LIMIT \$3`).
+				WithArgs(7, 20, 3).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(10, uuid.New(), 7, domain.InboxKindSubscription, "title", "body"))
+			sqlMock.ExpectQuery(`SELECT count\(\*\)`).
+				WithArgs(7).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+			next, err := service.GetNotifications(userID, &domain.InboxListParams{Cursor: page.NextCursor, Limit: 2})
+
+			Expect(err).To(BeNil())
+			Expect(next.Notifications).To(HaveLen(1))
+			Expect(next.NextCursor).To(BeEmpty())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should reject a cursor it did not return", func() {
+			// Act
+			page, err := service.GetNotifications(userID, &domain.InboxListParams{Cursor: "not a cursor"})
+
+			// Assert
+			Expect(page).To(BeNil())
+			Expect(err).To(MatchError(inbox.ErrInvalidCursor))
+		})
+	})
+
+	Describe("MarkRead", func() {
+		It("should return not found for the notification of another user", func() {
+			// Arrange
+			notificationID := uuid.New()
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectExec(`UPDATE "inbox_notifications" SET .* WHERE user_id = \$3 AND guid = \$4`).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 7, notificationID).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			sqlMock.ExpectCommit()
+
+			// Act
+			err := service.MarkRead(userID, notificationID)
+
+			// Assert
+			Expect(err).To(MatchError(inbox.ErrNotificationNotFound))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+
+	Describe("MarkAllRead", func() {
+		It("should return the number of notifications read", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectExec(`UPDATE "inbox_notifications" SET .* WHERE user_id = \$3 AND read_at IS NULL`).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 7).
+				WillReturnResult(sqlmock.NewResult(0, 4))
+			sqlMock.ExpectCommit()
+
+			// Act
+			read, err := service.MarkAllRead(userID)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(read.Read).To(Equal(int64(4)))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
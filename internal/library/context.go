@@ -0,0 +1,42 @@
+package library
+
+import (
+	"errors"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/book"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/entitlement"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+	"gorm.io/gorm"
+)
+
+type Context struct {
+	Service  Service
+	Database *gorm.DB
+}
+
+func NewContext() (*Context, error) {
+	// load configuration
+	config, err := config.NewConfig()
+	if err != nil {
+		return nil, errors.New("failed load library context config: " + err.Error())
+	}
+
+	// load database
+	database, err := database.NewDB(database.NewConn(&config.Database))
+	if err != nil {
+		return nil, errors.New("failed load library context database: " + err.Error())
+	}
+
+	userService := user.NewService(database)
+	entitlementService := entitlement.NewService(database, userService, &config.Entitlements)
+	bookService := book.NewService(database, userService, entitlementService)
+
+	service := NewService(database, &config.Library, userService, bookService, entitlementService)
+
+	return &Context{
+		Service:  service,
+		Database: database,
+	}, nil
+}
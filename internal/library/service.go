@@ -0,0 +1,237 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/book"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/entitlement"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+	"github.com/pietro-putelli/feynman-backend/langchain"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+/* Returned when no pick is relevant to the question */
+const refusalAnswer = "I couldn't find anything in your picks that answers this question."
+
+var _ Service = (*serviceImpl)(nil)
+
+type Service interface {
+	// Ask Answer a question grounded on the user's picks, citing the picks used.
+	// The AI call is counted only when some picks are relevant to the question.
+	Ask(userID uuid.UUID, body *domain.AskLibraryBody) (*domain.AskLibraryResponse, error)
+}
+
+type serviceImpl struct {
+	db           *gorm.DB
+	config       *config.Library
+	userService  user.Service
+	bookService  book.Service
+	entitlements entitlement.Service
+}
+
+// NewService creates a new library service
+func NewService(db *gorm.DB, config *config.Library, userService user.Service, bookService book.Service, entitlements entitlement.Service) Service {
+	return &serviceImpl{
+		db:           db,
+		config:       config,
+		userService:  userService,
+		bookService:  bookService,
+		entitlements: entitlements,
+	}
+}
+
+//---------------------------------------------------------------------
+// Service Implementation
+//---------------------------------------------------------------------
+
+func (service *serviceImpl) Ask(userID uuid.UUID, body *domain.AskLibraryBody) (*domain.AskLibraryResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	conversation := domain.LibraryConversation{UserID: user.ID}
+	history := []domain.LibraryMessage{}
+
+	/* 1. Load the conversation to follow up, only the last messages are kept in the prompt */
+	if body.ConversationID != "" {
+		err := service.db.Where("guid = ? AND user_id = ?", body.ConversationID, user.ID).First(&conversation).Error
+		if err != nil {
+			return nil, err
+		}
+
+		err = service.db.Where("conversation_id = ?", conversation.ID).
+			Order("created_at DESC").
+			Limit(service.config.MaxHistoryMessages).
+			Find(&history).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+			history[i], history[j] = history[j], history[i]
+		}
+	}
+
+	/* 2. Retrieve the candidate picks with the existing search */
+	candidates, err := service.searchCandidates(userID, body.Question, previousQuestion(history))
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domain.AskLibraryResponse{
+		Answer:    refusalAnswer,
+		Citations: []domain.LibraryCitation{},
+		Refused:   true,
+	}
+
+	if len(candidates) > 0 {
+		/* 3. Build the context window and ask the LLM, the refusal above is not counted as an AI call */
+		context, citations := BuildContextWindow(candidates, service.config.MaxContextTokens)
+
+		if err := service.entitlements.UseAICall(userID); err != nil {
+			return nil, err
+		}
+
+		result, err := langchain.AnswerLibraryQuestion(body.Question, formatHistory(history), context, service.config.MaxAnswerTokens)
+		if err != nil {
+			logger.Error("Failed to answer library question", zap.Error(err))
+			return nil, err
+		}
+
+		answer, _ := result["answer"].(string)
+		used := usedCitations(result["citations"], citations)
+
+		if strings.TrimSpace(answer) != "" {
+			response.Answer = answer
+			response.Citations = used
+			response.Refused = false
+		}
+	}
+
+	/* 4. Store the question and the answer to allow follow-ups */
+	err = service.db.Transaction(func(tx *gorm.DB) error {
+		if conversation.ID == 0 {
+			if err := tx.Create(&conversation).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Model(&domain.LibraryConversation{}).Where("id = ?", conversation.ID).Update("updated_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+
+		messages := []domain.LibraryMessage{
+			{
+				ConversationID: conversation.ID,
+				Role:           domain.LibraryMessageRoleUser,
+				Content:        body.Question,
+			},
+			{
+				ConversationID: conversation.ID,
+				Role:           domain.LibraryMessageRoleAssistant,
+				Content:        response.Answer,
+				Citations:      response.Citations,
+			},
+		}
+
+		return tx.Create(&messages).Error
+	})
+	if err != nil {
+		logger.Error("Failed to store library conversation", zap.Error(err))
+		return nil, err
+	}
+
+	response.ConversationID = conversation.Guid
+
+	return response, nil
+}
+
+/* Search the picks for each term generated from the question and rank the merged results */
+func (service *serviceImpl) searchCandidates(userID uuid.UUID, question, previous string) ([]domain.SemanticSearchResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	terms, err := langchain.GenerateSearchTerms(question, previous)
+	if err != nil || len(terms) == 0 {
+		logger.Error("Failed to generate search terms, falling back to the question", zap.Error(err))
+		terms = []string{question}
+	}
+
+	results := [][]domain.SemanticSearchResponse{}
+
+	for _, term := range terms {
+		picks, err := service.bookService.SemanticSearch(userID, &domain.SearchGetParams{
+			Query:  term,
+			Offset: 0,
+			Limit:  service.config.MaxCandidatePicks,
+		})
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+
+		results = append(results, picks)
+	}
+
+	return RankCandidates(results, service.config.MaxCandidatePicks), nil
+}
+
+func previousQuestion(history []domain.LibraryMessage) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == domain.LibraryMessageRoleUser {
+			return history[i].Content
+		}
+	}
+	return ""
+}
+
+func formatHistory(history []domain.LibraryMessage) string {
+	if len(history) == 0 {
+		return "None"
+	}
+
+	lines := make([]string, len(history))
+	for i, message := range history {
+		lines[i] = fmt.Sprintf("%s: %s", message.Role, message.Content)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+/* Keep only the citations returned by the LLM that match a pick of the context */
+func usedCitations(value interface{}, citations []domain.LibraryCitation) []domain.LibraryCitation {
+	used := []domain.LibraryCitation{}
+
+	indexes, ok := value.([]interface{})
+	if !ok {
+		return used
+	}
+
+	seen := map[int]bool{}
+	for _, rawIndex := range indexes {
+		number, ok := rawIndex.(float64)
+		if !ok {
+			continue
+		}
+
+		index := int(number)
+		if index < 1 || index > len(citations) || seen[index] {
+			continue
+		}
+
+		seen[index] = true
+		used = append(used, citations[index-1])
+	}
+
+	return used
+}
@@ -0,0 +1,85 @@
+package library
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+/* Rough estimation used for budgeting, on average a token is about 4 characters */
+const charactersPerToken = 4
+
+// EstimateTokens returns an approximation of the number of tokens of the text.
+func EstimateTokens(text string) int {
+	return (len([]rune(text)) + charactersPerToken - 1) / charactersPerToken
+}
+
+// RankCandidates merges the results of several searches, the picks found by more terms come first.
+func RankCandidates(results [][]domain.SemanticSearchResponse, limit int) []domain.SemanticSearchResponse {
+	hits := map[uuid.UUID]int{}
+	candidates := []domain.SemanticSearchResponse{}
+
+	for _, result := range results {
+		for _, pick := range result {
+			if _, ok := hits[pick.PickID]; !ok {
+				candidates = append(candidates, pick)
+			}
+			hits[pick.PickID]++
+		}
+	}
+
+	/* Stable insertion sort, keeps the search order for picks with the same hits */
+	for i := 1; i < len(candidates); i++ {
+		for j := i; j > 0 && hits[candidates[j].PickID] > hits[candidates[j-1].PickID]; j-- {
+			candidates[j], candidates[j-1] = candidates[j-1], candidates[j]
+		}
+	}
+
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	return candidates
+}
+
+// BuildContextWindow numbers the candidates starting from 1 and adds them to the prompt context until the token budget is reached.
+// It returns the context and the citations, the citation index matches the number in the context.
+func BuildContextWindow(candidates []domain.SemanticSearchResponse, maxTokens int) (string, []domain.LibraryCitation) {
+	var builder strings.Builder
+	citations := []domain.LibraryCitation{}
+	usedTokens := 0
+
+	for _, candidate := range candidates {
+		index := len(citations) + 1
+		header := fmt.Sprintf("[%d] From \"%s\": ", index, candidate.BookTitle)
+		content := strings.TrimSpace(candidate.PickContent)
+
+		available := maxTokens - usedTokens - EstimateTokens(header)
+		if available <= 0 {
+			break
+		}
+
+		/* Only the first pick is truncated to fit, the following ones are skipped */
+		if EstimateTokens(content) > available {
+			if len(citations) > 0 {
+				break
+			}
+			content = string([]rune(content)[:available*charactersPerToken])
+		}
+
+		entry := header + content + "\n"
+		builder.WriteString(entry)
+		usedTokens += EstimateTokens(entry)
+
+		citations = append(citations, domain.LibraryCitation{
+			Index:     index,
+			PickID:    candidate.PickID,
+			BookID:    candidate.BookID,
+			BookTitle: candidate.BookTitle,
+		})
+	}
+
+	return builder.String(), citations
+}
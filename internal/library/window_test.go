@@ -0,0 +1,100 @@
+package library_test
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/library"
+)
+
+var _ = Describe("Context Window", func() {
+	newPick := func(title, content string) domain.SemanticSearchResponse {
+		return domain.SemanticSearchResponse{
+			BookID:      uuid.Must(uuid.NewRandom()),
+			BookTitle:   title,
+			PickID:      uuid.Must(uuid.NewRandom()),
+			PickContent: content,
+		}
+	}
+
+	Describe("RankCandidates", func() {
+		It("should put the picks found by more terms first", func() {
+			// Arrange
+			first := newPick("Atomic Habits", "habits are the compound interest of self-improvement")
+			second := newPick("Thinking, Fast and Slow", "system 1 operates automatically")
+
+			// Act
+			result := library.RankCandidates([][]domain.SemanticSearchResponse{
+				{first, second},
+				{second},
+			}, 10)
+
+			// Assert
+			Expect(result).To(HaveLen(2))
+			Expect(result[0].PickID).To(Equal(second.PickID))
+			Expect(result[1].PickID).To(Equal(first.PickID))
+		})
+
+		It("should limit the candidates", func() {
+			// Arrange
+			picks := []domain.SemanticSearchResponse{newPick("A", "a"), newPick("B", "b"), newPick("C", "c")}
+
+			// Act
+			result := library.RankCandidates([][]domain.SemanticSearchResponse{picks}, 2)
+
+			// Assert
+			Expect(result).To(HaveLen(2))
+			Expect(result[0].PickID).To(Equal(picks[0].PickID))
+		})
+	})
+
+	Describe("BuildContextWindow", func() {
+		It("should number the picks and return matching citations", func() {
+			// Arrange
+			picks := []domain.SemanticSearchResponse{newPick("A", "first pick"), newPick("B", "second pick")}
+
+			// Act
+			context, citations := library.BuildContextWindow(picks, 1000)
+
+			// Assert
+			Expect(context).To(ContainSubstring("[1] From \"A\": first pick"))
+			Expect(context).To(ContainSubstring("[2] From \"B\": second pick"))
+			Expect(citations).To(HaveLen(2))
+			Expect(citations[1].Index).To(Equal(2))
+			Expect(citations[1].PickID).To(Equal(picks[1].PickID))
+			Expect(citations[1].BookID).To(Equal(picks[1].BookID))
+		})
+
+		It("should stop when the token budget is reached", func() {
+			// Arrange
+			picks := []domain.SemanticSearchResponse{
+				newPick("A", strings.Repeat("a", 200)),
+				newPick("B", strings.Repeat("b", 200)),
+			}
+
+			// Act
+			context, citations := library.BuildContextWindow(picks, 80)
+
+			// Assert
+			Expect(citations).To(HaveLen(1))
+			Expect(context).NotTo(ContainSubstring("bbb"))
+			Expect(library.EstimateTokens(context)).To(BeNumerically("<=", 80))
+		})
+
+		It("should truncate the first pick when it exceeds the budget", func() {
+			// Arrange
+			picks := []domain.SemanticSearchResponse{newPick("A", strings.Repeat("a", 1000))}
+
+			// Act
+			context, citations := library.BuildContextWindow(picks, 50)
+
+			// Assert
+			Expect(citations).To(HaveLen(1))
+			Expect(library.EstimateTokens(context)).To(BeNumerically("<=", 51))
+		})
+	})
+})
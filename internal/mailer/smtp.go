@@ -0,0 +1,125 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+)
+
+var _ Mailer = (*SMTPMailer)(nil)
+
+// SMTPMailer represents a mailer sending through a SMTP server with STARTTLS.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from *mail.Address
+}
+
+// NewSMTPMailer creates a new SMTP mailer.
+func NewSMTPMailer(cfg config.Smtp) (*SMTPMailer, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp sender: %w", err)
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return &SMTPMailer{
+		addr: cfg.Host + ":" + strconv.Itoa(cfg.Port),
+		auth: auth,
+		from: from,
+	}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message *Message) error {
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+
+	body, err := m.encode(to, message)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return smtp.SendMail(m.addr, m.auth, m.from.Address, []string{to.Address}, body)
+}
+
+/* Encode the message as multipart/alternative, the plain text part first */
+func (m *SMTPMailer) encode(to *mail.Address, message *Message) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+
+	buffer.WriteString(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n",
+		m.from.String(), to.String(), mime.QEncoding.Encode("utf-8", message.Subject)))
+
+	/* The extra headers in a stable order, a line break in a value would start a new header */
+	names := make([]string, 0, len(message.Headers))
+	for name := range message.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := message.Headers[name]
+		if strings.ContainsAny(name+value, "\r\n") {
+			return nil, fmt.Errorf("invalid header %s", name)
+		}
+		buffer.WriteString(fmt.Sprintf("%s: %s\r\n", textproto.CanonicalMIMEHeaderKey(name), value))
+	}
+
+	buffer.WriteString(fmt.Sprintf("MIME-Version: 1.0\r\nContent-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary()))
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", message.Text},
+		{"text/html; charset=utf-8", message.HTML},
+	}
+
+	for _, part := range parts {
+		if part.content == "" {
+			continue
+		}
+
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
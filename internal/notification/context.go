@@ -0,0 +1,35 @@
+package notification
+
+import (
+	"errors"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"gorm.io/gorm"
+)
+
+type Context struct {
+	Service  Service
+	Config   *config.Config
+	Database *gorm.DB
+}
+
+func NewContext() (*Context, error) {
+	config, err := config.NewConfig()
+	if err != nil {
+		return nil, errors.New("failed load notification context config: " + err.Error())
+	}
+
+	database, err := database.NewDB(database.NewConn(&config.Database))
+	if err != nil {
+		return nil, errors.New("failed load notification context database: " + err.Error())
+	}
+
+	service := NewService(database, &config.Push)
+
+	return &Context{
+		Service:  service,
+		Config:   config,
+		Database: database,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package notification
+
+import (
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/sns"
+	"go.uber.org/zap"
+)
+
+// BatchPublisher publishes the messages to a topic, it returns the indexes of the messages that were not published.
+type BatchPublisher interface {
+	PublishBatch(topicName string, messages []interface{}) ([]int, error)
+}
+
+// PublishDailyPicks publishes the sessions to the push notification topic, batchSize sessions per message. It returns
+// the number of sessions published and the sessions that were not, all of them when the publisher fails outright.
+func PublishDailyPicks(publisher BatchPublisher, sessions []domain.ShortSession, batchSize int) (int, []domain.ShortSession) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	batchSize = max(batchSize, 1)
+
+	batches := [][]domain.ShortSession{}
+	for start := 0; start < len(sessions); start += batchSize {
+		batches = append(batches, sessions[start:min(start+batchSize, len(sessions))])
+	}
+
+	messages := make([]interface{}, len(batches))
+	for i, batch := range batches {
+		messages[i] = batch
+	}
+
+	failedIndexes, err := publisher.PublishBatch(sns.TopicNames.PushNotification, messages)
+	if err != nil {
+		logger.Error("Error sending SNS messages", zap.Error(err))
+		return 0, sessions
+	}
+
+	unpublished := []domain.ShortSession{}
+	for _, i := range failedIndexes {
+		unpublished = append(unpublished, batches[i]...)
+	}
+
+	return len(sessions) - len(unpublished), unpublished
+}
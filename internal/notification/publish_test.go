@@ -0,0 +1,49 @@
+package notification_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/notification"
+)
+
+/* Publisher failing the messages of failed, or all of them with err */
+type failingPublisher struct {
+	failed []int
+	err    error
+}
+
+func (p *failingPublisher) PublishBatch(topicName string, messages []interface{}) ([]int, error) {
+	return p.failed, p.err
+}
+
+var _ = Describe("PublishDailyPicks", func() {
+	sessions := []domain.ShortSession{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	It("should return the sessions of the batches that were not published", func() {
+		// Arrange
+		publisher := &failingPublisher{failed: []int{1}}
+
+		// Act
+		published, unpublished := notification.PublishDailyPicks(publisher, sessions, 2)
+
+		// Assert
+		Expect(published).To(Equal(2))
+		Expect(unpublished).To(Equal([]domain.ShortSession{{ID: 3}}))
+	})
+
+	It("should return every session when the publisher fails outright", func() {
+		// Arrange
+		publisher := &failingPublisher{err: errors.New("topic not found")}
+
+		// Act
+		published, unpublished := notification.PublishDailyPicks(publisher, sessions, 2)
+
+		// Assert
+		Expect(published).To(Equal(0))
+		Expect(unpublished).To(Equal(sessions))
+	})
+})
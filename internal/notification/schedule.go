@@ -0,0 +1,110 @@
+package notification
+
+import (
+	"slices"
+	"time"
+
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+// DefaultNotificationTime is the local time of the daily pick of the users who have not chosen one,
+// with the UTC timezone it is the time of the former single daily run.
+const DefaultNotificationTime = "13:00"
+
+// Schedule represents when a user receives the daily pick, resolved from the settings. The invalid
+// settings fall back to the defaults so a user is never left out of the runs.
+type Schedule struct {
+	location *time.Location
+	// minute of the local day of the daily pick
+	minute int
+	// quiet hours in minutes of the local day, -1 when not set
+	quietStart int
+	quietEnd   int
+	// days of the daily pick, every day when empty
+	days []time.Weekday
+}
+
+// NewSchedule creates the schedule of the settings.
+func NewSchedule(settings *domain.UserSettings) Schedule {
+	minute, _ := parseClock(DefaultNotificationTime)
+
+	schedule := Schedule{
+		location:   settings.Location(),
+		minute:     minute,
+		quietStart: -1,
+		quietEnd:   -1,
+	}
+
+	if settings == nil {
+		return schedule
+	}
+
+	if minute, ok := parseClock(settings.NotificationTime); ok {
+		schedule.minute = minute
+	}
+
+	quietStart, startOk := parseClock(settings.QuietHoursStart)
+	quietEnd, endOk := parseClock(settings.QuietHoursEnd)
+	if startOk && endOk && quietStart != quietEnd {
+		schedule.quietStart = quietStart
+		schedule.quietEnd = quietEnd
+	}
+
+	for _, day := range settings.NotificationDays {
+		if day >= 0 && day <= 6 {
+			schedule.days = append(schedule.days, time.Weekday(day))
+		}
+	}
+
+	return schedule
+}
+
+// DueIn returns the local date of the daily pick when it is scheduled within [from, from+window).
+func (s Schedule) DueIn(from time.Time, window time.Duration) (string, bool) {
+	to := from.Add(window)
+	minute := s.sendMinute()
+
+	/* The window may span two local days */
+	for _, day := range []time.Time{from.In(s.location), to.In(s.location)} {
+		at := time.Date(day.Year(), day.Month(), day.Day(), minute/60, minute%60, 0, 0, s.location)
+
+		if !at.Before(from) && at.Before(to) && s.isSendDay(at.Weekday()) {
+			return at.Format(time.DateOnly), true
+		}
+	}
+
+	return "", false
+}
+
+// sendMinute returns the minute of the local day of the daily pick, the end of the quiet hours when it falls within them.
+func (s Schedule) sendMinute() int {
+	if s.quietStart < 0 {
+		return s.minute
+	}
+
+	isQuiet := s.minute >= s.quietStart && s.minute < s.quietEnd
+	if s.quietStart > s.quietEnd {
+		/* The quiet hours span midnight */
+		isQuiet = s.minute >= s.quietStart || s.minute < s.quietEnd
+	}
+
+	if isQuiet {
+		return s.quietEnd
+	}
+
+	return s.minute
+}
+
+func (s Schedule) isSendDay(day time.Weekday) bool {
+	return len(s.days) == 0 || slices.Contains(s.days, day)
+}
+
+/* Parse a HH:MM time to the minute of the day */
+func parseClock(value string) (int, bool) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false
+	}
+
+	return clock.Hour()*60 + clock.Minute(), true
+}
@@ -0,0 +1,109 @@
+package notification_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/notification"
+)
+
+var _ = Describe("Schedule", func() {
+	window := 15 * time.Minute
+
+	/* Every run of a day, returns the local dates of the daily picks */
+	runDay := func(schedule notification.Schedule, day time.Time) []string {
+		dates := []string{}
+		for from := day; from.Before(day.Add(24 * time.Hour)); from = from.Add(window) {
+			if localDate, isDue := schedule.DueIn(from, window); isDue {
+				dates = append(dates, localDate)
+			}
+		}
+		return dates
+	}
+
+	It("should send the daily pick at 13:00 UTC without settings", func() {
+		// Arrange
+		schedule := notification.NewSchedule(nil)
+
+		// Act
+		localDate, isDue := schedule.DueIn(time.Date(2024, 3, 5, 13, 0, 0, 0, time.UTC), window)
+		_, isDueBefore := schedule.DueIn(time.Date(2024, 3, 5, 12, 45, 0, 0, time.UTC), window)
+
+		// Assert
+		Expect(isDue).To(BeTrue())
+		Expect(localDate).To(Equal("2024-03-05"))
+		Expect(isDueBefore).To(BeFalse())
+	})
+
+	It("should send the daily pick at the local time of the user", func() {
+		// Arrange
+		schedule := notification.NewSchedule(&domain.UserSettings{Timezone: "Asia/Tokyo", NotificationTime: "08:30"})
+
+		// Act
+		localDate, isDue := schedule.DueIn(time.Date(2024, 3, 4, 23, 30, 0, 0, time.UTC), window)
+
+		// Assert
+		Expect(isDue).To(BeTrue())
+		Expect(localDate).To(Equal("2024-03-05"))
+	})
+
+	It("should send exactly one daily pick a day across the runs", func() {
+		// Arrange
+		schedule := notification.NewSchedule(&domain.UserSettings{Timezone: "America/Los_Angeles", NotificationTime: "19:10"})
+
+		// Act
+		dates := runDay(schedule, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+		// Assert
+		Expect(dates).To(Equal([]string{"2024-06-30"}))
+	})
+
+	It("should move the daily pick to the end of the quiet hours", func() {
+		// Arrange
+		schedule := notification.NewSchedule(&domain.UserSettings{
+			NotificationTime: "23:00",
+			QuietHoursStart:  "22:00",
+			QuietHoursEnd:    "07:00",
+		})
+
+		// Act
+		_, isDueAtTime := schedule.DueIn(time.Date(2024, 3, 5, 23, 0, 0, 0, time.UTC), window)
+		localDate, isDue := schedule.DueIn(time.Date(2024, 3, 6, 7, 0, 0, 0, time.UTC), window)
+
+		// Assert
+		Expect(isDueAtTime).To(BeFalse())
+		Expect(isDue).To(BeTrue())
+		Expect(localDate).To(Equal("2024-03-06"))
+	})
+
+	It("should only send the daily pick on the selected days", func() {
+		// Arrange
+		schedule := notification.NewSchedule(&domain.UserSettings{
+			Timezone:         "Europe/Rome",
+			NotificationTime: "09:00",
+			NotificationDays: []int{int(time.Saturday), int(time.Sunday)},
+		})
+
+		// Act
+		_, isDueOnFriday := schedule.DueIn(time.Date(2024, 3, 8, 8, 0, 0, 0, time.UTC), window)
+		_, isDueOnSaturday := schedule.DueIn(time.Date(2024, 3, 9, 8, 0, 0, 0, time.UTC), window)
+
+		// Assert
+		Expect(isDueOnFriday).To(BeFalse())
+		Expect(isDueOnSaturday).To(BeTrue())
+	})
+
+	It("should fall back to the defaults with invalid settings", func() {
+		// Arrange
+		schedule := notification.NewSchedule(&domain.UserSettings{Timezone: "Mars/Olympus", NotificationTime: "25:99"})
+
+		// Act
+		_, isDue := schedule.DueIn(time.Date(2024, 3, 5, 13, 0, 0, 0, time.UTC), window)
+
+		// Assert
+		Expect(isDue).To(BeTrue())
+	})
+})
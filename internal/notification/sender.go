@@ -0,0 +1,158 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+// ErrUnsupportedPlatform is returned when no sender delivers the push notifications of the platform.
+var ErrUnsupportedPlatform = errors.New("unsupported push platform")
+
+// PushMessage represents a push notification to a device.
+type PushMessage struct {
+	DeviceToken string
+	Platform    string
+	Environment string
+	Payload     domain.PushNotificationPayload
+}
+
+// PushError represents a push notification refused by the provider, Reason is the error code of the provider.
+type PushError struct {
+	StatusCode int
+	Reason     string
+}
+
+// Error returns the error message.
+func (e *PushError) Error() string {
+	return fmt.Sprintf("push refused with %d %s", e.StatusCode, e.Reason)
+}
+
+/* Reasons of the providers for a device token they do not know, APNs ones then FCM ones */
+var invalidTokenReasons = map[string]bool{
+	"BadDeviceToken":         true,
+	"Unregistered":           true,
+	"DeviceTokenNotForTopic": true,
+	"ExpiredToken":           true,
+	"UNREGISTERED":           true,
+	"SENDER_ID_MISMATCH":     true,
+}
+
+// ClassifyPush returns the outcome of a push notification from the error of its sender. The refused ones are
+// transient when the provider is overloaded or unavailable, the errors reaching no provider are transient too.
+func ClassifyPush(err error) string {
+	if err == nil {
+		return domain.PushOutcomeDelivered
+	}
+
+	if errors.Is(err, ErrUnsupportedPlatform) {
+		return domain.PushOutcomeFailed
+	}
+
+	var pushErr *PushError
+	if !errors.As(err, &pushErr) {
+		return domain.PushOutcomeTransient
+	}
+
+	switch {
+	case invalidTokenReasons[pushErr.Reason]:
+		return domain.PushOutcomeInvalidToken
+	case pushErr.StatusCode == http.StatusTooManyRequests || pushErr.StatusCode >= http.StatusInternalServerError:
+		return domain.PushOutcomeTransient
+	default:
+		return domain.PushOutcomeFailed
+	}
+}
+
+// PushSender represents a push notification provider, the refused notifications return a *PushError.
+type PushSender interface {
+	Send(ctx context.Context, message *PushMessage) error
+}
+
+// platformSender sends the messages with the sender of their platform.
+type platformSender struct {
+	senders map[string]PushSender
+}
+
+// NewPlatformSender creates a sender delivering the messages with the sender of their platform.
+func NewPlatformSender(senders map[string]PushSender) PushSender {
+	return &platformSender{senders: senders}
+}
+
+// NewPushSender creates the sender of the configuration, APNs for iOS and FCM for Android when it is configured.
+func NewPushSender(ctx context.Context, cfg *config.Config) (PushSender, error) {
+	apnsSender, err := NewAPNsSender(cfg.Apple)
+	if err != nil {
+		return nil, err
+	}
+
+	senders := map[string]PushSender{
+		domain.PlatformIOS: apnsSender,
+	}
+
+	if cfg.Fcm.ServiceAccount != "" {
+		fcmSender, err := NewFCMSender(ctx, cfg.Fcm)
+		if err != nil {
+			return nil, err
+		}
+
+		senders[domain.PlatformAndroid] = fcmSender
+	}
+
+	return NewPlatformSender(senders), nil
+}
+
+func (s *platformSender) Send(ctx context.Context, message *PushMessage) error {
+	/* The sessions registered before the platforms were stored are iOS devices */
+	platform := message.Platform
+	if platform == "" {
+		platform = domain.PlatformIOS
+	}
+
+	sender, ok := s.senders[platform]
+	if !ok {
+		return ErrUnsupportedPlatform
+	}
+
+	return sender.Send(ctx, message)
+}
+
+// retryingSender retries the transient errors of a sender, waiting twice as long before every attempt.
+type retryingSender struct {
+	sender   PushSender
+	attempts int
+	backoff  time.Duration
+}
+
+// NewRetryingSender creates a sender trying the transient errors of the sender up to attempts times.
+func NewRetryingSender(sender PushSender, attempts int, backoff time.Duration) PushSender {
+	return &retryingSender{
+		sender:   sender,
+		attempts: max(attempts, 1),
+		backoff:  backoff,
+	}
+}
+
+func (s *retryingSender) Send(ctx context.Context, message *PushMessage) error {
+	delay := s.backoff
+
+	for attempt := 1; ; attempt++ {
+		err := s.sender.Send(ctx, message)
+		if attempt >= s.attempts || ClassifyPush(err) != domain.PushOutcomeTransient {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+}
@@ -0,0 +1,59 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/sideshow/apns2"
+	"github.com/sideshow/apns2/token"
+)
+
+// apnsSender sends the push notifications with APNs, to the sandbox for the tokens of the development builds.
+type apnsSender struct {
+	production *apns2.Client
+	sandbox    *apns2.Client
+	topic      string
+}
+
+// NewAPNsSender creates the APNs sender of the Apple configuration, authenticated with the APNs key.
+func NewAPNsSender(appleConfig config.Apple) (PushSender, error) {
+	apnsKey, err := token.AuthKeyFromBytes([]byte(appleConfig.ApnsCertificate))
+	if err != nil {
+		return nil, err
+	}
+
+	authToken := &token.Token{
+		AuthKey: apnsKey,
+		KeyID:   appleConfig.ApnsCertificateKey,
+		TeamID:  appleConfig.TeamId,
+	}
+
+	return &apnsSender{
+		production: apns2.NewTokenClient(authToken).Production(),
+		sandbox:    apns2.NewTokenClient(authToken).Development(),
+		topic:      appleConfig.AppBundleId,
+	}, nil
+}
+
+func (s *apnsSender) Send(ctx context.Context, message *PushMessage) error {
+	client := s.production
+	if message.Environment == domain.PushEnvironmentSandbox {
+		client = s.sandbox
+	}
+
+	response, err := client.PushWithContext(ctx, &apns2.Notification{
+		DeviceToken: message.DeviceToken,
+		Topic:       s.topic,
+		Payload:     message.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !response.Sent() {
+		return &PushError{StatusCode: response.StatusCode, Reason: response.Reason}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package notification
+
+import (
+	"context"
+	"sync"
+)
+
+var _ PushSender = (*RecordingSender)(nil)
+
+// RecordingSender stands in for the push providers in tests and local use, it records the messages instead of
+// sending them. The messages to a device token of Errors are refused with its error.
+type RecordingSender struct {
+	mu       sync.Mutex
+	messages []PushMessage
+
+	Errors map[string]error
+}
+
+// NewRecordingSender creates a sender recording the messages.
+func NewRecordingSender() *RecordingSender {
+	return &RecordingSender{Errors: map[string]error{}}
+}
+
+func (s *RecordingSender) Send(ctx context.Context, message *PushMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err, ok := s.Errors[message.DeviceToken]; ok {
+		return err
+	}
+
+	s.messages = append(s.messages, *message)
+	return nil
+}
+
+// Messages returns the messages sent so far.
+func (s *RecordingSender) Messages() []PushMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]PushMessage{}, s.messages...)
+}
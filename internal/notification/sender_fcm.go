@@ -0,0 +1,153 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// FCMBaseURL is the base URL of the FCM HTTP v1 API.
+const FCMBaseURL = "https://fcm.googleapis.com"
+
+// fcmScope is the OAuth scope of the FCM HTTP v1 API.
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+/* Type of the error details holding the FCM error code, e.g. UNREGISTERED */
+const fcmErrorType = "type.googleapis.com/google.firebase.fcm.v1.FcmError"
+
+// fcmSender sends the push notifications with the FCM HTTP v1 API.
+type fcmSender struct {
+	baseURL   string
+	projectID string
+	tokens    oauth2.TokenSource
+	client    *http.Client
+}
+
+// NewFCMSender creates the FCM sender of the project of the service account.
+func NewFCMSender(ctx context.Context, fcmConfig config.Fcm) (PushSender, error) {
+	credentials, err := google.CredentialsFromJSON(ctx, []byte(fcmConfig.ServiceAccount), fcmScope)
+	if err != nil {
+		return nil, err
+	}
+
+	if credentials.ProjectID == "" {
+		return nil, errors.New("fcm service account has no project")
+	}
+
+	return NewFCMSenderFromTokenSource(FCMBaseURL, credentials.ProjectID, credentials.TokenSource), nil
+}
+
+// NewFCMSenderFromTokenSource creates the FCM sender of the project authenticated with the tokens of the source.
+func NewFCMSenderFromTokenSource(baseURL string, projectID string, tokens oauth2.TokenSource) PushSender {
+	return &fcmSender{
+		baseURL:   baseURL,
+		projectID: projectID,
+		tokens:    oauth2.ReuseTokenSource(nil, tokens),
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      fcmAndroid        `json:"android"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmAndroid struct {
+	Notification fcmAndroidNotification `json:"notification"`
+}
+
+type fcmAndroidNotification struct {
+	NotificationCount int `json:"notification_count,omitempty"`
+}
+
+type fcmErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Status  string `json:"status"`
+		Details []struct {
+			Type      string `json:"@type"`
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+func (s *fcmSender) Send(ctx context.Context, message *PushMessage) error {
+	payload := message.Payload
+
+	/* The data of the FCM messages only holds strings */
+	data := map[string]string{
+		"bookId": payload.Data.BookID.String(),
+		"pickId": payload.Data.PickID.String(),
+	}
+	if payload.Data.Kind != "" {
+		data["kind"] = payload.Data.Kind
+	}
+
+	body, err := json.Marshal(map[string]fcmMessage{
+		"message": {
+			Token:        message.DeviceToken,
+			Notification: fcmNotification{Title: payload.Aps.Alert.Title, Body: payload.Aps.Alert.Body},
+			Data:         data,
+			Android:      fcmAndroid{Notification: fcmAndroidNotification{NotificationCount: payload.Aps.Badge}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	token, err := s.tokens.Token()
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/v1/projects/%s/messages:send", s.baseURL, s.projectID)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	token.SetAuthHeader(request)
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	errorResponse := fcmErrorResponse{}
+	_ = json.NewDecoder(response.Body).Decode(&errorResponse)
+
+	/* The FCM error code is more precise than the status, UNREGISTERED is a dead token */
+	reason := errorResponse.Error.Status
+	for _, detail := range errorResponse.Error.Details {
+		if detail.Type == fcmErrorType && detail.ErrorCode != "" {
+			reason = detail.ErrorCode
+		}
+	}
+	if reason == "" {
+		reason = strconv.Itoa(response.StatusCode)
+	}
+
+	return &PushError{StatusCode: response.StatusCode, Reason: reason}
+}
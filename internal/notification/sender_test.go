@@ -0,0 +1,205 @@
+package notification_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/oauth2"
+
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/notification"
+)
+
+var _ = Describe("PushSender", func() {
+	payload := domain.PushNotificationPayload{
+		Aps: domain.PusNotificationAps{
+			Alert: domain.PushNotificationAlert{Title: "Daily Pick", Body: "A pick"},
+			Badge: 1,
+		},
+		Data: domain.PushNotificationPayloadData{BookID: uuid.MustParse("7f1c2b9e-6c1f-4c1e-9a57-2d3f0e4b8a10")},
+	}
+
+	Describe("platform routing", func() {
+		var (
+			ios     *notification.RecordingSender
+			android *notification.RecordingSender
+			sender  notification.PushSender
+		)
+
+		BeforeEach(func() {
+			ios = notification.NewRecordingSender()
+			android = notification.NewRecordingSender()
+			sender = notification.NewPlatformSender(map[string]notification.PushSender{
+				domain.PlatformIOS:     ios,
+				domain.PlatformAndroid: android,
+			})
+		})
+
+		It("should send with the sender of the platform", func() {
+			// Act
+			err := sender.Send(context.Background(), &notification.PushMessage{DeviceToken: "fcm-token", Platform: domain.PlatformAndroid, Payload: payload})
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(android.Messages()).To(HaveLen(1))
+			Expect(ios.Messages()).To(BeEmpty())
+		})
+
+		It("should send the messages without platform to iOS", func() {
+			// Act
+			err := sender.Send(context.Background(), &notification.PushMessage{DeviceToken: "apns-token", Payload: payload})
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ios.Messages()).To(HaveLen(1))
+		})
+
+		It("should fail for a platform without sender", func() {
+			// Arrange
+			sender = notification.NewPlatformSender(map[string]notification.PushSender{domain.PlatformIOS: ios})
+
+			// Act
+			err := sender.Send(context.Background(), &notification.PushMessage{DeviceToken: "fcm-token", Platform: domain.PlatformAndroid, Payload: payload})
+
+			// Assert
+			Expect(err).To(MatchError(notification.ErrUnsupportedPlatform))
+		})
+	})
+
+	Describe("FCM", func() {
+		var (
+			server   *httptest.Server
+			status   int
+			response string
+			request  map[string]map[string]any
+			path     string
+			auth     string
+		)
+
+		BeforeEach(func() {
+			status = http.StatusOK
+			response = `{"name":"projects/feynman/messages/1"}`
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				auth = r.Header.Get("Authorization")
+				_ = json.NewDecoder(r.Body).Decode(&request)
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(response))
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		send := func() error {
+			sender := notification.NewFCMSenderFromTokenSource(server.URL, "feynman", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "access"}))
+			return sender.Send(context.Background(), &notification.PushMessage{DeviceToken: "fcm-token", Platform: domain.PlatformAndroid, Payload: payload})
+		}
+
+		It("should send the message to the project", func() {
+			// Act
+			err := send()
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal("/v1/projects/feynman/messages:send"))
+			Expect(auth).To(Equal("Bearer access"))
+			Expect(request["message"]["token"]).To(Equal("fcm-token"))
+			Expect(request["message"]["notification"]).To(HaveKeyWithValue("body", "A pick"))
+			Expect(request["message"]["data"]).To(HaveKeyWithValue("bookId", "7f1c2b9e-6c1f-4c1e-9a57-2d3f0e4b8a10"))
+		})
+
+		It("should return the FCM error code of a refused message", func() {
+			// Arrange
+			status = http.StatusNotFound
+			response = `{"error":{"code":404,"status":"NOT_FOUND","details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`
+
+			// Act
+			err := send()
+
+			// Assert
+			pushErr := &notification.PushError{}
+			Expect(err).To(BeAssignableToTypeOf(pushErr))
+			Expect(err.(*notification.PushError).StatusCode).To(Equal(http.StatusNotFound))
+			Expect(err.(*notification.PushError).Reason).To(Equal("UNREGISTERED"))
+		})
+	})
+
+	Describe("delivery outcomes", func() {
+		DescribeTable("should classify the errors of the providers",
+			func(err error, outcome string) {
+				Expect(notification.ClassifyPush(err)).To(Equal(outcome))
+			},
+			Entry("delivered", nil, domain.PushOutcomeDelivered),
+			Entry("APNs unregistered", &notification.PushError{StatusCode: 410, Reason: "Unregistered"}, domain.PushOutcomeInvalidToken),
+			Entry("APNs bad device token", &notification.PushError{StatusCode: 400, Reason: "BadDeviceToken"}, domain.PushOutcomeInvalidToken),
+			Entry("FCM unregistered", &notification.PushError{StatusCode: 404, Reason: "UNREGISTERED"}, domain.PushOutcomeInvalidToken),
+			Entry("APNs too many requests", &notification.PushError{StatusCode: 429, Reason: "TooManyRequests"}, domain.PushOutcomeTransient),
+			Entry("FCM unavailable", &notification.PushError{StatusCode: 503, Reason: "UNAVAILABLE"}, domain.PushOutcomeTransient),
+			Entry("network error", errors.New("connection reset"), domain.PushOutcomeTransient),
+			Entry("APNs bad payload", &notification.PushError{StatusCode: 400, Reason: "PayloadEmpty"}, domain.PushOutcomeFailed),
+			Entry("unsupported platform", notification.ErrUnsupportedPlatform, domain.PushOutcomeFailed),
+		)
+
+		It("should retry the transient errors", func() {
+			// Arrange
+			fake := &flakySender{errs: []error{&notification.PushError{StatusCode: 503}, errors.New("timeout")}}
+			sender := notification.NewRetryingSender(fake, 3, time.Millisecond)
+
+			// Act
+			err := sender.Send(context.Background(), &notification.PushMessage{DeviceToken: "token", Payload: payload})
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fake.calls).To(Equal(3))
+		})
+
+		It("should stop after the attempts", func() {
+			// Arrange
+			fake := &flakySender{errs: []error{errors.New("timeout"), errors.New("timeout"), errors.New("timeout")}}
+			sender := notification.NewRetryingSender(fake, 2, time.Millisecond)
+
+			// Act
+			err := sender.Send(context.Background(), &notification.PushMessage{DeviceToken: "token", Payload: payload})
+
+			// Assert
+			Expect(err).To(HaveOccurred())
+			Expect(fake.calls).To(Equal(2))
+		})
+
+		It("should not retry an invalid device token", func() {
+			// Arrange
+			fake := &flakySender{errs: []error{&notification.PushError{StatusCode: 410, Reason: "Unregistered"}}}
+			sender := notification.NewRetryingSender(fake, 3, time.Millisecond)
+
+			// Act
+			err := sender.Send(context.Background(), &notification.PushMessage{DeviceToken: "token", Payload: payload})
+
+			// Assert
+			Expect(notification.ClassifyPush(err)).To(Equal(domain.PushOutcomeInvalidToken))
+			Expect(fake.calls).To(Equal(1))
+		})
+	})
+})
+
+/* Fails with the errors in order, then sends */
+type flakySender struct {
+	errs  []error
+	calls int
+}
+
+func (s *flakySender) Send(ctx context.Context, message *notification.PushMessage) error {
+	s.calls++
+	if s.calls <= len(s.errs) {
+		return s.errs[s.calls-1]
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+var _ Service = (*serviceImpl)(nil)
+
+// Service represents the push notifications service, the daily pick follows the schedule of the user settings.
+type Service interface {
+	// GetDueSessions Get the sessions to notify whose daily pick is scheduled within [from, from+window), scanning
+	// up to limit sessions after the cursor. It returns the cursor of the next page, 0 after the last one
+	GetDueSessions(from time.Time, window time.Duration, after uint, limit int) ([]domain.ShortSession, uint, error)
+	// ClaimDailyPicks Mark the daily picks of the local dates as sent to the sessions, it returns the sessions
+	// that were not already claimed
+	ClaimDailyPicks(sessions []domain.ShortSession) ([]domain.ShortSession, error)
+	// ReleaseDailyPicks Undo the claim of the daily picks when they could not be sent
+	ReleaseDailyPicks(sessions []domain.ShortSession) error
+	// SelectDailyPick Choose the daily pick of the session with the strategies of its notification mode, the same for the whole local date
+	SelectDailyPick(session *domain.ShortSession) (*domain.DailyPick, error)
+	// RecordDailyPick Add the daily pick sent to the user to the notification history
+	RecordDailyPick(userID uint, localDate string, pick *domain.DailyPick) error
+	// RecordPushDelivery Store the outcome of a push notification to the session, the invalid device tokens are
+	// cleared and the push notifications are disabled after too many failures in a row
+	RecordPushDelivery(sessionID uuid.UUID, outcome string, reason string) error
+}
+
+type serviceImpl struct {
+	db     *gorm.DB
+	config *config.Push
+}
+
+// NewService creates a new push notifications service.
+func NewService(db *gorm.DB, config *config.Push) Service {
+	return &serviceImpl{
+		db:     db,
+		config: config,
+	}
+}
+
+func (service *serviceImpl) GetDueSessions(from time.Time, window time.Duration, after uint, limit int) ([]domain.ShortSession, uint, error) {
+	sessions := []domain.ShortSession{}
+
+	/* Keyset pagination on the id, the schedule is resolved from the settings so the page is filtered after the query */
+	err := service.db.Model(&domain.Session{}).
+		Select("sessions.id, sessions.guid, sessions.user_id, sessions.device_token, sessions.platform, sessions.push_environment, users.settings").
+		Joins("JOIN users ON users.id = sessions.user_id").
+		Where("sessions.id > ?", after).
+		Where("sessions.expired_at = ? AND (device_token = '') IS NOT TRUE", "0001-01-01 00:00:00").
+		Where("sessions.push_disabled_at IS NULL").
+		Where("users.is_notification_enabled = ?", true).
+		Order("sessions.id").
+		Limit(limit).
+		Find(&sessions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	next := uint(0)
+	if len(sessions) == limit {
+		next = sessions[len(sessions)-1].ID
+	}
+
+	dueSessions := []domain.ShortSession{}
+
+	for _, session := range sessions {
+		/* Unreadable settings get the default schedule */
+		var settings *domain.UserSettings
+		if err := json.Unmarshal(session.Settings, &settings); err != nil {
+			settings = nil
+		}
+
+		localDate, isDue := NewSchedule(settings).DueIn(from, window)
+		if !isDue {
+			continue
+		}
+
+		session.LocalDate = localDate
+		dueSessions = append(dueSessions, session)
+	}
+
+	return dueSessions, next, nil
+}
+
+func (service *serviceImpl) ClaimDailyPicks(sessions []domain.ShortSession) ([]domain.ShortSession, error) {
+	claimed := []domain.ShortSession{}
+
+	for localDate, dateSessions := range byLocalDate(sessions) {
+		/* A single conditional update per local date, so a retried run cannot send the daily pick twice */
+		guids := []uuid.UUID{}
+		err := service.db.Raw(`
+			UPDATE sessions SET daily_pick_sent_on = ?
+			WHERE guid IN ? AND (daily_pick_sent_on IS NULL OR daily_pick_sent_on < ?)
+			RETURNING guid
+		`, localDate, guidsOf(dateSessions), localDate).Scan(&guids).Error
+		if err != nil {
+			return nil, err
+		}
+
+		isClaimed := map[uuid.UUID]bool{}
+		for _, guid := range guids {
+			isClaimed[guid] = true
+		}
+
+		for _, session := range dateSessions {
+			if isClaimed[session.Guid] {
+				claimed = append(claimed, session)
+			}
+		}
+	}
+
+	return claimed, nil
+}
+
+func (service *serviceImpl) ReleaseDailyPicks(sessions []domain.ShortSession) error {
+	for localDate, dateSessions := range byLocalDate(sessions) {
+		err := service.db.Model(&domain.Session{}).
+			Where("guid IN ? AND daily_pick_sent_on = ?", guidsOf(dateSessions), localDate).
+			Update("daily_pick_sent_on", nil).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (service *serviceImpl) SelectDailyPick(session *domain.ShortSession) (*domain.DailyPick, error) {
+	var settings domain.UserSettings
+	if err := json.Unmarshal(session.Settings, &settings); err != nil {
+		settings = domain.UserSettings{}
+	}
+
+	localDate, err := parseLocalDate(session.LocalDate)
+	if err != nil {
+		return nil, err
+	}
+
+	selection := &Selection{UserID: session.UserID, LocalDate: localDate}
+
+	for _, strategy := range StrategiesFor(settings.NotificationMode) {
+		picks := []domain.DailyPick{}
+
+		err := strategy.Apply(service.candidates(selection), selection).
+			Order(dailyOrder(selection)).
+			Limit(1).
+			Scan(&picks).Error
+		if err != nil {
+			return nil, err
+		}
+
+		if len(picks) > 0 {
+			picks[0].Strategy = strategy.Name()
+			return &picks[0], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (service *serviceImpl) RecordDailyPick(userID uint, localDate string, pick *domain.DailyPick) error {
+	date, err := parseLocalDate(localDate)
+	if err != nil {
+		return err
+	}
+
+	return service.db.Exec(`
+		INSERT INTO notification_history (user_id, pick_id, strategy, local_date) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, local_date, pick_id) DO NOTHING
+	`, userID, pick.PickID, pick.Strategy, date.Format(time.DateOnly)).Error
+}
+
+func (service *serviceImpl) RecordPushDelivery(sessionID uuid.UUID, outcome string, reason string) error {
+	now := time.Now()
+
+	data := map[string]interface{}{
+		"last_push_outcome": outcome,
+		"last_push_reason":  reason,
+		"last_push_at":      now,
+	}
+
+	switch outcome {
+	case domain.PushOutcomeDelivered:
+		data["push_failures"] = 0
+	case domain.PushOutcomeInvalidToken:
+		/* The session is not notified again until the app registers a new device token */
+		data["device_token"] = ""
+		data["push_failures"] = 0
+	default:
+		data["push_failures"] = gorm.Expr("push_failures + 1")
+		data["push_disabled_at"] = gorm.Expr(
+			"CASE WHEN push_failures + 1 >= ? THEN ?::timestamp ELSE push_disabled_at END", service.config.MaxFailures, now,
+		)
+	}
+
+	return service.db.Model(&domain.Session{}).Where("guid = ?", sessionID).Updates(data).Error
+}
+
+// candidates returns the picks of the user, except the ones surfaced within the cooldown before the local date.
+func (service *serviceImpl) candidates(selection *Selection) *gorm.DB {
+	localDate := selection.LocalDate.Format(time.DateOnly)
+	cooldownStart := selection.LocalDate.AddDate(0, 0, -service.config.PickCooldown).Format(time.DateOnly)
+
+	return service.db.Table("book_picks AS bp").
+		Select("bp.id AS pick_id, bp.guid AS pick_guid, b.guid AS book_id, b.title AS book_title, bp.content_text AS content").
+		Joins("JOIN books b ON b.id = bp.book_id").
+		Where("bp.user_id = ?", selection.UserID).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM notification_history nh WHERE nh.pick_id = bp.id AND nh.local_date >= ? AND nh.local_date < ?
+		)`, cooldownStart, localDate)
+}
+
+/* Break the ties of the strategies with a shuffle seeded by the user and the date, stable across the runs of the day */
+func dailyOrder(selection *Selection) string {
+	return fmt.Sprintf("md5(bp.id::text || '-%d-%s')", selection.UserID, selection.LocalDate.Format(time.DateOnly))
+}
+
+/* The messages of the former daily run have no local date, it is the UTC one */
+func parseLocalDate(localDate string) (time.Time, error) {
+	if localDate == "" {
+		return time.Now().UTC().Truncate(24 * time.Hour), nil
+	}
+
+	return time.Parse(time.DateOnly, localDate)
+}
+
+func byLocalDate(sessions []domain.ShortSession) map[string][]domain.ShortSession {
+	sessionsByDate := map[string][]domain.ShortSession{}
+	for _, session := range sessions {
+		sessionsByDate[session.LocalDate] = append(sessionsByDate[session.LocalDate], session)
+	}
+	return sessionsByDate
+}
+
+func guidsOf(sessions []domain.ShortSession) []uuid.UUID {
+	guids := make([]uuid.UUID, len(sessions))
+	for i, session := range sessions {
+		guids[i] = session.Guid
+	}
+	return guids
+}
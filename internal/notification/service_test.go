@@ -0,0 +1,96 @@
+package notification_test
+
+import (
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/postgres"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/notification"
+)
+
+var _ = Describe("Service", func() {
+	var (
+		service notification.Service
+		sqlMock sqlmock.Sqlmock
+	)
+
+	BeforeEach(func() {
+		db, sqlMockGen, _ := sqlmock.New()
+		sqlMock = sqlMockGen
+
+		conn := postgres.New(postgres.Config{
+			Conn: db,
+		})
+
+		database, _ := database.NewDB(conn)
+		service = notification.NewService(database, &config.Push{PickCooldown: 14})
+	})
+
+	Describe("GetDueSessions", func() {
+		columns := []string{"id", "guid", "user_id", "device_token", "platform", "push_environment", "settings"}
+		from := time.Date(2024, 3, 5, 13, 0, 0, 0, time.UTC)
+
+		It("should return the cursor of the next page of a full page", func() {
+			// Arrange
+			sqlMock.ExpectQuery(`sessions.id > \$1 .* ORDER BY sessions.id LIMIT \$4`).
+				WithArgs(10, "0001-01-01 00:00:00", true, 2).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(11, uuid.New(), 1, "token", "ios", "production", []byte(`{}`)).
+					AddRow(14, uuid.New(), 2, "token", "android", "production", []byte(`{"notificationTime":"09:00"}`)))
+
+			// Act
+			sessions, next, err := service.GetDueSessions(from, 15*time.Minute, 10, 2)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(next).To(Equal(uint(14)))
+			Expect(sessions).To(HaveLen(1))
+			Expect(sessions[0].ID).To(Equal(uint(11)))
+			Expect(sessions[0].LocalDate).To(Equal("2024-03-05"))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should end after a partial page", func() {
+			// Arrange
+			sqlMock.ExpectQuery(`sessions.id > \$1`).
+				WithArgs(14, "0001-01-01 00:00:00", true, 2).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(15, uuid.New(), 3, "token", "ios", "production", []byte(`{}`)))
+
+			// Act
+			sessions, next, err := service.GetDueSessions(from, 15*time.Minute, 14, 2)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(next).To(Equal(uint(0)))
+			Expect(sessions).To(HaveLen(1))
+		})
+	})
+
+	Describe("ClaimDailyPicks", func() {
+		It("should only return the sessions not already claimed", func() {
+			// Arrange
+			claimed := domain.ShortSession{Guid: uuid.New(), LocalDate: "2024-03-05"}
+			alreadySent := domain.ShortSession{Guid: uuid.New(), LocalDate: "2024-03-05"}
+
+			sqlMock.ExpectQuery(`UPDATE sessions SET daily_pick_sent_on = \$1\s+WHERE guid IN \(\$2,\$3\) .* RETURNING guid`).
+				WithArgs("2024-03-05", claimed.Guid, alreadySent.Guid, "2024-03-05").
+				WillReturnRows(sqlmock.NewRows([]string{"guid"}).AddRow(claimed.Guid))
+
+			// Act
+			sessions, err := service.ClaimDailyPicks([]domain.ShortSession{claimed, alreadySent})
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(sessions).To(Equal([]domain.ShortSession{claimed}))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,125 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Names of the selection strategies of the daily pick.
+const (
+	StrategyLeastRecentlySurfaced = "least-recently-surfaced"
+	StrategyOnThisDay             = "on-this-day"
+	StrategyDueReviews            = "due-reviews"
+	StrategyRecentlyEdited        = "recently-edited"
+	StrategyTopicRotation         = "topic-rotation"
+)
+
+// recentlyEditedBooks is the number of last edited books of the recently-edited strategy.
+const recentlyEditedBooks = 3
+
+// Selection represents the choice of the daily pick of a user for a local date.
+type Selection struct {
+	UserID    uint
+	LocalDate time.Time
+}
+
+// Strategy represents a way of choosing the daily pick. It narrows and orders the candidate picks of the
+// user, the query selects from book_picks AS bp joined with books AS b. It has to depend only on the
+// selection and on the stored data, so the choice is the same for every run of the day.
+type Strategy interface {
+	Name() string
+	Apply(query *gorm.DB, selection *Selection) *gorm.DB
+}
+
+// Strategies holds the available strategies by name.
+var Strategies = map[string]Strategy{
+	StrategyLeastRecentlySurfaced: leastRecentlySurfaced{},
+	StrategyOnThisDay:             onThisDay{},
+	StrategyDueReviews:            dueReviews{},
+	StrategyRecentlyEdited:        recentlyEdited{},
+	StrategyTopicRotation:         topicRotation{},
+}
+
+// StrategiesFor returns the strategies of the notification mode of the settings, tried in order until one
+// finds a pick. They all end with the least recently surfaced pick.
+func StrategiesFor(notificationMode string) []Strategy {
+	names := []string{StrategyOnThisDay, StrategyTopicRotation, StrategyLeastRecentlySurfaced}
+
+	switch notificationMode {
+	case "last-edit":
+		names = []string{StrategyRecentlyEdited, StrategyLeastRecentlySurfaced}
+	case "reviews":
+		names = []string{StrategyDueReviews, StrategyOnThisDay, StrategyLeastRecentlySurfaced}
+	}
+
+	strategies := make([]Strategy, len(names))
+	for i, name := range names {
+		strategies[i] = Strategies[name]
+	}
+
+	return strategies
+}
+
+/* The picks never surfaced first, then the ones surfaced the longest ago */
+type leastRecentlySurfaced struct{}
+
+func (leastRecentlySurfaced) Name() string { return StrategyLeastRecentlySurfaced }
+
+func (leastRecentlySurfaced) Apply(query *gorm.DB, selection *Selection) *gorm.DB {
+	/* Order does not take arguments, the date is formatted from a time.Time */
+	return query.Order(fmt.Sprintf(
+		"(SELECT MAX(nh.local_date) FROM notification_history nh WHERE nh.pick_id = bp.id AND nh.local_date < '%s') ASC NULLS FIRST",
+		selection.LocalDate.Format(time.DateOnly),
+	))
+}
+
+/* The picks created on the same day a year ago */
+type onThisDay struct{}
+
+func (onThisDay) Name() string { return StrategyOnThisDay }
+
+func (onThisDay) Apply(query *gorm.DB, selection *Selection) *gorm.DB {
+	return query.Where("bp.created_at::date = ?", selection.LocalDate.AddDate(-1, 0, 0).Format(time.DateOnly))
+}
+
+/* The picks whose spaced repetition review is due, the most overdue first */
+type dueReviews struct{}
+
+func (dueReviews) Name() string { return StrategyDueReviews }
+
+func (dueReviews) Apply(query *gorm.DB, selection *Selection) *gorm.DB {
+	return query.
+		Joins("JOIN pick_review_states rs ON rs.pick_id = bp.id").
+		Where("rs.due_at < ?", selection.LocalDate.AddDate(0, 0, 1)).
+		Order("rs.due_at ASC")
+}
+
+/* The picks of the last edited books */
+type recentlyEdited struct{}
+
+func (recentlyEdited) Name() string { return StrategyRecentlyEdited }
+
+func (recentlyEdited) Apply(query *gorm.DB, selection *Selection) *gorm.DB {
+	return query.Where(
+		"b.id IN (SELECT id FROM books WHERE user_id = ? ORDER BY updated_at DESC, id DESC LIMIT ?)",
+		selection.UserID, recentlyEditedBooks,
+	)
+}
+
+/* The picks of the books of a topic, a different topic of the user every day */
+type topicRotation struct{}
+
+func (topicRotation) Name() string { return StrategyTopicRotation }
+
+func (topicRotation) Apply(query *gorm.DB, selection *Selection) *gorm.DB {
+	day := selection.LocalDate.Unix() / int64(24*time.Hour/time.Second)
+
+	return query.Where(`b.id IN (
+		SELECT bt.book_id FROM book_topics bt WHERE bt.topic_id = (
+			SELECT t.id FROM topics t WHERE t.user_id = ? ORDER BY t.id
+			OFFSET (? % GREATEST((SELECT COUNT(*) FROM topics WHERE user_id = ?), 1)) LIMIT 1
+		)
+	)`, selection.UserID, day, selection.UserID)
+}
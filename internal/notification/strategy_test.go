@@ -0,0 +1,90 @@
+package notification_test
+
+import (
+	"encoding/json"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/postgres"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/notification"
+)
+
+var _ = Describe("Strategies", func() {
+	Describe("StrategiesFor", func() {
+		names := func(strategies []notification.Strategy) []string {
+			result := []string{}
+			for _, strategy := range strategies {
+				result = append(result, strategy.Name())
+			}
+			return result
+		}
+
+		It("should start from the recently edited books in last-edit mode", func() {
+			Expect(names(notification.StrategiesFor("last-edit"))).To(Equal([]string{
+				notification.StrategyRecentlyEdited,
+				notification.StrategyLeastRecentlySurfaced,
+			}))
+		})
+
+		It("should start from the due reviews in reviews mode", func() {
+			Expect(names(notification.StrategiesFor("reviews"))[0]).To(Equal(notification.StrategyDueReviews))
+		})
+
+		It("should always end with the least recently surfaced pick", func() {
+			for _, mode := range []string{"all", "last-edit", "reviews", ""} {
+				strategies := names(notification.StrategiesFor(mode))
+				Expect(strategies[len(strategies)-1]).To(Equal(notification.StrategyLeastRecentlySurfaced))
+			}
+		})
+	})
+
+	Describe("SelectDailyPick", func() {
+		var (
+			service notification.Service
+			sqlMock sqlmock.Sqlmock
+		)
+
+		BeforeEach(func() {
+			db, sqlMockGen, _ := sqlmock.New()
+			sqlMock = sqlMockGen
+
+			conn := postgres.New(postgres.Config{
+				Conn: db,
+			})
+
+			database, _ := database.NewDB(conn)
+			service = notification.NewService(database, &config.Push{PickCooldown: 14})
+		})
+
+		It("should fall back to the next strategy, skipping the picks of the cooldown", func() {
+			// Arrange
+			settings, _ := json.Marshal(domain.UserSettings{NotificationMode: "last-edit"})
+			session := &domain.ShortSession{UserID: 7, Settings: settings, LocalDate: "2024-03-05"}
+			bookID := uuid.Must(uuid.NewRandom())
+
+			sqlMock.ExpectQuery(`nh.local_date >= \$2 AND nh.local_date < \$3.*ORDER BY updated_at DESC.*md5\(bp.id::text \|\| '-7-2024-03-05'\)`).
+				WithArgs(7, "2024-02-20", "2024-03-05", 7, 3, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"pick_id", "book_id", "content"}))
+
+			sqlMock.ExpectQuery(`ORDER BY \(SELECT MAX\(nh.local_date\) .* nh.local_date < '2024-03-05'\) ASC NULLS FIRST,md5\(bp.id::text \|\| '-7-2024-03-05'\) LIMIT \$4`).
+				WithArgs(7, "2024-02-20", "2024-03-05", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"pick_id", "book_id", "content"}).AddRow(42, bookID, "content"))
+
+			// Act
+			pick, err := service.SelectDailyPick(session)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(pick.PickID).To(Equal(uint(42)))
+			Expect(pick.BookID).To(Equal(bookID))
+			Expect(pick.Strategy).To(Equal(notification.StrategyLeastRecentlySurfaced))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,180 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+// Types of the push notifications, each one has its templates.
+const (
+	NotificationTypeDailyPick = "daily_pick"
+	NotificationTypeReview    = "review"
+)
+
+// DefaultLanguage is the language of the templates when the language of the user has none.
+const DefaultLanguage = "en"
+
+// MaxBodyLength is the number of characters of the body shown by the lock screens, the longer picks are truncated.
+const MaxBodyLength = 150
+
+const ellipsis = "…"
+
+// Template represents the content of a push notification, the fields are text/template executed with TemplateData.
+type Template struct {
+	Title    string
+	Subtitle string
+	Body     string
+}
+
+// TemplateData represents the values available to the templates.
+type TemplateData struct {
+	BookTitle string
+	Content   string
+}
+
+/* The templates by notification type and language, every type has the default language */
+var templates = map[string]map[string]Template{
+	NotificationTypeDailyPick: {
+		"en": {Title: "📚 Daily Pick Reminder", Subtitle: "{{.BookTitle}}", Body: "{{.Content}}"},
+		"it": {Title: "📚 Il tuo pick del giorno", Subtitle: "{{.BookTitle}}", Body: "{{.Content}}"},
+		"es": {Title: "📚 Tu pick del día", Subtitle: "{{.BookTitle}}", Body: "{{.Content}}"},
+		"fr": {Title: "📚 Votre pick du jour", Subtitle: "{{.BookTitle}}", Body: "{{.Content}}"},
+		"de": {Title: "📚 Dein Pick des Tages", Subtitle: "{{.BookTitle}}", Body: "{{.Content}}"},
+		"pt": {Title: "📚 O seu pick do dia", Subtitle: "{{.BookTitle}}", Body: "{{.Content}}"},
+	},
+	NotificationTypeReview: {
+		"en": {Title: "🧠 Time to review", Subtitle: "{{.BookTitle}}", Body: "{{.Content}}"},
+		"it": {Title: "🧠 È ora di ripassare", Subtitle: "{{.BookTitle}}", Body: "{{.Content}}"},
+		"es": {Title: "🧠 Hora de repasar", Subtitle: "{{.BookTitle}}", Body: "{{.Content}}"},
+		"fr": {Title: "🧠 C'est l'heure de réviser", Subtitle: "{{.BookTitle}}", Body: "{{.Content}}"},
+		"de": {Title: "🧠 Zeit zum Wiederholen", Subtitle: "{{.BookTitle}}", Body: "{{.Content}}"},
+		"pt": {Title: "🧠 Hora de rever", Subtitle: "{{.BookTitle}}", Body: "{{.Content}}"},
+	},
+}
+
+// TemplateFor returns the template of the notification type in the language. It falls back to the base
+// language of a regional one (pt-BR to pt), then to the default language, then to the daily pick.
+func TemplateFor(notificationType string, language string) Template {
+	languages, ok := templates[notificationType]
+	if !ok {
+		languages = templates[NotificationTypeDailyPick]
+	}
+
+	language = strings.ToLower(strings.TrimSpace(language))
+	base, _, _ := strings.Cut(strings.ReplaceAll(language, "_", "-"), "-")
+
+	for _, candidate := range []string{language, base} {
+		if t, ok := languages[candidate]; ok {
+			return t
+		}
+	}
+
+	return languages[DefaultLanguage]
+}
+
+// Render executes the template with the data, the body is truncated to MaxBodyLength.
+func (t Template) Render(data TemplateData) (domain.PushNotificationAlert, error) {
+	fields := []string{t.Title, t.Subtitle, t.Body}
+	rendered := make([]string, len(fields))
+
+	for i, field := range fields {
+		parsed, err := template.New("notification").Parse(field)
+		if err != nil {
+			return domain.PushNotificationAlert{}, err
+		}
+
+		var buffer bytes.Buffer
+		if err := parsed.Execute(&buffer, data); err != nil {
+			return domain.PushNotificationAlert{}, err
+		}
+
+		rendered[i] = strings.TrimSpace(buffer.String())
+	}
+
+	return domain.PushNotificationAlert{
+		Title:    rendered[0],
+		Subtitle: rendered[1],
+		Body:     Truncate(rendered[2], MaxBodyLength),
+	}, nil
+}
+
+// DailyPickPayload returns the push notification of the daily pick in the language, the badge is left to the sender.
+func DailyPickPayload(pick *domain.DailyPick, language string) (domain.PushNotificationPayload, error) {
+	notificationType := NotificationTypeDailyPick
+	kind := ""
+	if pick.Strategy == StrategyDueReviews {
+		notificationType = NotificationTypeReview
+		kind = domain.PushNotificationKindReview
+	}
+
+	alert, err := TemplateFor(notificationType, language).Render(TemplateData{
+		BookTitle: pick.BookTitle,
+		Content:   pick.Content,
+	})
+	if err != nil {
+		return domain.PushNotificationPayload{}, err
+	}
+
+	return domain.PushNotificationPayload{
+		Aps: domain.PusNotificationAps{
+			Alert: alert,
+		},
+		Data: domain.PushNotificationPayloadData{
+			BookID: pick.BookID,
+			PickID: pick.PickGuid,
+			Kind:   kind,
+		},
+	}, nil
+}
+
+// SessionLanguage returns the app language of the settings of the session, empty when they cannot be read.
+func SessionLanguage(session *domain.ShortSession) string {
+	var settings domain.UserSettings
+	if err := json.Unmarshal(session.Settings, &settings); err != nil {
+		return ""
+	}
+
+	return settings.AppLanguage
+}
+
+// Truncate shortens the text to at most limit characters, ellipsis included. It cuts at the last space when
+// there is one in the second half, and never splits a character from its combining marks or joined emojis.
+func Truncate(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	runes := []rune(text)
+	cut := max(limit-utf8.RuneCountInString(ellipsis), 0)
+
+	/* Step back over the runes attached to the previous one */
+	for cut > 0 && isAttached(runes, cut) {
+		cut--
+	}
+
+	if space := strings.LastIndexFunc(string(runes[:cut]), unicode.IsSpace); space >= 0 {
+		if spaceRunes := utf8.RuneCountInString(string(runes[:cut])[:space]); spaceRunes > cut/2 {
+			cut = spaceRunes
+		}
+	}
+
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace) + ellipsis
+}
+
+/* Whether the rune at i belongs with the one before it: combining marks, variation selectors, joiners, skin tones */
+func isAttached(runes []rune, i int) bool {
+	r := runes[i]
+	if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.Is(unicode.Variation_Selector, r) {
+		return true
+	}
+	if r == '\u200d' || (r >= 0x1F3FB && r <= 0x1F3FF) {
+		return true
+	}
+	return runes[i-1] == '\u200d'
+}
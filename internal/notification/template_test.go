@@ -0,0 +1,74 @@
+package notification_test
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/notification"
+)
+
+var _ = Describe("Templates", func() {
+	Describe("TemplateFor", func() {
+		It("should return the template of the language", func() {
+			Expect(notification.TemplateFor(notification.NotificationTypeDailyPick, "it").Title).To(Equal("📚 Il tuo pick del giorno"))
+		})
+
+		It("should fall back to the base language of a regional one", func() {
+			Expect(notification.TemplateFor(notification.NotificationTypeReview, "pt_BR").Title).To(Equal("🧠 Hora de rever"))
+		})
+
+		It("should fall back to English", func() {
+			Expect(notification.TemplateFor(notification.NotificationTypeDailyPick, "ja").Title).To(Equal("📚 Daily Pick Reminder"))
+			Expect(notification.TemplateFor(notification.NotificationTypeDailyPick, "").Title).To(Equal("📚 Daily Pick Reminder"))
+		})
+	})
+
+	Describe("DailyPickPayload", func() {
+		It("should render the pick with the book title as subtitle", func() {
+			// Arrange
+			pick := &domain.DailyPick{
+				PickGuid:  uuid.New(),
+				BookID:    uuid.New(),
+				BookTitle: "Surely You're Joking",
+				Content:   "What I cannot create, I do not understand.",
+				Strategy:  notification.StrategyDueReviews,
+			}
+
+			// Act
+			payload, err := notification.DailyPickPayload(pick, "en")
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(payload.Aps.Alert.Title).To(Equal("🧠 Time to review"))
+			Expect(payload.Aps.Alert.Subtitle).To(Equal("Surely You're Joking"))
+			Expect(payload.Aps.Alert.Body).To(Equal(pick.Content))
+			Expect(payload.Data.PickID).To(Equal(pick.PickGuid))
+			Expect(payload.Data.Kind).To(Equal(domain.PushNotificationKindReview))
+		})
+	})
+
+	Describe("Truncate", func() {
+		It("should keep a short text", func() {
+			Expect(notification.Truncate("short text", 20)).To(Equal("short text"))
+		})
+
+		It("should cut at a word boundary", func() {
+			Expect(notification.Truncate("the pleasure of finding things out", 20)).To(Equal("the pleasure of…"))
+		})
+
+		It("should not split the combining marks and the joined emojis", func() {
+			accented := strings.Repeat("e\u0301", 10)
+			truncated := notification.Truncate(accented, 8)
+			Expect(utf8.ValidString(truncated)).To(BeTrue())
+			Expect(truncated).To(Equal(strings.Repeat("e\u0301", 3) + "…"))
+
+			family := "ab\U0001F468\u200d\U0001F469\u200d\U0001F467cd"
+			Expect(notification.Truncate(family, 6)).To(Equal("ab…"))
+		})
+	})
+})
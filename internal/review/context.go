@@ -0,0 +1,38 @@
+package review
+
+import (
+	"errors"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+	"gorm.io/gorm"
+)
+
+type Context struct {
+	Service  Service
+	Database *gorm.DB
+}
+
+func NewContext() (*Context, error) {
+	// load configuration
+	config, err := config.NewConfig()
+	if err != nil {
+		return nil, errors.New("failed load review context config: " + err.Error())
+	}
+
+	// load database
+	database, err := database.NewDB(database.NewConn(&config.Database))
+	if err != nil {
+		return nil, errors.New("failed load review context database: " + err.Error())
+	}
+
+	userService := user.NewService(database)
+
+	service := NewService(database, &config.Review, userService)
+
+	return &Context{
+		Service:  service,
+		Database: database,
+	}, nil
+}
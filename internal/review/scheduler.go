@@ -0,0 +1,67 @@
+package review
+
+import (
+	"math"
+	"time"
+
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+const (
+	DefaultEase = 2.5
+	MinimumEase = 1.3
+
+	/* Grades below this value are considered a lapse */
+	PassingGrade = 3
+)
+
+// NewState returns the scheduling state of a pick never reviewed before.
+func NewState(pickID, userID uint, now time.Time) domain.PickReviewState {
+	return domain.PickReviewState{
+		PickID: pickID,
+		UserID: userID,
+		Ease:   DefaultEase,
+		DueAt:  now,
+	}
+}
+
+// ReviewDay returns the bounds of the day of now in the timezone of the user, in UTC like the stored times.
+func ReviewDay(now time.Time, location *time.Location) (time.Time, time.Time) {
+	local := now.In(location)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+
+	return start.UTC(), start.AddDate(0, 0, 1).UTC()
+}
+
+// Schedule applies the SM-2 algorithm to the state for the given grade (0-5) and returns the next state.
+func Schedule(state domain.PickReviewState, grade int, now time.Time) domain.PickReviewState {
+	next := state
+
+	if grade < PassingGrade {
+		next.Repetitions = 0
+		next.IntervalDays = 1
+		next.Lapses++
+	} else {
+		next.Repetitions++
+		switch next.Repetitions {
+		case 1:
+			next.IntervalDays = 1
+		case 2:
+			next.IntervalDays = 6
+		default:
+			next.IntervalDays = int(math.Round(float64(state.IntervalDays) * state.Ease))
+		}
+	}
+
+	quality := float64(5 - grade)
+	next.Ease = state.Ease + (0.1 - quality*(0.08+quality*0.02))
+	if next.Ease < MinimumEase {
+		next.Ease = MinimumEase
+	}
+
+	reviewedAt := now
+	next.LastReviewedAt = &reviewedAt
+	next.DueAt = now.AddDate(0, 0, next.IntervalDays)
+
+	return next
+}
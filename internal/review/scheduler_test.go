@@ -0,0 +1,99 @@
+package review_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/review"
+)
+
+var _ = Describe("Scheduler", func() {
+	now := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+
+	Describe("Schedule", func() {
+		It("should follow the SM-2 intervals for correct answers", func() {
+			// Arrange
+			state := review.NewState(1, 1, now)
+
+			// Act
+			first := review.Schedule(state, 4, now)
+			second := review.Schedule(first, 4, now)
+			third := review.Schedule(second, 4, now)
+
+			// Assert
+			Expect(first.IntervalDays).To(Equal(1))
+			Expect(second.IntervalDays).To(Equal(6))
+			Expect(third.IntervalDays).To(Equal(15))
+			Expect(third.Repetitions).To(Equal(3))
+			Expect(third.DueAt).To(Equal(now.AddDate(0, 0, 15)))
+			Expect(*third.LastReviewedAt).To(Equal(now))
+		})
+
+		It("should reset the repetitions and count a lapse on a wrong answer", func() {
+			// Arrange
+			state := review.NewState(1, 1, now)
+			state.Repetitions = 3
+			state.IntervalDays = 15
+
+			// Act
+			result := review.Schedule(state, 1, now)
+
+			// Assert
+			Expect(result.Repetitions).To(Equal(0))
+			Expect(result.IntervalDays).To(Equal(1))
+			Expect(result.Lapses).To(Equal(1))
+			Expect(result.Ease).To(BeNumerically("<", review.DefaultEase))
+		})
+
+		It("should never go below the minimum ease", func() {
+			// Arrange
+			state := review.NewState(1, 1, now)
+
+			// Act
+			for i := 0; i < 10; i++ {
+				state = review.Schedule(state, 0, now)
+			}
+
+			// Assert
+			Expect(state.Ease).To(Equal(review.MinimumEase))
+		})
+
+		It("should increase the ease on a perfect answer", func() {
+			// Arrange
+			state := review.NewState(1, 1, now)
+
+			// Act
+			result := review.Schedule(state, 5, now)
+
+			// Assert
+			Expect(result.Ease).To(BeNumerically("~", 2.6, 0.0001))
+		})
+	})
+
+	Describe("ReviewDay", func() {
+		It("should return the local day of the user", func() {
+			// Arrange
+			location, _ := time.LoadLocation("America/New_York")
+			lateEvening := time.Date(2024, 5, 11, 2, 0, 0, 0, time.UTC)
+
+			// Act
+			start, end := review.ReviewDay(lateEvening, location)
+
+			// Assert
+			Expect(start).To(Equal(time.Date(2024, 5, 10, 4, 0, 0, 0, time.UTC)))
+			Expect(end).To(Equal(time.Date(2024, 5, 11, 4, 0, 0, 0, time.UTC)))
+		})
+
+		It("should return the UTC day without a timezone", func() {
+			// Act
+			start, end := review.ReviewDay(now, (*domain.UserSettings)(nil).Location())
+
+			// Assert
+			Expect(start).To(Equal(time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)))
+			Expect(end).To(Equal(time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC)))
+		})
+	})
+})
@@ -0,0 +1,306 @@
+package review
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var _ Service = (*serviceImpl)(nil)
+
+type Service interface {
+	// GetDueReviews Get the picks to review today, within the daily limits
+	GetDueReviews(userID uuid.UUID, params *domain.ReviewDueParams) (*domain.ReviewDueResponse, error)
+
+	// GradeReview Grade the review of a pick and schedule the next one
+	GradeReview(userID uuid.UUID, body *domain.GradeReviewBody) (*domain.GradeReviewResponse, error)
+
+	// GetReviewLimits Get the daily limits stored by the user, for all the picks and for books and topics
+	GetReviewLimits(userID uuid.UUID) ([]domain.ReviewLimitResponse, error)
+
+	// SetReviewLimit Set the daily limits of a book, a topic or all the picks
+	SetReviewLimit(userID uuid.UUID, body *domain.ReviewLimitBody) (*domain.ReviewLimitResponse, error)
+}
+
+type serviceImpl struct {
+	db          *gorm.DB
+	config      *config.Review
+	userService user.Service
+}
+
+// NewService creates a new review service
+func NewService(db *gorm.DB, config *config.Review, userService user.Service) Service {
+	return &serviceImpl{
+		db:          db,
+		config:      config,
+		userService: userService,
+	}
+}
+
+//---------------------------------------------------------------------
+// Service Implementation
+//---------------------------------------------------------------------
+
+func (service *serviceImpl) GetDueReviews(userID uuid.UUID, params *domain.ReviewDueParams) (*domain.ReviewDueResponse, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	/* Today and its limits follow the timezone of the user */
+	startOfDay, endOfDay := ReviewDay(time.Now(), user.Settings.Location())
+
+	response := &domain.ReviewDueResponse{Picks: []domain.ReviewPickResponse{}}
+
+	err = service.db.Transaction(func(tx *gorm.DB) error {
+		limits, err := service.reviewLimits(tx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		limit, newLimit := service.dailyLimits(limits, params)
+
+		/* 1. Subtract the reviews already done today in the same book or topic from the daily limits */
+		var today struct {
+			Reviewed int64
+			NewPicks int64
+		}
+
+		err = scope(tx.Table("pick_review_logs AS rl").
+			Select("COUNT(*) FILTER (WHERE NOT rl.was_new) AS reviewed, COUNT(*) FILTER (WHERE rl.was_new) AS new_picks").
+			Joins("JOIN book_picks bp ON bp.id = rl.pick_id").
+			Joins("JOIN books b ON b.id = bp.book_id").
+			Where("rl.user_id = ? AND rl.reviewed_at >= ?", user.ID, startOfDay), user.ID, params).
+			Scan(&today).Error
+		if err != nil {
+			return err
+		}
+
+		response.RemainingReviews = max(limit-int(today.Reviewed), 0)
+		response.RemainingNew = max(newLimit-int(today.NewPicks), 0)
+
+		/* 2. Picks already scheduled and due by the end of today, the most overdue first */
+		if response.RemainingReviews > 0 {
+			due := []domain.ReviewPickResponse{}
+			err := service.reviewQuery(tx, user.ID, params).
+				Where("rs.id IS NOT NULL AND rs.due_at < ?", endOfDay).
+				Order("rs.due_at ASC").
+				Limit(response.RemainingReviews).
+				Scan(&due).Error
+			if err != nil {
+				return err
+			}
+			response.Picks = append(response.Picks, due...)
+		}
+
+		/* 3. Picks never reviewed, the oldest first */
+		if response.RemainingNew > 0 {
+			newPicks := []domain.ReviewPickResponse{}
+			err := service.reviewQuery(tx, user.ID, params).
+				Where("rs.id IS NULL").
+				Order("bp.created_at ASC").
+				Limit(response.RemainingNew).
+				Scan(&newPicks).Error
+			if err != nil {
+				return err
+			}
+			response.Picks = append(response.Picks, newPicks...)
+		}
+
+		return nil
+	})
+
+	return response, err
+}
+
+/* Base query of the reviewable picks, optionally filtered by book or topic */
+func (service *serviceImpl) reviewQuery(tx *gorm.DB, userID uint, params *domain.ReviewDueParams) *gorm.DB {
+	query := tx.Table("book_picks AS bp").
+		Select(`bp.guid AS pick_id, b.guid AS book_id, b.title AS book_title, bp.title AS pick_title, bp.content AS pick_content,
+			bp.content_text AS pick_content_text, rs.id IS NULL AS is_new, rs.due_at, COALESCE(rs.interval_days, 0) AS interval_days`).
+		Joins("JOIN books b ON b.id = bp.book_id").
+		Joins("LEFT JOIN pick_review_states rs ON rs.pick_id = bp.id").
+		Where("bp.user_id = ?", userID)
+
+	return scope(query, userID, params)
+}
+
+/* Narrow a query joined with the books as b to the book or the topic of the params */
+func scope(query *gorm.DB, userID uint, params *domain.ReviewDueParams) *gorm.DB {
+	if params.BookID != "" {
+		query = query.Where("b.guid = ?", params.BookID)
+	}
+
+	if params.Topic != "" {
+		query = query.Where("b.id IN (SELECT bt.book_id FROM book_topics bt JOIN topics t ON t.id = bt.topic_id WHERE t.user_id = ? AND t.topic = ?)", userID, params.Topic)
+	}
+
+	return query
+}
+
+/* The limits stored by the user, the ones of all the picks have neither a book nor a topic */
+func (service *serviceImpl) reviewLimits(tx *gorm.DB, userID uint) ([]domain.ReviewLimitResponse, error) {
+	limits := []domain.ReviewLimitResponse{}
+
+	err := tx.Table("review_limits AS rl").
+		Select("b.guid AS book_id, COALESCE(t.topic, '') AS topic, rl.daily_limit, rl.new_daily_limit").
+		Joins("LEFT JOIN books b ON b.id = rl.book_id").
+		Joins("LEFT JOIN topics t ON t.id = rl.topic_id").
+		Where("rl.user_id = ?", userID).
+		Order("rl.id").
+		Scan(&limits).Error
+
+	return limits, err
+}
+
+/* The limits of the book, then of the topic, then of all the picks, then the configured ones */
+func (service *serviceImpl) dailyLimits(limits []domain.ReviewLimitResponse, params *domain.ReviewDueParams) (int, int) {
+	limit, newLimit := service.config.DailyLimit, service.config.NewDailyLimit
+	precedence := 0
+
+	for _, stored := range limits {
+		current := 0
+		switch {
+		case stored.BookID != nil && strings.EqualFold(stored.BookID.String(), params.BookID):
+			current = 3
+		case stored.Topic != "" && stored.Topic == params.Topic:
+			current = 2
+		case stored.BookID == nil && stored.Topic == "":
+			current = 1
+		}
+
+		if current > precedence {
+			precedence = current
+			limit, newLimit = stored.DailyLimit, stored.NewDailyLimit
+		}
+	}
+
+	return limit, newLimit
+}
+
+func (service *serviceImpl) GetReviewLimits(userID uuid.UUID) ([]domain.ReviewLimitResponse, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.reviewLimits(service.db, user.ID)
+}
+
+func (service *serviceImpl) SetReviewLimit(userID uuid.UUID, body *domain.ReviewLimitBody) (*domain.ReviewLimitResponse, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domain.ReviewLimitResponse{
+		Topic:         body.Topic,
+		DailyLimit:    *body.DailyLimit,
+		NewDailyLimit: *body.NewDailyLimit,
+	}
+
+	err = service.db.Transaction(func(tx *gorm.DB) error {
+		limit := domain.ReviewLimit{}
+		/* Unset scopes are NULL, a limit is found by its book and topic or created with them */
+		conditions := map[string]interface{}{"user_id": user.ID, "book_id": nil, "topic_id": nil}
+
+		if body.BookID != "" {
+			book := domain.Book{}
+			if err := tx.Where("guid = ? AND user_id = ?", body.BookID, user.ID).First(&book).Error; err != nil {
+				return err
+			}
+			conditions["book_id"] = &book.ID
+			response.BookID = &book.Guid
+		}
+
+		if body.Topic != "" {
+			topicIDs := []uint{}
+			if err := tx.Table("topics").Where("user_id = ? AND topic = ?", user.ID, body.Topic).Pluck("id", &topicIDs).Error; err != nil {
+				return err
+			}
+			if len(topicIDs) == 0 {
+				return gorm.ErrRecordNotFound
+			}
+			conditions["topic_id"] = &topicIDs[0]
+		}
+
+		return tx.Where(conditions).
+			Assign(map[string]interface{}{"daily_limit": response.DailyLimit, "new_daily_limit": response.NewDailyLimit}).
+			FirstOrCreate(&limit).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (service *serviceImpl) GradeReview(userID uuid.UUID, body *domain.GradeReviewBody) (*domain.GradeReviewResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	grade := *body.Grade
+	now := time.Now().UTC()
+
+	pick := domain.BookPick{}
+	next := domain.PickReviewState{}
+
+	err = service.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.BookPick{}).Where("guid = ? AND user_id = ?", body.PickID, user.ID).First(&pick).Error; err != nil {
+			return err
+		}
+
+		state := domain.PickReviewState{}
+		wasNew := false
+
+		err := tx.Where("pick_id = ?", pick.ID).First(&state).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			state = NewState(pick.ID, user.ID, now)
+			wasNew = true
+		} else if err != nil {
+			return err
+		}
+
+		next = Schedule(state, grade, now)
+
+		if err := tx.Save(&next).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&domain.PickReviewLog{
+			PickID:               pick.ID,
+			UserID:               user.ID,
+			Grade:                grade,
+			WasNew:               wasNew,
+			Ease:                 next.Ease,
+			IntervalDays:         next.IntervalDays,
+			PreviousIntervalDays: state.IntervalDays,
+			ReviewedAt:           now,
+		}).Error
+	})
+	if err != nil {
+		logger.Error("Failed to grade review", zap.Error(err))
+		return nil, err
+	}
+
+	return &domain.GradeReviewResponse{
+		PickID:       pick.Guid,
+		Ease:         next.Ease,
+		IntervalDays: next.IntervalDays,
+		Repetitions:  next.Repetitions,
+		Lapses:       next.Lapses,
+		DueAt:        next.DueAt,
+	}, nil
+}
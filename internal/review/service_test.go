@@ -0,0 +1,142 @@
+package review_test
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"gorm.io/driver/postgres"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/review"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+)
+
+var _ = Describe("Service", func() {
+	var (
+		service review.Service
+		sqlMock sqlmock.Sqlmock
+		userID  uuid.UUID
+		bookID  uuid.UUID
+	)
+
+	limitColumns := []string{"book_id", "topic", "daily_limit", "new_daily_limit"}
+	pickColumns := []string{"pick_id", "book_id", "book_title", "is_new"}
+
+	BeforeEach(func() {
+		db, sqlMockGen, _ := sqlmock.New()
+		sqlMock = sqlMockGen
+
+		conn := postgres.New(postgres.Config{
+			Conn: db,
+		})
+
+		database, _ := database.NewDB(conn)
+
+		userID = uuid.New()
+		bookID = uuid.New()
+
+		userService := user.NewMockService(gomock.NewController(GinkgoT()))
+		userService.EXPECT().GetUserByGuid(userID).Return(&domain.User{ID: 7, Guid: userID}, nil)
+
+		service = review.NewService(database, &config.Review{DailyLimit: 20, NewDailyLimit: 10}, userService)
+	})
+
+	Describe("GetDueReviews", func() {
+		It("should count only the reviews of the book against the limits of the book", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`FROM review_limits AS rl .* WHERE rl.user_id = \$1`).
+				WithArgs(7).
+				WillReturnRows(sqlmock.NewRows(limitColumns).
+					AddRow(nil, "", 50, 20).
+					AddRow(bookID, "", 5, 2))
+			sqlMock.ExpectQuery(`FROM pick_review_logs AS rl .* WHERE \(rl.user_id = \$1 AND rl.reviewed_at >= \$2\) AND b.guid = \$3`).
+				WithArgs(7, sqlmock.AnyArg(), bookID.String()).
+				WillReturnRows(sqlmock.NewRows([]string{"reviewed", "new_picks"}).AddRow(3, 2))
+			sqlMock.ExpectQuery(`FROM book_picks AS bp .* \(rs.id IS NOT NULL AND rs.due_at < \$3\) ORDER BY rs.due_at ASC LIMIT \$4`).
+				WithArgs(7, bookID.String(), sqlmock.AnyArg(), 2).
+				WillReturnRows(sqlmock.NewRows(pickColumns).
+					AddRow(uuid.New(), bookID, "Title", false))
+			sqlMock.ExpectCommit()
+
+			// Act
+			result, err := service.GetDueReviews(userID, &domain.ReviewDueParams{BookID: bookID.String()})
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(result.RemainingReviews).To(Equal(2))
+			Expect(result.RemainingNew).To(Equal(0))
+			Expect(result.Picks).To(HaveLen(1))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should use the limits of the user without a stored limit for the topic", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`FROM review_limits AS rl`).
+				WillReturnRows(sqlmock.NewRows(limitColumns).
+					AddRow(nil, "", 1, 0).
+					AddRow(bookID, "", 5, 2))
+			sqlMock.ExpectQuery(`FROM pick_review_logs AS rl .* t.topic = \$4`).
+				WithArgs(7, sqlmock.AnyArg(), 7, "psychology").
+				WillReturnRows(sqlmock.NewRows([]string{"reviewed", "new_picks"}).AddRow(1, 0))
+			sqlMock.ExpectCommit()
+
+			// Act
+			result, err := service.GetDueReviews(userID, &domain.ReviewDueParams{Topic: "psychology"})
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(result.RemainingReviews).To(Equal(0))
+			Expect(result.RemainingNew).To(Equal(0))
+			Expect(result.Picks).To(BeEmpty())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should use the configured limits when the user has none", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`FROM review_limits AS rl`).
+				WillReturnRows(sqlmock.NewRows(limitColumns))
+			sqlMock.ExpectQuery(`FROM pick_review_logs AS rl`).
+				WillReturnRows(sqlmock.NewRows([]string{"reviewed", "new_picks"}).AddRow(20, 10))
+			sqlMock.ExpectCommit()
+
+			// Act
+			result, err := service.GetDueReviews(userID, &domain.ReviewDueParams{})
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(result.RemainingReviews).To(Equal(0))
+			Expect(result.RemainingNew).To(Equal(0))
+		})
+	})
+
+	Describe("SetReviewLimit", func() {
+		It("should update the limits of all the picks", func() {
+			// Arrange
+			dailyLimit, newDailyLimit := 30, 5
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT \* FROM "review_limits" WHERE "book_id" IS NULL AND "topic_id" IS NULL AND "user_id" = \$1`).
+				WithArgs(7, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "daily_limit", "new_daily_limit"}).AddRow(4, 7, 20, 10))
+			sqlMock.ExpectExec(`UPDATE "review_limits" SET "daily_limit"=\$1,"new_daily_limit"=\$2,"updated_at"=\$3 WHERE .*"id" = \$\d+`).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectCommit()
+
+			// Act
+			result, err := service.SetReviewLimit(userID, &domain.ReviewLimitBody{DailyLimit: &dailyLimit, NewDailyLimit: &newDailyLimit})
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(result.BookID).To(BeNil())
+			Expect(result.DailyLimit).To(Equal(30))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
@@ -15,6 +15,15 @@ var _ Service = (*serviceImpl)(nil)
 type Service interface {
 	UpdateUserSession(userID uuid.UUID, session *domain.PatchSessionBody) error
 	LogoutSession(userID uuid.UUID, sessionID uuid.UUID) error
+
+	// GetActiveSessions List the sessions the user is logged in with, the current one is flagged
+	GetActiveSessions(userID uuid.UUID, currentSessionID uuid.UUID) ([]domain.SessionResponse, error)
+
+	// RevokeSession Log out a single active session of the user, e.g. a lost device
+	RevokeSession(userID uuid.UUID, sessionID uuid.UUID) error
+
+	// LogoutOtherSessions Log out every session but the current one, returns the number of revoked sessions
+	LogoutOtherSessions(userID uuid.UUID, currentSessionID uuid.UUID) (int, error)
 }
 
 type serviceImpl struct {
@@ -46,6 +55,18 @@ func (service *serviceImpl) UpdateUserSession(userID uuid.UUID, s *domain.PatchS
 		if s.DeviceToken != "" {
 			data["device_token"] = s.DeviceToken
 
+			/* A new device token starts again with the push notifications enabled */
+			data["push_failures"] = gorm.Expr("CASE WHEN device_token = ? THEN push_failures ELSE 0 END", s.DeviceToken)
+			data["push_disabled_at"] = gorm.Expr("CASE WHEN device_token = ? THEN push_disabled_at END", s.DeviceToken)
+
+			/* The platform and environment of the token, the former app versions do not send them */
+			if s.Platform != "" {
+				data["platform"] = s.Platform
+			}
+			if s.Environment != "" {
+				data["push_environment"] = s.Environment
+			}
+
 			/* If the device token is updated, the user has granted permission for push notifications, therefore update corresponding user entry */
 			err := tx.Model(&domain.User{}).Where("id = ?", user.ID).Update("is_notification_enabled", true).Error
 			if err != nil {
@@ -69,10 +90,92 @@ func (service *serviceImpl) LogoutSession(userID uuid.UUID, sessionID uuid.UUID)
 		return err
 	}
 
-	// Search for the session in the database and delete it
-	if err := service.db.Model(&domain.Session{}).Where("user_id = ? AND guid = ?", user.ID, sessionID).Update("expired_at", time.Now()).Error; err != nil {
+	return service.db.Transaction(func(tx *gorm.DB) error {
+		// Search for the session in the database and expire it
+		ids := []uint{}
+		if err := tx.Model(&domain.Session{}).Where("user_id = ? AND guid = ?", user.ID, sessionID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		return expireSessions(tx, ids)
+	})
+}
+
+func (service *serviceImpl) GetActiveSessions(userID uuid.UUID, currentSessionID uuid.UUID) ([]domain.SessionResponse, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []domain.Session{}
+	err = service.db.Where("user_id = ? AND expired_at = ?", user.ID, "0001-01-01 00:00:00").Order("updated_at DESC").Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]domain.SessionResponse, len(sessions))
+	for i := range sessions {
+		response[i] = domain.SessionResponseFromModel(&sessions[i], currentSessionID)
+	}
+
+	return response, nil
+}
+
+func (service *serviceImpl) RevokeSession(userID uuid.UUID, sessionID uuid.UUID) error {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return err
+	}
+
+	return service.db.Transaction(func(tx *gorm.DB) error {
+		ids := []uint{}
+		err := tx.Model(&domain.Session{}).Where("user_id = ? AND guid = ? AND expired_at = ?", user.ID, sessionID, "0001-01-01 00:00:00").Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return expireSessions(tx, ids)
+	})
+}
+
+func (service *serviceImpl) LogoutOtherSessions(userID uuid.UUID, currentSessionID uuid.UUID) (int, error) {
+	user, err := service.userService.GetUserByGuid(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := []uint{}
+
+	err = service.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.Session{}).Where("user_id = ? AND guid <> ? AND expired_at = ?", user.ID, currentSessionID, "0001-01-01 00:00:00").Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+
+		return expireSessions(tx, ids)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(ids), nil
+}
+
+/* Expire the sessions and revoke their refresh tokens, access tokens are denied by the authorizer */
+func expireSessions(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	if err := tx.Model(&domain.Session{}).Where("id IN ?", ids).Update("expired_at", now).Error; err != nil {
 		return err
 	}
 
-	return nil
+	return tx.Model(&domain.RefreshToken{}).Where("session_id IN ? AND revoked_at IS NULL", ids).Update("revoked_at", now).Error
 }
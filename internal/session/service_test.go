@@ -0,0 +1,151 @@
+package session_test
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/session"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+)
+
+var _ = Describe("Service", func() {
+	var (
+		service          session.Service
+		sqlMock          sqlmock.Sqlmock
+		userID           uuid.UUID
+		currentSessionID uuid.UUID
+	)
+
+	BeforeEach(func() {
+		db, sqlMockGen, _ := sqlmock.New()
+		sqlMock = sqlMockGen
+
+		conn := postgres.New(postgres.Config{
+			Conn: db,
+		})
+
+		database, _ := database.NewDB(conn)
+
+		userID = uuid.New()
+		currentSessionID = uuid.New()
+
+		userService := user.NewMockService(gomock.NewController(GinkgoT()))
+		userService.EXPECT().GetUserByGuid(userID).Return(&domain.User{ID: 7, Guid: userID}, nil)
+
+		service = session.NewService(database, userService)
+	})
+
+	Describe("GetActiveSessions", func() {
+		It("should flag the current session", func() {
+			// Arrange
+			otherSessionID := uuid.New()
+
+			sqlMock.ExpectQuery(`SELECT \* FROM "sessions" WHERE user_id = \$1 AND expired_at = \$2 ORDER BY updated_at DESC`).
+				WithArgs(7, "0001-01-01 00:00:00").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "guid", "user_id", "device_id"}).
+					AddRow(1, currentSessionID, 7, "iphone").
+					AddRow(2, otherSessionID, 7, "ipad"))
+
+			// Act
+			sessions, err := service.GetActiveSessions(userID, currentSessionID)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(sessions).To(HaveLen(2))
+			Expect(sessions[0].IsCurrent).To(BeTrue())
+			Expect(sessions[1].IsCurrent).To(BeFalse())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+
+	Describe("RevokeSession", func() {
+		It("should return not found when the session belongs to another user", func() {
+			// Arrange
+			otherUserSessionID := uuid.New()
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT "id" FROM "sessions" WHERE user_id = \$1 AND guid = \$2 AND expired_at = \$3`).
+				WithArgs(7, otherUserSessionID, "0001-01-01 00:00:00").
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			sqlMock.ExpectRollback()
+
+			// Act
+			err := service.RevokeSession(userID, otherUserSessionID)
+
+			// Assert
+			Expect(err).To(Equal(gorm.ErrRecordNotFound))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should expire the session and revoke its refresh tokens", func() {
+			// Arrange
+			sessionID := uuid.New()
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT "id" FROM "sessions" WHERE user_id = \$1 AND guid = \$2 AND expired_at = \$3`).
+				WithArgs(7, sessionID, "0001-01-01 00:00:00").
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			sqlMock.ExpectExec(`UPDATE "sessions" SET "expired_at"=\$1,"updated_at"=\$2 WHERE id IN \(\$3\)`).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectExec(`UPDATE "refresh_tokens" SET "revoked_at"=\$1.* WHERE session_id IN \(\$\d\) AND revoked_at IS NULL`).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectCommit()
+
+			// Act
+			err := service.RevokeSession(userID, sessionID)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+
+	Describe("LogoutOtherSessions", func() {
+		It("should keep the current session", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT "id" FROM "sessions" WHERE user_id = \$1 AND guid <> \$2 AND expired_at = \$3`).
+				WithArgs(7, currentSessionID, "0001-01-01 00:00:00").
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+			sqlMock.ExpectExec(`UPDATE "sessions" SET "expired_at"=\$1,"updated_at"=\$2 WHERE id IN \(\$3,\$4\)`).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2, 3).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			sqlMock.ExpectExec(`UPDATE "refresh_tokens" SET "revoked_at"=\$1.* WHERE session_id IN \(\$\d,\$\d\) AND revoked_at IS NULL`).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			sqlMock.ExpectCommit()
+
+			// Act
+			count, err := service.LogoutOtherSessions(userID, currentSessionID)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(count).To(Equal(2))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should do nothing when the current session is the only one", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT "id" FROM "sessions" WHERE user_id = \$1 AND guid <> \$2 AND expired_at = \$3`).
+				WithArgs(7, currentSessionID, "0001-01-01 00:00:00").
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			sqlMock.ExpectCommit()
+
+			// Act
+			count, err := service.LogoutOtherSessions(userID, currentSessionID)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(count).To(Equal(0))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
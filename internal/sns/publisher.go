@@ -0,0 +1,113 @@
+package sns
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+// MaxBatchSize is the maximum number of messages of a batch publish.
+const MaxBatchSize = 10
+
+// ErrTopicNotFound is returned when no topic has the name.
+var ErrTopicNotFound = errors.New("sns topic not found")
+
+// Publisher publishes the messages in batches, it reuses the AWS session and the topic ARNs across the calls.
+type Publisher struct {
+	sess      *session.Session
+	svc       *sns.SNS
+	topicArns map[string]*string
+}
+
+// NewPublisher creates a new publisher.
+func NewPublisher() *Publisher {
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+		Config:            aws.Config{Region: aws.String("eu-central-1")},
+	}))
+
+	return &Publisher{
+		sess:      sess,
+		svc:       sns.New(sess),
+		topicArns: map[string]*string{},
+	}
+}
+
+// PublishBatch publishes the messages to the topic, MaxBatchSize per call. It returns the indexes of the
+// messages that were not published, the error only when none of them could be published.
+func (p *Publisher) PublishBatch(topicName string, messages []interface{}) ([]int, error) {
+	topicArn, err := p.topicArn(topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := []int{}
+
+	for start := 0; start < len(messages); start += MaxBatchSize {
+		end := min(start+MaxBatchSize, len(messages))
+
+		entries := []*sns.PublishBatchRequestEntry{}
+		for i := start; i < end; i++ {
+			jsonMessage, err := json.Marshal(messages[i])
+			if err != nil {
+				failed = append(failed, i)
+				continue
+			}
+
+			/* The ids only have to be unique within the batch, the index finds the message back */
+			entries = append(entries, &sns.PublishBatchRequestEntry{
+				Id:      aws.String(strconv.Itoa(i)),
+				Message: aws.String(string(jsonMessage)),
+			})
+		}
+
+		if len(entries) == 0 {
+			continue
+		}
+
+		output, err := p.svc.PublishBatch(&sns.PublishBatchInput{
+			TopicArn:                   topicArn,
+			PublishBatchRequestEntries: entries,
+		})
+		if err != nil {
+			for _, entry := range entries {
+				index, _ := strconv.Atoi(*entry.Id)
+				failed = append(failed, index)
+			}
+			continue
+		}
+
+		for _, entry := range output.Failed {
+			index, _ := strconv.Atoi(aws.StringValue(entry.Id))
+			failed = append(failed, index)
+		}
+	}
+
+	if len(messages) > 0 && len(failed) == len(messages) {
+		return failed, errors.New("no message published")
+	}
+
+	return failed, nil
+}
+
+func (p *Publisher) topicArn(topicName string) (*string, error) {
+	if topicArn, ok := p.topicArns[topicName]; ok {
+		return topicArn, nil
+	}
+
+	topicArn, err := getTopicArnFromName(p.sess, topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	if topicArn == nil {
+		return nil, ErrTopicNotFound
+	}
+
+	p.topicArns[topicName] = topicArn
+	return topicArn, nil
+}
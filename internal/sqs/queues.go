@@ -2,8 +2,12 @@ package sqs
 
 type QueueNamesStruct struct {
 	PickKeywords string
+	PickCards    string
+	BookDigests  string
 }
 
 var QueueNames = QueueNamesStruct{
 	PickKeywords: "pick-keywords",
+	PickCards:    "pick-cards",
+	BookDigests:  "book-digests",
 }
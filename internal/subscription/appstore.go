@@ -0,0 +1,176 @@
+package subscription
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+// Base URLs of the App Store Server API.
+const (
+	AppStoreProductionURL = "https://api.storekit.itunes.apple.com"
+	AppStoreSandboxURL    = "https://api.storekit-sandbox.itunes.apple.com"
+)
+
+var (
+	// ErrTransactionNotFound is returned when the App Store does not know the transaction.
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrAppStoreNotConfigured is returned when the in-app purchase key is not configured.
+	ErrAppStoreNotConfigured = errors.New("app store not configured")
+)
+
+// AppStoreClient represents the App Store Server API, the returned data is signed by the App Store.
+type AppStoreClient interface {
+	// GetTransactionInfo returns the signed transaction of the ID.
+	GetTransactionInfo(ctx context.Context, transactionID string) (string, error)
+	// GetSubscriptionStatuses returns the last transactions of the subscriptions of the transaction, with their renewal info.
+	GetSubscriptionStatuses(ctx context.Context, transactionID string) ([]domain.AppStoreLastTransaction, error)
+}
+
+// httpAppStoreClient represents the App Store Server API client.
+type httpAppStoreClient struct {
+	baseURL   string
+	isSandbox bool
+	keyID     string
+	issuerID  string
+	bundleID  string
+	key       *ecdsa.PrivateKey
+	client    *http.Client
+}
+
+// NewAppStoreClient creates the App Store Server API client of the configuration.
+func NewAppStoreClient(appleConfig config.Apple, appStoreConfig config.AppStore) (AppStoreClient, error) {
+	if appStoreConfig.KeyID == "" || appStoreConfig.PrivateKey == "" {
+		return nil, ErrAppStoreNotConfigured
+	}
+
+	block, _ := pem.Decode([]byte(appStoreConfig.PrivateKey))
+	if block == nil {
+		return nil, errors.New("app store private key is not PEM encoded")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	ecdsaKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported app store key type %T", key)
+	}
+
+	client := &httpAppStoreClient{
+		baseURL:  AppStoreProductionURL,
+		keyID:    appStoreConfig.KeyID,
+		issuerID: appleConfig.IssuerId,
+		bundleID: appleConfig.AppBundleId,
+		key:      ecdsaKey,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+
+	if appStoreConfig.Environment == domain.AppStoreEnvironmentSandbox {
+		client.baseURL = AppStoreSandboxURL
+		client.isSandbox = true
+	}
+
+	return client, nil
+}
+
+func (c *httpAppStoreClient) GetTransactionInfo(ctx context.Context, transactionID string) (string, error) {
+	response := struct {
+		SignedTransactionInfo string `json:"signedTransactionInfo"`
+	}{}
+
+	if err := c.get(ctx, "/inApps/v1/transactions/"+url.PathEscape(transactionID), &response); err != nil {
+		return "", err
+	}
+
+	return response.SignedTransactionInfo, nil
+}
+
+func (c *httpAppStoreClient) GetSubscriptionStatuses(ctx context.Context, transactionID string) ([]domain.AppStoreLastTransaction, error) {
+	response := struct {
+		Data []struct {
+			LastTransactions []domain.AppStoreLastTransaction `json:"lastTransactions"`
+		} `json:"data"`
+	}{}
+
+	if err := c.get(ctx, "/inApps/v1/subscriptions/"+url.PathEscape(transactionID), &response); err != nil {
+		return nil, err
+	}
+
+	lastTransactions := []domain.AppStoreLastTransaction{}
+	for _, group := range response.Data {
+		lastTransactions = append(lastTransactions, group.LastTransactions...)
+	}
+
+	return lastTransactions, nil
+}
+
+// get calls the endpoint of the production environment, then the sandbox one when the transaction is not found
+// there, as Apple recommends for the purchases made with TestFlight builds.
+func (c *httpAppStoreClient) get(ctx context.Context, path string, response interface{}) error {
+	err := c.getFrom(ctx, c.baseURL, path, response)
+
+	if errors.Is(err, ErrTransactionNotFound) && !c.isSandbox {
+		return c.getFrom(ctx, AppStoreSandboxURL, path, response)
+	}
+
+	return err
+}
+
+func (c *httpAppStoreClient) getFrom(ctx context.Context, baseURL string, path string, response interface{}) error {
+	token, err := c.authToken()
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	httpResponse, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return ErrTransactionNotFound
+	}
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return fmt.Errorf("app store server api %s returned %d", path, httpResponse.StatusCode)
+	}
+
+	return json.NewDecoder(httpResponse.Body).Decode(response)
+}
+
+// authToken signs the token of the App Store Server API with the in-app purchase key.
+func (c *httpAppStoreClient) authToken() (string, error) {
+	now := time.Now()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": c.issuerID,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+		"aud": "appstoreconnect-v1",
+		"bid": c.bundleID,
+	})
+	token.Header["kid"] = c.keyID
+
+	return token.SignedString(c.key)
+}
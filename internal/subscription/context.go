@@ -0,0 +1,46 @@
+package subscription
+
+import (
+	"errors"
+
+	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"gorm.io/gorm"
+)
+
+type Context struct {
+	Service  Service
+	Config   *config.Config
+	Database *gorm.DB
+}
+
+func NewContext() (*Context, error) {
+
+	config, err := config.NewConfig()
+	if err != nil {
+		return nil, errors.New("failed load subscription context config: " + err.Error())
+	}
+
+	database, err := database.NewDB(database.NewConn(&config.Database))
+	if err != nil {
+		return nil, errors.New("failed load subscription context database: " + err.Error())
+	}
+
+	client, err := NewAppStoreClient(config.Apple, config.AppStore)
+	if err != nil {
+		return nil, errors.New("failed load subscription context app store client: " + err.Error())
+	}
+
+	verifier, err := NewVerifierFromPEM(config.AppStore.RootCertificate)
+	if err != nil {
+		return nil, errors.New("failed load subscription context verifier: " + err.Error())
+	}
+
+	service := NewService(database, client, verifier, config.Apple.AppBundleId)
+
+	return &Context{
+		Service:  service,
+		Config:   config,
+		Database: database,
+	}, nil
+}
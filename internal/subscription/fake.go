@@ -0,0 +1,224 @@
+package subscription
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+var _ AppStoreClient = (*FakeAppStore)(nil)
+
+// FakeAppStore stands in for the App Store Server API in tests and local use. It signs the data with
+// its own certificate chain, shaped as the App Store one and trusted by the verifier it returns.
+type FakeAppStore struct {
+	key   *ecdsa.PrivateKey
+	chain []string
+	roots *x509.CertPool
+
+	mu           sync.Mutex
+	transactions map[string]domain.AppStoreTransaction
+	renewalInfos map[string]domain.AppStoreRenewalInfo
+}
+
+// NewFakeAppStore creates a fake App Store with a new certificate chain.
+func NewFakeAppStore() (*FakeAppStore, error) {
+	rootKey, root, err := newFakeCertificate("Fake Apple Root CA", true, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	intermediateKey, intermediate, err := newFakeCertificate("Fake Apple Worldwide Developer Relations CA", true, appStoreIntermediateOID, root, rootKey)
+	if err != nil {
+		return nil, err
+	}
+
+	leafKey, leaf, err := newFakeCertificate("Fake App Store Signing", false, appStoreLeafOID, intermediate, intermediateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	return &FakeAppStore{
+		key: leafKey,
+		chain: []string{
+			base64.StdEncoding.EncodeToString(leaf.Raw),
+			base64.StdEncoding.EncodeToString(intermediate.Raw),
+			base64.StdEncoding.EncodeToString(root.Raw),
+		},
+		roots:        roots,
+		transactions: map[string]domain.AppStoreTransaction{},
+		renewalInfos: map[string]domain.AppStoreRenewalInfo{},
+	}, nil
+}
+
+// newFakeCertificate creates a certificate signed by the parent, a self-signed root when the parent is nil.
+func newFakeCertificate(name string, isCA bool, marker asn1.ObjectIdentifier, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*ecdsa.PrivateKey, *x509.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+
+	if isCA {
+		template.IsCA = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	if marker != nil {
+		/* The App Store marker extensions hold an ASN.1 NULL */
+		template.ExtraExtensions = []pkix.Extension{{Id: marker, Value: []byte{0x05, 0x00}}}
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, certificate, nil
+}
+
+// Verifier returns a verifier trusting the chain of the fake App Store.
+func (f *FakeAppStore) Verifier() *Verifier {
+	return NewVerifier(f.roots)
+}
+
+// Sign signs the payload as the App Store does.
+func (f *FakeAppStore) Sign(payload interface{}) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	/* Keep the dates in milliseconds as integers */
+	claims := jwt.MapClaims{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["x5c"] = f.chain
+
+	return token.SignedString(f.key)
+}
+
+// SignNotification signs a notification of the subscription data of the transaction.
+func (f *FakeAppStore) SignNotification(notificationType string, subtype string, transaction domain.AppStoreTransaction, renewalInfo domain.AppStoreRenewalInfo) (string, error) {
+	signedTransaction, err := f.Sign(transaction)
+	if err != nil {
+		return "", err
+	}
+
+	signedRenewalInfo, err := f.Sign(renewalInfo)
+	if err != nil {
+		return "", err
+	}
+
+	return f.Sign(domain.AppStoreNotification{
+		NotificationType: notificationType,
+		Subtype:          subtype,
+		NotificationUUID: transaction.TransactionID + "-" + notificationType,
+		Data: domain.AppStoreNotificationData{
+			BundleID:              transaction.BundleID,
+			Environment:           transaction.Environment,
+			SignedTransactionInfo: signedTransaction,
+			SignedRenewalInfo:     signedRenewalInfo,
+		},
+		Version:    "2.0",
+		SignedDate: transaction.SignedDate,
+	})
+}
+
+// AddTransaction records a transaction and the renewal info of its subscription.
+func (f *FakeAppStore) AddTransaction(transaction domain.AppStoreTransaction, renewalInfo domain.AppStoreRenewalInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.transactions[transaction.TransactionID] = transaction
+	f.renewalInfos[transaction.OriginalTransactionID] = renewalInfo
+}
+
+func (f *FakeAppStore) GetTransactionInfo(ctx context.Context, transactionID string) (string, error) {
+	f.mu.Lock()
+	transaction, ok := f.transactions[transactionID]
+	f.mu.Unlock()
+
+	if !ok {
+		return "", ErrTransactionNotFound
+	}
+
+	return f.Sign(transaction)
+}
+
+func (f *FakeAppStore) GetSubscriptionStatuses(ctx context.Context, transactionID string) ([]domain.AppStoreLastTransaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	transaction, ok := f.transactions[transactionID]
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+
+	/* The last transaction of the subscription is the latest renewal */
+	last := transaction
+	for _, other := range f.transactions {
+		if other.OriginalTransactionID == transaction.OriginalTransactionID && other.PurchaseDate > last.PurchaseDate {
+			last = other
+		}
+	}
+
+	signedTransaction, err := f.Sign(last)
+	if err != nil {
+		return nil, err
+	}
+
+	signedRenewalInfo, err := f.Sign(f.renewalInfos[last.OriginalTransactionID])
+	if err != nil {
+		return nil, err
+	}
+
+	return []domain.AppStoreLastTransaction{{
+		OriginalTransactionID: last.OriginalTransactionID,
+		Status:                1,
+		SignedTransactionInfo: signedTransaction,
+		SignedRenewalInfo:     signedRenewalInfo,
+	}}, nil
+}
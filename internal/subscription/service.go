@@ -0,0 +1,336 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/inbox"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSubscriptionNotFound is returned when the user or the notification has no known subscription.
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	// ErrSubscriptionOwnedByAnotherUser is returned when the transaction has been purchased by another user.
+	ErrSubscriptionOwnedByAnotherUser = errors.New("subscription belongs to another user")
+	// ErrBundleMismatch is returned when the signed data belongs to another app.
+	ErrBundleMismatch = errors.New("signed data of another app")
+)
+
+// Service represents the subscription service, the premium status of the users follows the verified subscriptions.
+type Service interface {
+	// GetSubscription Find the latest subscription of the user
+	GetSubscription(userID uuid.UUID) (*domain.SubscriptionResponse, error)
+	// VerifyTransaction Verify a purchase of the user with the App Store and store its subscription
+	VerifyTransaction(ctx context.Context, userID uuid.UUID, transactionID string) (*domain.SubscriptionResponse, error)
+	// HandleNotification Verify an App Store Server Notification V2 and update the subscription
+	HandleNotification(ctx context.Context, signedPayload string) error
+}
+
+type serviceImpl struct {
+	db       *gorm.DB
+	client   AppStoreClient
+	verifier *Verifier
+	bundleID string
+}
+
+// NewService creates a new subscription service.
+func NewService(db *gorm.DB, client AppStoreClient, verifier *Verifier, bundleID string) Service {
+	return &serviceImpl{
+		db:       db,
+		client:   client,
+		verifier: verifier,
+		bundleID: bundleID,
+	}
+}
+
+func (service *serviceImpl) GetSubscription(userID uuid.UUID) (*domain.SubscriptionResponse, error) {
+	var subscription domain.Subscription
+
+	err := service.db.Model(&domain.Subscription{}).
+		Joins("JOIN users ON users.id = subscriptions.user_id").
+		Where("users.guid = ?", userID).
+		Order("subscriptions.expires_at DESC").
+		First(&subscription).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.SubscriptionResponseFromModel(&subscription, time.Now()), nil
+}
+
+func (service *serviceImpl) VerifyTransaction(ctx context.Context, userID uuid.UUID, transactionID string) (*domain.SubscriptionResponse, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	signedTransaction, err := service.client.GetTransactionInfo(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := service.verifyTransaction(signedTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	/* The app sets the GUID of the user as app account token when purchasing */
+	if transaction.AppAccountToken != "" && transaction.AppAccountToken != userID.String() {
+		return nil, ErrSubscriptionOwnedByAnotherUser
+	}
+
+	/* The state of the subscription is the one of its last transaction, the purchase may have been renewed since */
+	statuses, err := service.client.GetSubscriptionStatuses(ctx, transaction.OriginalTransactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastTransaction *domain.AppStoreTransaction
+	var renewalInfo *domain.AppStoreRenewalInfo
+
+	for _, status := range statuses {
+		if status.OriginalTransactionID != transaction.OriginalTransactionID {
+			continue
+		}
+
+		if lastTransaction, err = service.verifyTransaction(status.SignedTransactionInfo); err != nil {
+			return nil, err
+		}
+
+		if renewalInfo, err = service.verifier.VerifyRenewalInfo(status.SignedRenewalInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	if lastTransaction == nil {
+		return nil, ErrTransactionNotFound
+	}
+
+	var subscription *domain.Subscription
+
+	err = service.db.Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.Where("guid = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+
+		subscription, err = saveSubscription(tx, user.ID, lastTransaction, renewalInfo)
+		return err
+	})
+	if err != nil {
+		logger.Error("Failed to save subscription", zap.Error(err))
+		return nil, err
+	}
+
+	return domain.SubscriptionResponseFromModel(subscription, time.Now()), nil
+}
+
+func (service *serviceImpl) HandleNotification(ctx context.Context, signedPayload string) error {
+	notification, err := service.verifier.VerifyNotification(signedPayload)
+	if err != nil {
+		return err
+	}
+
+	if notification.Data.BundleID != service.bundleID {
+		return ErrBundleMismatch
+	}
+
+	/* Test notifications and the ones of the one-time purchases carry no subscription */
+	if notification.NotificationType == domain.AppStoreNotificationTest || notification.Data.SignedTransactionInfo == "" {
+		return nil
+	}
+
+	transaction, err := service.verifyTransaction(notification.Data.SignedTransactionInfo)
+	if err != nil {
+		return err
+	}
+
+	var renewalInfo *domain.AppStoreRenewalInfo
+	if notification.Data.SignedRenewalInfo != "" {
+		if renewalInfo, err = service.verifier.VerifyRenewalInfo(notification.Data.SignedRenewalInfo); err != nil {
+			return err
+		}
+	}
+
+	return service.db.Transaction(func(tx *gorm.DB) error {
+		userID, err := findSubscriptionUser(tx, transaction)
+		if err != nil {
+			return err
+		}
+
+		if _, err := saveSubscription(tx, userID, transaction, renewalInfo); err != nil {
+			return err
+		}
+
+		/* The events the user has to know about are kept in the inbox, once per notification */
+		title, body, ok := inboxContent(notification)
+		if !ok {
+			return nil
+		}
+
+		return inbox.Add(tx, &domain.InboxNotification{
+			UserID:    userID,
+			Kind:      domain.InboxKindSubscription,
+			Title:     title,
+			Body:      body,
+			DedupeKey: inbox.DedupeKey(domain.InboxKindSubscription, notification.NotificationUUID),
+		})
+	})
+}
+
+// inboxContent returns the inbox notification of the App Store notification, false for the ones the user
+// does not need to know about (e.g. the renewals).
+func inboxContent(notification *domain.AppStoreNotification) (string, string, bool) {
+	switch notification.NotificationType {
+	case "SUBSCRIBED":
+		return "Welcome to Premium", "Your premium subscription is active, enjoy the whole of Feynman.", true
+	case "DID_FAIL_TO_RENEW":
+		return "Subscription not renewed", "The App Store could not renew your subscription, check your payment method to keep premium.", true
+	case "DID_CHANGE_RENEWAL_STATUS":
+		if notification.Subtype == "AUTO_RENEW_DISABLED" {
+			return "Auto-renew turned off", "Your subscription will not renew, premium stays active until it expires.", true
+		}
+	case "EXPIRED", "GRACE_PERIOD_EXPIRED":
+		return "Premium ended", "Your premium subscription has ended, your library is still here.", true
+	case "REFUND", "REVOKE":
+		return "Premium revoked", "Your premium access was refunded or revoked.", true
+	}
+
+	return "", "", false
+}
+
+// verifyTransaction verifies a signed transaction of the app.
+func (service *serviceImpl) verifyTransaction(signedTransaction string) (*domain.AppStoreTransaction, error) {
+	transaction, err := service.verifier.VerifyTransaction(signedTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	if transaction.BundleID != service.bundleID {
+		return nil, ErrBundleMismatch
+	}
+
+	return transaction, nil
+}
+
+// ApplyTransaction updates the subscription with the signed data of its last transaction and renewal info,
+// the renewal info is optional.
+func ApplyTransaction(subscription *domain.Subscription, transaction *domain.AppStoreTransaction, renewalInfo *domain.AppStoreRenewalInfo) {
+	subscription.OriginalTransactionID = transaction.OriginalTransactionID
+	subscription.TransactionID = transaction.TransactionID
+	subscription.ProductID = transaction.ProductID
+	subscription.Environment = transaction.Environment
+	subscription.PurchasedAt = time.UnixMilli(transaction.PurchaseDate)
+	subscription.ExpiresAt = time.UnixMilli(transaction.ExpiresDate)
+	subscription.SignedAt = time.UnixMilli(transaction.SignedDate)
+
+	/* Refunded or revoked by family sharing */
+	subscription.RevokedAt = nil
+	subscription.RevocationReason = transaction.RevocationReason
+	if transaction.RevocationDate > 0 {
+		revokedAt := time.UnixMilli(transaction.RevocationDate)
+		subscription.RevokedAt = &revokedAt
+	}
+
+	if renewalInfo == nil {
+		return
+	}
+
+	subscription.IsAutoRenewEnabled = renewalInfo.AutoRenewStatus == 1
+	subscription.AutoRenewProductID = renewalInfo.AutoRenewProductID
+	subscription.IsInBillingRetry = renewalInfo.IsInBillingRetryPeriod
+
+	subscription.GracePeriodExpiresAt = nil
+	if renewalInfo.GracePeriodExpiresDate > 0 {
+		gracePeriodExpiresAt := time.UnixMilli(renewalInfo.GracePeriodExpiresDate)
+		subscription.GracePeriodExpiresAt = &gracePeriodExpiresAt
+	}
+
+	if signedAt := time.UnixMilli(renewalInfo.SignedDate); signedAt.After(subscription.SignedAt) {
+		subscription.SignedAt = signedAt
+	}
+}
+
+/* Find the user of the subscription, by the app account token when it is not known yet */
+func findSubscriptionUser(tx *gorm.DB, transaction *domain.AppStoreTransaction) (uint, error) {
+	var subscription domain.Subscription
+	err := tx.Where("original_transaction_id = ?", transaction.OriginalTransactionID).First(&subscription).Error
+	if err == nil {
+		return subscription.UserID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	userID, err := uuid.Parse(transaction.AppAccountToken)
+	if err != nil {
+		return 0, ErrSubscriptionNotFound
+	}
+
+	var user domain.User
+	if err := tx.Where("guid = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrSubscriptionNotFound
+		}
+		return 0, err
+	}
+
+	return user.ID, nil
+}
+
+/* Create or update the subscription of the transaction, data signed before the stored one is ignored */
+func saveSubscription(tx *gorm.DB, userID uint, transaction *domain.AppStoreTransaction, renewalInfo *domain.AppStoreRenewalInfo) (*domain.Subscription, error) {
+	var subscription domain.Subscription
+
+	err := tx.Where("original_transaction_id = ?", transaction.OriginalTransactionID).First(&subscription).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err == nil && subscription.UserID != userID {
+		return nil, ErrSubscriptionOwnedByAnotherUser
+	}
+
+	previous := subscription
+	subscription.UserID = userID
+	ApplyTransaction(&subscription, transaction, renewalInfo)
+
+	if previous.ID != 0 && subscription.SignedAt.Before(previous.SignedAt) {
+		return &previous, nil
+	}
+
+	if err := tx.Save(&subscription).Error; err != nil {
+		return nil, err
+	}
+
+	if err := syncPremium(tx, userID); err != nil {
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+/* Update the premium status of the user with the subscription ending last */
+func syncPremium(tx *gorm.DB, userID uint) error {
+	var subscriptions []domain.Subscription
+	if err := tx.Where("user_id = ?", userID).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	var premiumExpiresAt *time.Time
+	for _, subscription := range subscriptions {
+		expiresAt := subscription.PremiumExpiresAt()
+		if expiresAt != nil && (premiumExpiresAt == nil || expiresAt.After(*premiumExpiresAt)) {
+			premiumExpiresAt = expiresAt
+		}
+	}
+
+	return tx.Model(&domain.User{}).Where("id = ?", userID).Update("premium_expires_at", premiumExpiresAt).Error
+}
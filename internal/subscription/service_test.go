@@ -0,0 +1,144 @@
+package subscription_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/subscription"
+)
+
+var _ = Describe("Service", func() {
+	Describe("ApplyTransaction", func() {
+		It("should keep the premium access during the grace period", func() {
+			// Arrange
+			transaction := newTestTransaction()
+			transaction.ExpiresDate = time.Now().Add(-time.Hour).UnixMilli()
+			renewalInfo := &domain.AppStoreRenewalInfo{
+				AutoRenewStatus:        1,
+				IsInBillingRetryPeriod: true,
+				GracePeriodExpiresDate: time.Now().Add(24 * time.Hour).UnixMilli(),
+			}
+			result := &domain.Subscription{}
+
+			// Act
+			subscription.ApplyTransaction(result, &transaction, renewalInfo)
+
+			// Assert
+			Expect(result.IsAutoRenewEnabled).To(BeTrue())
+			Expect(result.IsInBillingRetry).To(BeTrue())
+			Expect(result.GracePeriodExpiresAt).NotTo(BeNil())
+			Expect(result.IsPremium(time.Now())).To(BeTrue())
+			Expect(result.IsPremium(time.Now().Add(48 * time.Hour))).To(BeFalse())
+		})
+
+		It("should end the premium access of a refunded transaction", func() {
+			// Arrange
+			transaction := newTestTransaction()
+			reason := 1
+			transaction.RevocationDate = time.Now().UnixMilli()
+			transaction.RevocationReason = &reason
+			result := &domain.Subscription{}
+
+			// Act
+			subscription.ApplyTransaction(result, &transaction, nil)
+
+			// Assert
+			Expect(result.RevokedAt).NotTo(BeNil())
+			Expect(result.PremiumExpiresAt()).To(BeNil())
+			Expect(result.IsPremium(time.Now())).To(BeFalse())
+		})
+	})
+
+	Describe("HandleNotification", func() {
+		var appStore *subscription.FakeAppStore
+
+		BeforeEach(func() {
+			var err error
+			appStore, err = subscription.NewFakeAppStore()
+			Expect(err).To(BeNil())
+		})
+
+		It("should acknowledge test notifications without querying the database", func() {
+			// Arrange
+			service := subscription.NewService(nil, appStore, appStore.Verifier(), "app.feynman")
+			signed, err := appStore.Sign(domain.AppStoreNotification{
+				NotificationType: domain.AppStoreNotificationTest,
+				Data:             domain.AppStoreNotificationData{BundleID: "app.feynman"},
+			})
+			Expect(err).To(BeNil())
+
+			// Act
+			err = service.HandleNotification(context.Background(), signed)
+
+			// Assert
+			Expect(err).To(BeNil())
+		})
+
+		It("should reject the notifications of another app", func() {
+			// Arrange
+			service := subscription.NewService(nil, appStore, appStore.Verifier(), "app.other")
+			transaction := newTestTransaction()
+			signed, err := appStore.SignNotification("DID_RENEW", "", transaction, domain.AppStoreRenewalInfo{})
+			Expect(err).To(BeNil())
+
+			// Act
+			err = service.HandleNotification(context.Background(), signed)
+
+			// Assert
+			Expect(err).To(MatchError(subscription.ErrBundleMismatch))
+		})
+
+		It("should reject payloads not signed by the App Store", func() {
+			// Arrange
+			otherStore, err := subscription.NewFakeAppStore()
+			Expect(err).To(BeNil())
+			service := subscription.NewService(nil, appStore, appStore.Verifier(), "app.feynman")
+			signed, err := otherStore.SignNotification("REFUND", "", newTestTransaction(), domain.AppStoreRenewalInfo{})
+			Expect(err).To(BeNil())
+
+			// Act
+			err = service.HandleNotification(context.Background(), signed)
+
+			// Assert
+			Expect(err).To(MatchError(subscription.ErrInvalidSignedData))
+		})
+	})
+
+	Describe("VerifyTransaction", func() {
+		It("should reject transactions purchased with the account token of another user", func() {
+			// Arrange
+			appStore, err := subscription.NewFakeAppStore()
+			Expect(err).To(BeNil())
+			service := subscription.NewService(nil, appStore, appStore.Verifier(), "app.feynman")
+
+			transaction := newTestTransaction()
+			transaction.AppAccountToken = "7f6c7b2e-7a55-4b8e-9a55-2a0b3f1c9d10"
+			appStore.AddTransaction(transaction, domain.AppStoreRenewalInfo{OriginalTransactionID: transaction.OriginalTransactionID})
+
+			// Act
+			result, err := service.VerifyTransaction(context.Background(), uuid.Must(uuid.NewRandom()), transaction.TransactionID)
+
+			// Assert
+			Expect(result).To(BeNil())
+			Expect(err).To(MatchError(subscription.ErrSubscriptionOwnedByAnotherUser))
+		})
+
+		It("should return an error for unknown transactions", func() {
+			// Arrange
+			appStore, err := subscription.NewFakeAppStore()
+			Expect(err).To(BeNil())
+			service := subscription.NewService(nil, appStore, appStore.Verifier(), "app.feynman")
+
+			// Act
+			_, err = service.VerifyTransaction(context.Background(), uuid.Must(uuid.NewRandom()), "2000000000000009")
+
+			// Assert
+			Expect(err).To(MatchError(subscription.ErrTransactionNotFound))
+		})
+	})
+})
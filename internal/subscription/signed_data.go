@@ -0,0 +1,161 @@
+package subscription
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+)
+
+// ErrInvalidSignedData is returned when the data is not signed by the App Store.
+var ErrInvalidSignedData = errors.New("invalid app store signed data")
+
+// Marker extensions of the certificates of the App Store signing chain.
+var (
+	appStoreLeafOID         = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 11, 1}
+	appStoreIntermediateOID = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 2, 1}
+)
+
+// Verifier verifies the data signed by the App Store, a JWS with the certificate chain in the x5c header
+// which has to lead to the Apple root certificate.
+type Verifier struct {
+	roots *x509.CertPool
+}
+
+// NewVerifier creates a verifier trusting the given root certificates.
+func NewVerifier(roots *x509.CertPool) *Verifier {
+	return &Verifier{roots: roots}
+}
+
+// NewVerifierFromPEM creates a verifier trusting the PEM encoded root certificate.
+func NewVerifierFromPEM(rootCertificate string) (*Verifier, error) {
+	block, _ := pem.Decode([]byte(rootCertificate))
+	if block == nil {
+		return nil, errors.New("app store root certificate is not PEM encoded")
+	}
+
+	root, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	return NewVerifier(roots), nil
+}
+
+// VerifyTransaction verifies a signed transaction and returns its payload.
+func (v *Verifier) VerifyTransaction(signedTransaction string) (*domain.AppStoreTransaction, error) {
+	transaction := &domain.AppStoreTransaction{}
+	if err := v.verify(signedTransaction, transaction); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// VerifyRenewalInfo verifies a signed renewal info and returns its payload.
+func (v *Verifier) VerifyRenewalInfo(signedRenewalInfo string) (*domain.AppStoreRenewalInfo, error) {
+	renewalInfo := &domain.AppStoreRenewalInfo{}
+	if err := v.verify(signedRenewalInfo, renewalInfo); err != nil {
+		return nil, err
+	}
+
+	return renewalInfo, nil
+}
+
+// VerifyNotification verifies the signed payload of a notification and returns it.
+func (v *Verifier) VerifyNotification(signedPayload string) (*domain.AppStoreNotification, error) {
+	notification := &domain.AppStoreNotification{}
+	if err := v.verify(signedPayload, notification); err != nil {
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+// verify checks the signature with the leaf certificate of the chain and decodes the payload.
+func (v *Verifier) verify(signed string, payload interface{}) error {
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}), jwt.WithoutClaimsValidation())
+
+	if _, err := parser.Parse(signed, v.leafKey); err != nil {
+		return ErrInvalidSignedData
+	}
+
+	/* Decode the payload as is, the dates in milliseconds do not fit the registered claims */
+	segment, err := parser.DecodeSegment(strings.Split(signed, ".")[1])
+	if err != nil {
+		return ErrInvalidSignedData
+	}
+
+	if err := json.Unmarshal(segment, payload); err != nil {
+		return ErrInvalidSignedData
+	}
+
+	return nil
+}
+
+// leafKey returns the public key of the leaf certificate once the chain is verified up to the roots.
+func (v *Verifier) leafKey(token *jwt.Token) (interface{}, error) {
+	chain, _ := token.Header["x5c"].([]interface{})
+	if len(chain) != 3 {
+		return nil, ErrInvalidSignedData
+	}
+
+	certificates := []*x509.Certificate{}
+	for _, encoded := range chain {
+		value, _ := encoded.(string)
+
+		der, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, ErrInvalidSignedData
+		}
+
+		certificate, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, ErrInvalidSignedData
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	leaf, intermediate := certificates[0], certificates[1]
+
+	if !hasExtension(leaf, appStoreLeafOID) || !hasExtension(intermediate, appStoreIntermediateOID) {
+		return nil, ErrInvalidSignedData
+	}
+
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(intermediate)
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, ErrInvalidSignedData
+	}
+
+	publicKey, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, ErrInvalidSignedData
+	}
+
+	return publicKey, nil
+}
+
+func hasExtension(certificate *x509.Certificate, oid asn1.ObjectIdentifier) bool {
+	return slices.ContainsFunc(certificate.Extensions, func(extension pkix.Extension) bool {
+		return extension.Id.Equal(oid)
+	})
+}
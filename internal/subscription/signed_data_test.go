@@ -0,0 +1,107 @@
+package subscription_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/subscription"
+)
+
+/* Transaction of a monthly subscription purchased now */
+func newTestTransaction() domain.AppStoreTransaction {
+	now := time.Now()
+
+	return domain.AppStoreTransaction{
+		TransactionID:         "2000000000000002",
+		OriginalTransactionID: "2000000000000001",
+		BundleID:              "app.feynman",
+		ProductID:             "app.feynman.premium.monthly",
+		PurchaseDate:          now.UnixMilli(),
+		ExpiresDate:           now.AddDate(0, 1, 0).UnixMilli(),
+		Environment:           domain.AppStoreEnvironmentSandbox,
+		SignedDate:            now.UnixMilli(),
+	}
+}
+
+var _ = Describe("Verifier", func() {
+	var appStore *subscription.FakeAppStore
+
+	BeforeEach(func() {
+		var err error
+		appStore, err = subscription.NewFakeAppStore()
+		Expect(err).To(BeNil())
+	})
+
+	It("should verify the data signed with the App Store chain", func() {
+		// Arrange
+		transaction := newTestTransaction()
+		signed, err := appStore.Sign(transaction)
+		Expect(err).To(BeNil())
+
+		// Act
+		result, err := appStore.Verifier().VerifyTransaction(signed)
+
+		// Assert
+		Expect(err).To(BeNil())
+		Expect(*result).To(Equal(transaction))
+	})
+
+	It("should reject the data signed with a chain of another root", func() {
+		// Arrange
+		otherStore, err := subscription.NewFakeAppStore()
+		Expect(err).To(BeNil())
+		signed, err := otherStore.Sign(newTestTransaction())
+		Expect(err).To(BeNil())
+
+		// Act
+		_, err = appStore.Verifier().VerifyTransaction(signed)
+
+		// Assert
+		Expect(err).To(MatchError(subscription.ErrInvalidSignedData))
+	})
+
+	It("should reject a tampered payload", func() {
+		// Arrange
+		signed, err := appStore.Sign(newTestTransaction())
+		Expect(err).To(BeNil())
+
+		other := newTestTransaction()
+		other.ExpiresDate = time.Now().AddDate(10, 0, 0).UnixMilli()
+		otherSigned, err := appStore.Sign(other)
+		Expect(err).To(BeNil())
+
+		parts := strings.Split(signed, ".")
+		parts[1] = strings.Split(otherSigned, ".")[1]
+
+		// Act
+		_, err = appStore.Verifier().VerifyTransaction(strings.Join(parts, "."))
+
+		// Assert
+		Expect(err).To(MatchError(subscription.ErrInvalidSignedData))
+	})
+
+	It("should verify the notifications with their signed data", func() {
+		// Arrange
+		transaction := newTestTransaction()
+		signed, err := appStore.SignNotification("DID_RENEW", "", transaction, domain.AppStoreRenewalInfo{
+			OriginalTransactionID: transaction.OriginalTransactionID,
+			AutoRenewStatus:       1,
+		})
+		Expect(err).To(BeNil())
+
+		// Act
+		notification, err := appStore.Verifier().VerifyNotification(signed)
+		Expect(err).To(BeNil())
+		renewalInfo, renewalErr := appStore.Verifier().VerifyRenewalInfo(notification.Data.SignedRenewalInfo)
+
+		// Assert
+		Expect(notification.NotificationType).To(Equal("DID_RENEW"))
+		Expect(notification.Data.BundleID).To(Equal(transaction.BundleID))
+		Expect(renewalErr).To(BeNil())
+		Expect(renewalInfo.AutoRenewStatus).To(Equal(1))
+	})
+})
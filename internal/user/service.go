@@ -1,18 +1,42 @@
 package user
 
 import (
-	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/pietro-putelli/feynman-backend/internal/domain"
 	"github.com/pietro-putelli/feynman-backend/internal/utility"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var _ Service = (*serviceImpl)(nil)
 
+var (
+	// ErrInvalidRefreshToken is returned when the refresh token is unknown, expired or its session is no longer valid.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is used again, the whole session is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+
+	// ErrEmailAlreadyRegistered is returned when signing in with an unknown identity whose email belongs to another account.
+	ErrEmailAlreadyRegistered = errors.New("email already registered with another provider")
+
+	// ErrIdentityLinkedToAnotherUser is returned when the identity to link already belongs to another account.
+	ErrIdentityLinkedToAnotherUser = errors.New("identity already linked to another account")
+
+	// ErrProviderAlreadyLinked is returned when the user has already linked another account of the same provider.
+	ErrProviderAlreadyLinked = errors.New("provider already linked")
+
+	// ErrLastIdentity is returned when unlinking the only identity left to sign in with.
+	ErrLastIdentity = errors.New("cannot unlink the last identity")
+
+	// ErrInvalidSettings is returned when the settings patch is not a JSON object, has unknown fields or invalid values.
+	ErrInvalidSettings = errors.New("invalid settings")
+)
+
 // Service represents the user service.
 //
 //go:generate mockgen -source=service.go -destination=./service_mock.go -package=user
@@ -20,8 +44,21 @@ type Service interface {
 	GetUserByGuid(guid uuid.UUID) (*domain.User, error)
 	CreateUserIfNotExists(user *domain.ThirdPartyUser) (*domain.User, uuid.UUID, error)
 	UpdateUserProfile(userID uuid.UUID, data *domain.UserProfileUpdate) error
+	GetUserSettings(userID uuid.UUID) (*domain.UserSettings, error)
+	PatchUserSettings(userID uuid.UUID, patch []byte) (*domain.UserSettings, error)
 	CheckProfileHealth(userID uuid.UUID) (*domain.UserHealth, error)
 	DeleteUserProfile(userID uuid.UUID) error
+	CreateRefreshToken(sessionID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	RotateRefreshToken(sessionID uuid.UUID, tokenHash string, nextTokenHash string, expiresAt time.Time) (*domain.User, error)
+	MigrateLegacyRefreshToken(userID uuid.UUID, tokenHash string, nextTokenHash string, expiresAt time.Time) (*domain.User, uuid.UUID, error)
+	IsSessionActive(userID uuid.UUID, sessionID uuid.UUID) (bool, error)
+	CreateSession(userID uuid.UUID, deviceID string) (uuid.UUID, error)
+	GetIdentities(userID uuid.UUID) ([]domain.UserIdentityResponse, error)
+	LinkIdentity(userID uuid.UUID, identity *domain.ThirdPartyUser) (*domain.UserIdentityResponse, error)
+	UnlinkIdentity(userID uuid.UUID, provider string) error
+	RevokeIdentitySessions(provider string, subject string) error
+	DeleteIdentity(provider string, subject string) error
+	SetIdentityEmailEnabled(provider string, subject string, email string, isEnabled bool) error
 }
 
 // serviceImpl represents the user service implementation.
@@ -54,7 +91,24 @@ func (s *serviceImpl) CreateUserIfNotExists(user *domain.ThirdPartyUser) (*domai
 	defer logger.Sync()
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
-		if err := s.db.Where("email = ?", user.Email).First(&responseUser).Error; err != nil {
+		/* Users are found by the subject of the provider, the email may change or be an Apple private relay address */
+		err := findUserByIdentity(tx, user, &responseUser)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Error("Failed to get user identity", zap.Error(err))
+			return err
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			/* The email of an unknown identity belonging to an existing account, the identity has to be linked explicitly */
+			var count int64
+			if err := tx.Model(&domain.User{}).Where("email = ?", user.Email).Count(&count).Error; err != nil {
+				return err
+			}
+
+			if count > 0 {
+				return ErrEmailAlreadyRegistered
+			}
+
 			responseUser = domain.User{
 				Email:      user.Email,
 				GivenName:  user.GivenName,
@@ -65,11 +119,21 @@ func (s *serviceImpl) CreateUserIfNotExists(user *domain.ThirdPartyUser) (*domai
 				IsCreated:  true,
 			}
 
-			if err := s.db.Create(&responseUser).Error; err != nil {
+			if err := tx.Create(&responseUser).Error; err != nil {
 				logger.Error("Failed to create user", zap.Error(err))
 				return err
 			}
 
+			if err := tx.Create(&domain.UserIdentity{
+				UserID:   responseUser.ID,
+				Provider: user.Provider,
+				Subject:  user.Sub,
+				Email:    user.Email,
+			}).Error; err != nil {
+				logger.Error("Failed to create user identity", zap.Error(err))
+				return err
+			}
+
 			/* New user has been created, copy the sample books to gettings started */
 			err := utility.CopyBookSamplesToUser(responseUser.ID, tx)
 			if err != nil {
@@ -79,14 +143,18 @@ func (s *serviceImpl) CreateUserIfNotExists(user *domain.ThirdPartyUser) (*domai
 
 			utility.TelegramSendNewUser(&responseUser)
 
+			device := domain.NewSessionDevice(user.DeviceID, user.DeviceToken, user.DevicePlatform, user.DeviceEnvironment)
+
 			newSession := domain.Session{
 				UserID:   responseUser.ID,
-				DeviceID: user.DeviceID,
+				DeviceID: device.ID,
 				// DeviceToken can be empty (not null) if the user has not granted notification permission
-				DeviceToken: user.DeviceToken,
+				DeviceToken:     device.Token,
+				Platform:        device.Platform,
+				PushEnvironment: device.Environment,
 			}
 
-			if err := s.db.Create(&newSession).Error; err != nil {
+			if err := tx.Create(&newSession).Error; err != nil {
 				logger.Error("Failed to create new session", zap.Error(err))
 				return err
 			}
@@ -96,149 +164,496 @@ func (s *serviceImpl) CreateUserIfNotExists(user *domain.ThirdPartyUser) (*domai
 			return nil
 		}
 
-		/* If the user already exists, update existing and not expired session or create a new session */
-		var existingSession domain.Session
-		err := s.db.Model(&domain.Session{}).Where("user_id = ? AND expired_at = ?", responseUser.ID, "0001-01-01 00:00:00").First(&existingSession).Error
+		/* If the user already exists, update the not expired session of the device or create a new one, there is one session per device */
+		device := domain.NewSessionDevice(user.DeviceID, user.DeviceToken, user.DevicePlatform, user.DeviceEnvironment)
 
+		sessionID, err = upsertDeviceSession(tx, responseUser.ID, device)
 		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				newSession := domain.Session{
-					UserID:      responseUser.ID,
-					DeviceID:    user.DeviceID,
-					DeviceToken: user.DeviceToken,
-				}
-
-				if err := s.db.Create(&newSession).Error; err != nil {
-					logger.Error("Failed to create new session", zap.Error(err))
-					return err
-				}
-
-				sessionID = newSession.Guid
-
-			} else {
-				logger.Error("Failed to get existing session", zap.Error(err))
-				return err
-			}
-		} else {
-			updateData := map[string]interface{}{
-				"device_id":    user.DeviceID,
-				"device_token": user.DeviceToken,
-			}
-
-			if err := s.db.Model(&domain.Session{}).Where("guid = ?", existingSession.Guid).Updates(updateData).Error; err != nil {
-				logger.Error("Failed to update existing session", zap.Error(err))
-				return err
-			}
-
-			sessionID = existingSession.Guid
+			return err
 		}
 
 		return nil
 	})
+	if err != nil {
+		return nil, uuid.UUID{}, err
+	}
 
-	return &responseUser, sessionID, err
+	return &responseUser, sessionID, nil
 }
 
-// UpdateUserProfile changes a restricted set of user profile fields.
-
+// UpdateUserProfile changes a restricted set of user profile fields, the settings are merged like PatchUserSettings.
 func (s *serviceImpl) UpdateUserProfile(userID uuid.UUID, data *domain.UserProfileUpdate) error {
+	if len(data.Settings) == 0 {
+		return nil
+	}
 
-	fieldsToUpdate := map[string]interface{}{}
+	_, err := s.PatchUserSettings(userID, data.Settings)
+	return err
+}
 
-	jsonSettings, err := json.Marshal(data.Settings)
-	if err == nil {
-		fieldsToUpdate["settings"] = string(jsonSettings)
-		fieldsToUpdate["is_notification_enabled"] = data.Settings.NotificationEnabled
+// CheckProfileHealth checks the user is still valid and the user subscription status.
+func (s *serviceImpl) CheckProfileHealth(userID uuid.UUID) (*domain.UserHealth, error) {
+	user, err := s.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
 	}
 
-	if data.SubscriptionReceiptID != "" {
-		fieldsToUpdate["subscription_receipt_id"] = data.SubscriptionReceiptID
+	response := &domain.UserHealth{
+		IsPremium: false,
+		IsHealthy: false,
 	}
 
-	if err := s.db.Model(&domain.User{}).Where("guid = ?", userID).Updates(fieldsToUpdate); err != nil {
-		return err.Error
+	if !user.IsActive {
+		return response, nil
 	}
 
-	return nil
+	response.IsHealthy = true
+	/* The premium status is kept in sync with the subscriptions verified with the App Store */
+	response.IsPremium = user.IsPremium(time.Now())
+
+	return response, nil
 }
 
-// CheckProfileHealth checks the user is still valid and the user subscription status.
-func (s *serviceImpl) CheckProfileHealth(userID uuid.UUID) (*domain.UserHealth, error) {
+/* DeleteUserProfile deletes the user profile and all related data */
+func (service *serviceImpl) DeleteUserProfile(userID uuid.UUID) error {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
 
+	/* Sessions and their refresh tokens are deleted in cascade */
+	err := service.db.Table("users").Where("guid = ?", userID).Delete(&domain.User{}).Error
+
+	if err != nil {
+		logger.Error("Failed to delete user profile", zap.Error(err))
+	}
+
+	return err
+}
+
+// CreateRefreshToken stores the hash of a refresh token issued for the session.
+func (s *serviceImpl) CreateRefreshToken(sessionID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	session := domain.Session{}
+	if err := s.db.Where("guid = ?", sessionID).First(&session).Error; err != nil {
+		return err
+	}
+
+	return s.db.Create(&domain.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+// RotateRefreshToken replaces the refresh token of the session with the next one and returns the session user.
+// The session must not be expired and the user must be active, reusing a rotated token revokes the session.
+func (s *serviceImpl) RotateRefreshToken(sessionID uuid.UUID, tokenHash string, nextTokenHash string, expiresAt time.Time) (*domain.User, error) {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
-	user, err := s.GetUserByGuid(userID)
+	var user domain.User
+	var session domain.Session
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("guid = ? AND expired_at = ?", sessionID, "0001-01-01 00:00:00").First(&session).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		} else if err != nil {
+			return err
+		}
+
+		err = tx.Where("id = ? AND is_active = ?", session.UserID, true).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		} else if err != nil {
+			return err
+		}
+
+		token := domain.RefreshToken{}
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("session_id = ? AND token_hash = ?", session.ID, tokenHash).First(&token).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		} else if err != nil {
+			return err
+		}
+
+		if token.RotatedAt != nil || token.RevokedAt != nil {
+			return ErrRefreshTokenReused
+		}
+
+		now := time.Now()
+		if token.ExpiresAt.Before(now) {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := tx.Model(&domain.RefreshToken{}).Where("id = ?", token.ID).Update("rotated_at", now).Error; err != nil {
+			return err
+		}
+
+		/* The refresh is the last time the device has been seen */
+		if err := tx.Model(&domain.Session{}).Where("id = ?", session.ID).Update("updated_at", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&domain.RefreshToken{
+			SessionID: session.ID,
+			TokenHash: nextTokenHash,
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+
+	if errors.Is(err, ErrRefreshTokenReused) {
+		/* The token family is compromised, revoke the session outside the rolled back transaction */
+		logger.Warn("Refresh token reused, revoking session", zap.String("session", sessionID.String()))
+
+		if err := revokeSession(s.db, session.ID); err != nil {
+			logger.Error("Failed to revoke session", zap.Error(err))
+		}
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
-	response := &domain.UserHealth{
-		IsPremium: true,
-		IsHealthy: false,
+	return &user, nil
+}
+
+// MigrateLegacyRefreshToken exchanges a refresh token issued before the sessions for a new session of the user.
+// The legacy token is stored as rotated, so it can be exchanged only once.
+func (s *serviceImpl) MigrateLegacyRefreshToken(userID uuid.UUID, tokenHash string, nextTokenHash string, expiresAt time.Time) (*domain.User, uuid.UUID, error) {
+	var user domain.User
+	var session domain.Session
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("guid = ? AND is_active = ?", userID, true).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		} else if err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&domain.RefreshToken{}).Where("token_hash = ?", tokenHash).Count(&count).Error; err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return ErrRefreshTokenReused
+		}
+
+		/* Legacy clients do not send the device on refresh, the session has no device until it registers again */
+		session = domain.Session{UserID: user.ID}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		tokens := []domain.RefreshToken{
+			{SessionID: session.ID, TokenHash: tokenHash, ExpiresAt: now, RotatedAt: &now},
+			{SessionID: session.ID, TokenHash: nextTokenHash, ExpiresAt: expiresAt},
+		}
+
+		return tx.Create(&tokens).Error
+	})
+	if err != nil {
+		return nil, uuid.UUID{}, err
 	}
 
-	if !user.IsActive {
-		return response, nil
+	return &user, session.Guid, nil
+}
+
+// IsSessionActive checks the session of the user is not expired and the user is still active.
+func (s *serviceImpl) IsSessionActive(userID uuid.UUID, sessionID uuid.UUID) (bool, error) {
+	var count int64
+
+	err := s.db.Table("sessions AS s").
+		Joins("JOIN users u ON u.id = s.user_id").
+		Where("s.guid = ? AND u.guid = ? AND s.expired_at = ? AND u.is_active = ?", sessionID, userID, "0001-01-01 00:00:00", true).
+		Count(&count).Error
+	if err != nil {
+		return false, err
 	}
 
-	response.IsHealthy = true
+	return count > 0, nil
+}
 
-	// if user.SubscriptionReceiptID != "" {
+// CreateSession returns the active session of the device of the user, a new one is created when there is none.
+func (s *serviceImpl) CreateSession(userID uuid.UUID, deviceID string) (uuid.UUID, error) {
+	user, err := s.GetUserByGuid(userID)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return upsertDeviceSession(s.db, user.ID, domain.NewSessionDevice(deviceID, "", "", ""))
+}
+
+/* Update the device token of the not expired session of the device or create a new one */
+func upsertDeviceSession(db *gorm.DB, userID uint, device *domain.SessionDevice) (uuid.UUID, error) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	var existingSession domain.Session
+	err := db.Model(&domain.Session{}).Where("user_id = ? AND device_id = ? AND expired_at = ?", userID, device.ID, "0001-01-01 00:00:00").First(&existingSession).Error
+
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Error("Failed to get existing session", zap.Error(err))
+			return uuid.UUID{}, err
+		}
+
+		newSession := domain.Session{
+			UserID:          userID,
+			DeviceID:        device.ID,
+			DeviceToken:     device.Token,
+			Platform:        device.Platform,
+			PushEnvironment: device.Environment,
+		}
+
+		if err := db.Create(&newSession).Error; err != nil {
+			logger.Error("Failed to create new session", zap.Error(err))
+			return uuid.UUID{}, err
+		}
 
-	// 	/* Check the receipt validity against Apple Server */
-	// 	ctx, err := NewContext()
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
+		return newSession.Guid, nil
+	}
 
-	// 	appleConfig := ctx.Config.Apple
+	updateData := map[string]interface{}{
+		"device_token":     device.Token,
+		"platform":         device.Platform,
+		"push_environment": device.Environment,
+		"updated_at":       time.Now(),
+	}
 
-	// 	// https://github.com/awa/go-iap?tab=readme-ov-file#in-app-store-server-api
+	/* A new device token starts again with the push notifications enabled */
+	if device.Token != existingSession.DeviceToken {
+		updateData["push_failures"] = 0
+		updateData["push_disabled_at"] = nil
+	}
 
-	// 	config := &api.StoreConfig{
-	// 		KeyContent: []byte(appleConfig.IAPCertificate),
-	// 		KeyID:      appleConfig.IAPCertificateKey,
-	// 		BundleID:   appleConfig.AppBundleId,
-	// 		Issuer:     appleConfig.IssuerId,
-	// 		Sandbox:    true,
-	// 	}
+	if err := db.Model(&domain.Session{}).Where("guid = ?", existingSession.Guid).Updates(updateData).Error; err != nil {
+		logger.Error("Failed to update existing session", zap.Error(err))
+		return uuid.UUID{}, err
+	}
 
-	// 	transactionId := user.SubscriptionReceiptID
-	// 	storeClient := api.NewStoreClient(config)
+	return existingSession.Guid, nil
+}
 
-	// 	ctx1 := context.Background()
+/* Find the user of the provider subject, the email given by the provider is kept up to date */
+func findUserByIdentity(tx *gorm.DB, user *domain.ThirdPartyUser, responseUser *domain.User) error {
+	identity := domain.UserIdentity{}
+	if err := tx.Where("provider = ? AND subject = ?", user.Provider, user.Sub).First(&identity).Error; err != nil {
+		return err
+	}
 
-	// 	transactionResponse, err := storeClient.GetTransactionInfo(ctx1, transactionId)
-	// 	if err != nil {
-	// 		logger.Error("Failed to get transaction info", zap.Error(err))
-	// 		return nil, err
-	// 	}
+	if user.Email != "" && user.Email != identity.Email {
+		if err := tx.Model(&domain.UserIdentity{}).Where("id = ?", identity.ID).Update("email", user.Email).Error; err != nil {
+			return err
+		}
+	}
 
-	// 	transaction, err := storeClient.ParseSignedTransaction(transactionResponse.SignedTransactionInfo)
-	// 	if err != nil {
-	// 		logger.Error("Failed to parse signed transaction", zap.Error(err))
-	// 		return nil, err
-	// 	}
+	return tx.Where("id = ?", identity.UserID).First(responseUser).Error
+}
 
-	// 	if transaction.OriginalTransactionId == transactionId {
-	// 		response.IsPremium = true
-	// 	}
-	// }
+// GetIdentities lists the provider accounts the user can sign in with.
+func (s *serviceImpl) GetIdentities(userID uuid.UUID) ([]domain.UserIdentityResponse, error) {
+	user, err := s.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	identities := []domain.UserIdentity{}
+	if err := s.db.Where("user_id = ?", user.ID).Order("created_at ASC").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]domain.UserIdentityResponse, len(identities))
+	for i := range identities {
+		response[i] = domain.UserIdentityResponseFromModel(&identities[i])
+	}
 
 	return response, nil
 }
 
-/* DeleteUserProfile deletes the user profile and all related data */
-func (service *serviceImpl) DeleteUserProfile(userID uuid.UUID) error {
-	logger, _ := zap.NewProduction()
-	defer logger.Sync()
+// LinkIdentity links a provider account to the user, linking the same account again has no effect.
+func (s *serviceImpl) LinkIdentity(userID uuid.UUID, thirdPartyUser *domain.ThirdPartyUser) (*domain.UserIdentityResponse, error) {
+	user, err := s.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
 
-	err := service.db.Table("users").Where("guid = ?", userID).Delete(&domain.User{}).Error
+	identity := domain.UserIdentity{}
 
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("provider = ? AND subject = ?", thirdPartyUser.Provider, thirdPartyUser.Sub).First(&identity).Error
+		if err == nil {
+			if identity.UserID != user.ID {
+				return ErrIdentityLinkedToAnotherUser
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&domain.UserIdentity{}).Where("user_id = ? AND provider = ?", user.ID, thirdPartyUser.Provider).Count(&count).Error; err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return ErrProviderAlreadyLinked
+		}
+
+		identity = domain.UserIdentity{
+			UserID:   user.ID,
+			Provider: thirdPartyUser.Provider,
+			Subject:  thirdPartyUser.Sub,
+			Email:    thirdPartyUser.Email,
+		}
+
+		return tx.Create(&identity).Error
+	})
 	if err != nil {
-		logger.Error("Failed to delete user profile", zap.Error(err))
+		return nil, err
 	}
 
-	return err
+	response := domain.UserIdentityResponseFromModel(&identity)
+	return &response, nil
+}
+
+// UnlinkIdentity unlinks the provider account from the user, at least one identity is always kept.
+func (s *serviceImpl) UnlinkIdentity(userID uuid.UUID, provider string) error {
+	user, err := s.GetUserByGuid(userID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return unlinkIdentity(tx, user, provider)
+	})
+}
+
+// RevokeIdentitySessions expires all the sessions of the user of the provider subject,
+// e.g. when the user stops using Sign in with Apple for the app.
+// Sessions do not record the identity they were created with, so they are kept while another identity is linked.
+func (s *serviceImpl) RevokeIdentitySessions(provider string, subject string) error {
+	identity := domain.UserIdentity{}
+	if err := s.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&domain.UserIdentity{}).Where("user_id = ? AND provider <> ?", identity.UserID, provider).Count(&count).Error; err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return nil
+		}
+
+		now := time.Now()
+		sessionIDs := tx.Model(&domain.Session{}).Select("id").Where("user_id = ?", identity.UserID)
+
+		if err := tx.Model(&domain.RefreshToken{}).Where("session_id IN (?) AND revoked_at IS NULL", sessionIDs).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&domain.Session{}).Where("user_id = ? AND expired_at = ?", identity.UserID, "0001-01-01 00:00:00").Update("expired_at", now).Error
+	})
+}
+
+// DeleteIdentity deletes the provider account, the user is deleted too when it was the only identity left.
+func (s *serviceImpl) DeleteIdentity(provider string, subject string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		identity := domain.UserIdentity{}
+		if err := tx.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+			return err
+		}
+
+		user := domain.User{}
+		if err := tx.Where("id = ?", identity.UserID).First(&user).Error; err != nil {
+			return err
+		}
+
+		err := unlinkIdentity(tx, &user, provider)
+		if errors.Is(err, ErrLastIdentity) {
+			/* Sessions, refresh tokens and identities are deleted in cascade */
+			return tx.Delete(&domain.User{}, user.ID).Error
+		}
+
+		return err
+	})
+}
+
+// SetIdentityEmailEnabled records whether the provider forwards emails to the user, e.g. to an Apple private relay address.
+func (s *serviceImpl) SetIdentityEmailEnabled(provider string, subject string, email string, isEnabled bool) error {
+	updates := map[string]interface{}{
+		"email_enabled": isEnabled,
+		"updated_at":    time.Now(),
+	}
+	if email != "" {
+		updates["email"] = email
+	}
+
+	result := s.db.Model(&domain.UserIdentity{}).Where("provider = ? AND subject = ?", provider, subject).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+/* Delete the identity of the provider, the sign up identity stored on the user is moved to a remaining one */
+func unlinkIdentity(tx *gorm.DB, user *domain.User, provider string) error {
+	identities := []domain.UserIdentity{}
+	if err := tx.Where("user_id = ?", user.ID).Order("created_at ASC").Find(&identities).Error; err != nil {
+		return err
+	}
+
+	var unlinked *domain.UserIdentity
+	var remaining *domain.UserIdentity
+	for i := range identities {
+		if identities[i].Provider == provider {
+			unlinked = &identities[i]
+		} else if remaining == nil {
+			remaining = &identities[i]
+		}
+	}
+
+	if unlinked == nil {
+		return gorm.ErrRecordNotFound
+	}
+
+	if remaining == nil {
+		return ErrLastIdentity
+	}
+
+	if err := tx.Delete(&domain.UserIdentity{}, unlinked.ID).Error; err != nil {
+		return err
+	}
+
+	if user.Provider == unlinked.Provider && user.ExternalID == unlinked.Subject {
+		return tx.Model(&domain.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"provider":    remaining.Provider,
+			"external_id": remaining.Subject,
+		}).Error
+	}
+
+	return nil
+}
+
+/* Expire the session and revoke all its refresh tokens */
+func revokeSession(db *gorm.DB, sessionID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		if err := tx.Model(&domain.Session{}).Where("id = ?", sessionID).Update("expired_at", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&domain.RefreshToken{}).Where("session_id = ? AND revoked_at IS NULL", sessionID).Update("revoked_at", now).Error
+	})
 }
@@ -11,9 +11,10 @@ package user
 
 import (
 	reflect "reflect"
+	time "time"
 
-	domain "github.com/pietro-putelli/feynman-backend/internal/domain"
 	uuid "github.com/google/uuid"
+	domain "github.com/pietro-putelli/feynman-backend/internal/domain"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -40,13 +41,58 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// CheckProfileHealth mocks base method.
+func (m *MockService) CheckProfileHealth(userID uuid.UUID) (*domain.UserHealth, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckProfileHealth", userID)
+	ret0, _ := ret[0].(*domain.UserHealth)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckProfileHealth indicates an expected call of CheckProfileHealth.
+func (mr *MockServiceMockRecorder) CheckProfileHealth(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckProfileHealth", reflect.TypeOf((*MockService)(nil).CheckProfileHealth), userID)
+}
+
+// CreateRefreshToken mocks base method.
+func (m *MockService) CreateRefreshToken(sessionID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRefreshToken", sessionID, tokenHash, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRefreshToken indicates an expected call of CreateRefreshToken.
+func (mr *MockServiceMockRecorder) CreateRefreshToken(sessionID, tokenHash, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRefreshToken", reflect.TypeOf((*MockService)(nil).CreateRefreshToken), sessionID, tokenHash, expiresAt)
+}
+
+// CreateSession mocks base method.
+func (m *MockService) CreateSession(userID uuid.UUID, deviceID string) (uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", userID, deviceID)
+	ret0, _ := ret[0].(uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockServiceMockRecorder) CreateSession(userID, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockService)(nil).CreateSession), userID, deviceID)
+}
+
 // CreateUserIfNotExists mocks base method.
-func (m *MockService) CreateUserIfNotExists(user *domain.ThirdPartyUser) (*domain.User, error) {
+func (m *MockService) CreateUserIfNotExists(user *domain.ThirdPartyUser) (*domain.User, uuid.UUID, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateUserIfNotExists", user)
 	ret0, _ := ret[0].(*domain.User)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(uuid.UUID)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // CreateUserIfNotExists indicates an expected call of CreateUserIfNotExists.
@@ -55,6 +101,49 @@ func (mr *MockServiceMockRecorder) CreateUserIfNotExists(user any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserIfNotExists", reflect.TypeOf((*MockService)(nil).CreateUserIfNotExists), user)
 }
 
+// DeleteIdentity mocks base method.
+func (m *MockService) DeleteIdentity(provider, subject string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdentity", provider, subject)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIdentity indicates an expected call of DeleteIdentity.
+func (mr *MockServiceMockRecorder) DeleteIdentity(provider, subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdentity", reflect.TypeOf((*MockService)(nil).DeleteIdentity), provider, subject)
+}
+
+// DeleteUserProfile mocks base method.
+func (m *MockService) DeleteUserProfile(userID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserProfile", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserProfile indicates an expected call of DeleteUserProfile.
+func (mr *MockServiceMockRecorder) DeleteUserProfile(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserProfile", reflect.TypeOf((*MockService)(nil).DeleteUserProfile), userID)
+}
+
+// GetIdentities mocks base method.
+func (m *MockService) GetIdentities(userID uuid.UUID) ([]domain.UserIdentityResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdentities", userID)
+	ret0, _ := ret[0].([]domain.UserIdentityResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdentities indicates an expected call of GetIdentities.
+func (mr *MockServiceMockRecorder) GetIdentities(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdentities", reflect.TypeOf((*MockService)(nil).GetIdentities), userID)
+}
+
 // GetUserByGuid mocks base method.
 func (m *MockService) GetUserByGuid(guid uuid.UUID) (*domain.User, error) {
 	m.ctrl.T.Helper()
@@ -69,3 +158,150 @@ func (mr *MockServiceMockRecorder) GetUserByGuid(guid any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByGuid", reflect.TypeOf((*MockService)(nil).GetUserByGuid), guid)
 }
+
+// GetUserSettings mocks base method.
+func (m *MockService) GetUserSettings(userID uuid.UUID) (*domain.UserSettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserSettings", userID)
+	ret0, _ := ret[0].(*domain.UserSettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSettings indicates an expected call of GetUserSettings.
+func (mr *MockServiceMockRecorder) GetUserSettings(userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSettings", reflect.TypeOf((*MockService)(nil).GetUserSettings), userID)
+}
+
+// IsSessionActive mocks base method.
+func (m *MockService) IsSessionActive(userID, sessionID uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSessionActive", userID, sessionID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsSessionActive indicates an expected call of IsSessionActive.
+func (mr *MockServiceMockRecorder) IsSessionActive(userID, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSessionActive", reflect.TypeOf((*MockService)(nil).IsSessionActive), userID, sessionID)
+}
+
+// LinkIdentity mocks base method.
+func (m *MockService) LinkIdentity(userID uuid.UUID, identity *domain.ThirdPartyUser) (*domain.UserIdentityResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkIdentity", userID, identity)
+	ret0, _ := ret[0].(*domain.UserIdentityResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkIdentity indicates an expected call of LinkIdentity.
+func (mr *MockServiceMockRecorder) LinkIdentity(userID, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkIdentity", reflect.TypeOf((*MockService)(nil).LinkIdentity), userID, identity)
+}
+
+// MigrateLegacyRefreshToken mocks base method.
+func (m *MockService) MigrateLegacyRefreshToken(userID uuid.UUID, tokenHash, nextTokenHash string, expiresAt time.Time) (*domain.User, uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateLegacyRefreshToken", userID, tokenHash, nextTokenHash, expiresAt)
+	ret0, _ := ret[0].(*domain.User)
+	ret1, _ := ret[1].(uuid.UUID)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// MigrateLegacyRefreshToken indicates an expected call of MigrateLegacyRefreshToken.
+func (mr *MockServiceMockRecorder) MigrateLegacyRefreshToken(userID, tokenHash, nextTokenHash, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateLegacyRefreshToken", reflect.TypeOf((*MockService)(nil).MigrateLegacyRefreshToken), userID, tokenHash, nextTokenHash, expiresAt)
+}
+
+// PatchUserSettings mocks base method.
+func (m *MockService) PatchUserSettings(userID uuid.UUID, patch []byte) (*domain.UserSettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchUserSettings", userID, patch)
+	ret0, _ := ret[0].(*domain.UserSettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PatchUserSettings indicates an expected call of PatchUserSettings.
+func (mr *MockServiceMockRecorder) PatchUserSettings(userID, patch any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchUserSettings", reflect.TypeOf((*MockService)(nil).PatchUserSettings), userID, patch)
+}
+
+// RevokeIdentitySessions mocks base method.
+func (m *MockService) RevokeIdentitySessions(provider, subject string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeIdentitySessions", provider, subject)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeIdentitySessions indicates an expected call of RevokeIdentitySessions.
+func (mr *MockServiceMockRecorder) RevokeIdentitySessions(provider, subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeIdentitySessions", reflect.TypeOf((*MockService)(nil).RevokeIdentitySessions), provider, subject)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockService) RotateRefreshToken(sessionID uuid.UUID, tokenHash, nextTokenHash string, expiresAt time.Time) (*domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", sessionID, tokenHash, nextTokenHash, expiresAt)
+	ret0, _ := ret[0].(*domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockServiceMockRecorder) RotateRefreshToken(sessionID, tokenHash, nextTokenHash, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockService)(nil).RotateRefreshToken), sessionID, tokenHash, nextTokenHash, expiresAt)
+}
+
+// SetIdentityEmailEnabled mocks base method.
+func (m *MockService) SetIdentityEmailEnabled(provider, subject, email string, isEnabled bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetIdentityEmailEnabled", provider, subject, email, isEnabled)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetIdentityEmailEnabled indicates an expected call of SetIdentityEmailEnabled.
+func (mr *MockServiceMockRecorder) SetIdentityEmailEnabled(provider, subject, email, isEnabled any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIdentityEmailEnabled", reflect.TypeOf((*MockService)(nil).SetIdentityEmailEnabled), provider, subject, email, isEnabled)
+}
+
+// UnlinkIdentity mocks base method.
+func (m *MockService) UnlinkIdentity(userID uuid.UUID, provider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlinkIdentity", userID, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlinkIdentity indicates an expected call of UnlinkIdentity.
+func (mr *MockServiceMockRecorder) UnlinkIdentity(userID, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlinkIdentity", reflect.TypeOf((*MockService)(nil).UnlinkIdentity), userID, provider)
+}
+
+// UpdateUserProfile mocks base method.
+func (m *MockService) UpdateUserProfile(userID uuid.UUID, data *domain.UserProfileUpdate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserProfile", userID, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserProfile indicates an expected call of UpdateUserProfile.
+func (mr *MockServiceMockRecorder) UpdateUserProfile(userID, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserProfile", reflect.TypeOf((*MockService)(nil).UpdateUserProfile), userID, data)
+}
@@ -1,6 +1,8 @@
 package user_test
 
 import (
+	"time"
+
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo/v2"
@@ -31,6 +33,9 @@ var _ = Describe("Service", func() {
 			sqlMock sqlmock.Sqlmock
 		)
 
+		identityColumns := []string{"id", "user_id", "provider", "subject", "email"}
+		expectedIdentitySelect := `SELECT \* FROM "user_identities" WHERE provider = \$1 AND subject = \$2`
+
 		BeforeEach(func() {
 			db, sqlMockGen, _ := sqlmock.New()
 			sqlMock = sqlMockGen
@@ -45,103 +50,394 @@ var _ = Describe("Service", func() {
 
 		It("should create a new user", func() {
 			// Arrange
-			user := &domain.ThirdPartyUser{
+			thirdPartyUser := &domain.ThirdPartyUser{
 				Email:      "test@test.com",
 				Sub:        "test123",
 				GivenName:  "Given",
 				FamilyName: "Family",
 				Provider:   "google",
+				DeviceID:   "device",
 			}
 
-			expectedSelect := "^SELECT (.+) FROM \"users\" WHERE email = (.+)$"
-			sqlMock.ExpectQuery(expectedSelect).WillReturnError(gorm.ErrRecordNotFound)
 			sqlMock.ExpectBegin()
-			sqlMock.ExpectQuery("^INSERT INTO \"users\" (.+) RETURNING (.+)$").
-				WithArgs(user.Sub, user.Email, user.GivenName, user.FamilyName, user.Provider, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
-				WillReturnRows(sqlMock.NewRows([]string{"id"}).AddRow(1))
+			sqlMock.ExpectQuery(expectedIdentitySelect).
+				WithArgs(thirdPartyUser.Provider, thirdPartyUser.Sub, 1).
+				WillReturnRows(sqlmock.NewRows(identityColumns))
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "users" WHERE email = \$1`).
+				WithArgs(thirdPartyUser.Email).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			sqlMock.ExpectQuery(`INSERT INTO "users" (.+) RETURNING`).
+				WillReturnRows(sqlmock.NewRows([]string{"guid", "id"}).AddRow(uuid.New(), 1))
+			sqlMock.ExpectQuery(`INSERT INTO "user_identities" (.+) RETURNING`).
+				WillReturnRows(sqlmock.NewRows([]string{"guid", "id"}).AddRow(uuid.New(), 1))
+			sqlMock.ExpectQuery(`SELECT \* FROM "books" WHERE user_id IS NULL`).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			sqlMock.ExpectQuery(`INSERT INTO "sessions" (.+) RETURNING`).
+				WillReturnRows(sqlmock.NewRows([]string{"guid", "id"}).AddRow(uuid.New(), 1))
 			sqlMock.ExpectCommit()
 
 			// Act
-			result, sessionID, err := service.CreateUserIfNotExists(user)
+			result, sessionID, err := service.CreateUserIfNotExists(thirdPartyUser)
 
 			// Assert
 			Expect(err).To(BeNil())
 			Expect(result).NotTo(BeNil())
-			Expect(sessionID).NotTo(BeEmpty())
-			Expect(result.Email).To(Equal(user.Email))
-			Expect(result.GivenName).To(Equal(user.GivenName))
-			Expect(result.FamilyName).To(Equal(user.FamilyName))
+			Expect(sessionID).NotTo(Equal(uuid.Nil))
+			Expect(result.IsCreated).To(BeTrue())
+			Expect(result.Email).To(Equal(thirdPartyUser.Email))
+			Expect(result.GivenName).To(Equal(thirdPartyUser.GivenName))
+			Expect(result.FamilyName).To(Equal(thirdPartyUser.FamilyName))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
 		})
 
-		It("should return an existing user", func() {
+		It("should return the user of an existing identity", func() {
 			// Arrange
-			user := &domain.ThirdPartyUser{
+			thirdPartyUser := &domain.ThirdPartyUser{
 				Email:      "test@test.com",
 				Sub:        "test123",
 				GivenName:  "Given",
 				FamilyName: "Family",
+				Provider:   "apple",
+				DeviceID:   "device",
 			}
 
-			// return user
-			expectedSelect := "^SELECT (.+) FROM \"users\" WHERE email = (.+)$"
-			sqlMock.ExpectQuery(expectedSelect).
-				WithArgs(user.Email, 1).
-				WillReturnRows(sqlMock.NewRows([]string{"id", "email", "given_name", "family_name", "external_id"}).
-					AddRow(1, user.Email, user.GivenName, user.FamilyName, user.Sub))
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(expectedIdentitySelect).
+				WithArgs(thirdPartyUser.Provider, thirdPartyUser.Sub, 1).
+				WillReturnRows(sqlmock.NewRows(identityColumns).AddRow(1, 1, thirdPartyUser.Provider, thirdPartyUser.Sub, thirdPartyUser.Email))
+			sqlMock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+				WithArgs(1, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "given_name", "family_name", "external_id"}).
+					AddRow(1, thirdPartyUser.Email, thirdPartyUser.GivenName, thirdPartyUser.FamilyName, thirdPartyUser.Sub))
+			sqlMock.ExpectQuery(`SELECT \* FROM "sessions" WHERE user_id = \$1 AND device_id = \$2 AND expired_at = \$3`).
+				WithArgs(1, thirdPartyUser.DeviceID, "0001-01-01 00:00:00", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			sqlMock.ExpectQuery(`INSERT INTO "sessions" (.+) RETURNING`).
+				WillReturnRows(sqlmock.NewRows([]string{"guid", "id"}).AddRow(uuid.New(), 1))
+			sqlMock.ExpectCommit()
 
 			// Act
-			result, sessionID, err := service.CreateUserIfNotExists(user)
+			result, sessionID, err := service.CreateUserIfNotExists(thirdPartyUser)
 
 			// Assert
 			Expect(err).To(BeNil())
 			Expect(result).NotTo(BeNil())
-			Expect(sessionID).NotTo(BeEmpty())
-			Expect(result.Email).To(Equal(user.Email))
-			Expect(result.GivenName).To(Equal(user.GivenName))
-			Expect(result.FamilyName).To(Equal(user.FamilyName))
-			Expect(result.ExternalID).To(Equal(user.Sub))
+			Expect(sessionID).NotTo(Equal(uuid.Nil))
+			Expect(result.IsCreated).To(BeFalse())
+			Expect(result.Email).To(Equal(thirdPartyUser.Email))
+			Expect(result.ExternalID).To(Equal(thirdPartyUser.Sub))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should not create a user for the email of another account", func() {
+			// Arrange
+			thirdPartyUser := &domain.ThirdPartyUser{
+				Email:    "test@test.com",
+				Sub:      "test123",
+				Provider: "google",
+			}
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(expectedIdentitySelect).
+				WithArgs(thirdPartyUser.Provider, thirdPartyUser.Sub, 1).
+				WillReturnRows(sqlmock.NewRows(identityColumns))
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "users" WHERE email = \$1`).
+				WithArgs(thirdPartyUser.Email).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			sqlMock.ExpectRollback()
+
+			// Act
+			result, sessionID, err := service.CreateUserIfNotExists(thirdPartyUser)
+
+			// Assert
+			Expect(err).To(Equal(user.ErrEmailAlreadyRegistered))
+			Expect(result).To(BeNil())
+			Expect(sessionID).To(Equal(uuid.Nil))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
 		})
 
 		It("select fails, should return an error", func() {
 			// Arrange
-			user := &domain.ThirdPartyUser{
-				Email:      "test@test.com",
-				Sub:        "test123",
-				GivenName:  "Given",
-				FamilyName: "Family",
+			thirdPartyUser := &domain.ThirdPartyUser{
+				Email:    "test@test.com",
+				Sub:      "test123",
+				Provider: "google",
 			}
 
-			expectedSelect := "^SELECT (.+) FROM \"users\" WHERE email = (.+)$"
-			sqlMock.ExpectQuery(expectedSelect).WillReturnError(gorm.ErrInvalidDB)
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(expectedIdentitySelect).WillReturnError(gorm.ErrInvalidDB)
+			sqlMock.ExpectRollback()
 
 			// Act
-			result, sessionID, err := service.CreateUserIfNotExists(user)
+			result, sessionID, err := service.CreateUserIfNotExists(thirdPartyUser)
 
 			// Assert
 			Expect(err).ToNot(BeNil())
 			Expect(result).To(BeNil())
-			Expect(sessionID).To(BeEmpty())
+			Expect(sessionID).To(Equal(uuid.Nil))
 		})
 
-		It("insert fails, should return an error", func() {
+		It("insert fails, should not leave the user behind", func() {
 			// Arrange
-			user := &domain.ThirdPartyUser{
-				Email: "test@test.com",
+			thirdPartyUser := &domain.ThirdPartyUser{
+				Email:    "test@test.com",
+				Sub:      "test123",
+				Provider: "google",
 			}
 
-			expectedSelect := "^SELECT (.+) FROM \"users\" WHERE email = (.+)$"
-			sqlMock.ExpectQuery(expectedSelect).WillReturnError(gorm.ErrRecordNotFound)
 			sqlMock.ExpectBegin()
-			sqlMock.ExpectQuery("^INSERT INTO \"users\" (.+) RETURNING").WillReturnError(gorm.ErrInvalidDB)
+			sqlMock.ExpectQuery(expectedIdentitySelect).
+				WillReturnRows(sqlmock.NewRows(identityColumns))
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "users"`).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			sqlMock.ExpectQuery(`INSERT INTO "users" (.+) RETURNING`).
+				WillReturnRows(sqlmock.NewRows([]string{"guid", "id"}).AddRow(uuid.New(), 1))
+			sqlMock.ExpectQuery(`INSERT INTO "user_identities" (.+) RETURNING`).WillReturnError(gorm.ErrInvalidDB)
 			sqlMock.ExpectRollback()
 
 			// Act
-			result, sessionID, err := service.CreateUserIfNotExists(user)
+			result, sessionID, err := service.CreateUserIfNotExists(thirdPartyUser)
 
 			// Assert
 			Expect(err).ToNot(BeNil())
 			Expect(result).To(BeNil())
-			Expect(sessionID).To(BeEmpty())
+			Expect(sessionID).To(Equal(uuid.Nil))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+
+	Describe("LinkIdentity", func() {
+		var (
+			service user.Service
+			sqlMock sqlmock.Sqlmock
+			userID  uuid.UUID
+		)
+
+		identityColumns := []string{"id", "user_id", "provider", "subject", "email"}
+
+		BeforeEach(func() {
+			db, sqlMockGen, _ := sqlmock.New()
+			sqlMock = sqlMockGen
+
+			conn := postgres.New(postgres.Config{
+				Conn: db,
+			})
+
+			database, _ := database.NewDB(conn)
+			service = user.NewService(database)
+			userID = uuid.New()
+
+			sqlMock.ExpectQuery(`SELECT \* FROM "users" WHERE guid = \$1`).
+				WithArgs(userID, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "guid"}).AddRow(7, userID))
+		})
+
+		It("should link the provider account", func() {
+			// Arrange
+			thirdPartyUser := &domain.ThirdPartyUser{Email: "relay@privaterelay.appleid.com", Sub: "apple123", Provider: "apple"}
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT \* FROM "user_identities" WHERE provider = \$1 AND subject = \$2`).
+				WithArgs("apple", "apple123", 1).
+				WillReturnRows(sqlmock.NewRows(identityColumns))
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "user_identities" WHERE user_id = \$1 AND provider = \$2`).
+				WithArgs(7, "apple").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			sqlMock.ExpectQuery(`INSERT INTO "user_identities" (.+) RETURNING`).
+				WillReturnRows(sqlmock.NewRows([]string{"guid", "id"}).AddRow(uuid.New(), 2))
+			sqlMock.ExpectCommit()
+
+			// Act
+			identity, err := service.LinkIdentity(userID, thirdPartyUser)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(identity.Provider).To(Equal("apple"))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should not link an account of another user", func() {
+			// Arrange
+			thirdPartyUser := &domain.ThirdPartyUser{Sub: "apple123", Provider: "apple"}
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT \* FROM "user_identities" WHERE provider = \$1 AND subject = \$2`).
+				WillReturnRows(sqlmock.NewRows(identityColumns).AddRow(2, 8, "apple", "apple123", ""))
+			sqlMock.ExpectRollback()
+
+			// Act
+			identity, err := service.LinkIdentity(userID, thirdPartyUser)
+
+			// Assert
+			Expect(err).To(Equal(user.ErrIdentityLinkedToAnotherUser))
+			Expect(identity).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should not link a second account of the same provider", func() {
+			// Arrange
+			thirdPartyUser := &domain.ThirdPartyUser{Sub: "google456", Provider: "google"}
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT \* FROM "user_identities" WHERE provider = \$1 AND subject = \$2`).
+				WillReturnRows(sqlmock.NewRows(identityColumns))
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "user_identities" WHERE user_id = \$1 AND provider = \$2`).
+				WithArgs(7, "google").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			sqlMock.ExpectRollback()
+
+			// Act
+			identity, err := service.LinkIdentity(userID, thirdPartyUser)
+
+			// Assert
+			Expect(err).To(Equal(user.ErrProviderAlreadyLinked))
+			Expect(identity).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+
+	Describe("UnlinkIdentity", func() {
+		var (
+			service user.Service
+			sqlMock sqlmock.Sqlmock
+			userID  uuid.UUID
+		)
+
+		identityColumns := []string{"id", "user_id", "provider", "subject"}
+
+		BeforeEach(func() {
+			db, sqlMockGen, _ := sqlmock.New()
+			sqlMock = sqlMockGen
+
+			conn := postgres.New(postgres.Config{
+				Conn: db,
+			})
+
+			database, _ := database.NewDB(conn)
+			service = user.NewService(database)
+			userID = uuid.New()
+
+			sqlMock.ExpectQuery(`SELECT \* FROM "users" WHERE guid = \$1`).
+				WithArgs(userID, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "guid", "provider", "external_id"}).AddRow(7, userID, "google", "google123"))
+		})
+
+		It("should move the sign up identity to the remaining one", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT \* FROM "user_identities" WHERE user_id = \$1 ORDER BY created_at ASC`).
+				WithArgs(7).
+				WillReturnRows(sqlmock.NewRows(identityColumns).
+					AddRow(1, 7, "google", "google123").
+					AddRow(2, 7, "apple", "apple123"))
+			sqlMock.ExpectExec(`DELETE FROM "user_identities" WHERE "user_identities"."id" = \$1`).
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectExec(`UPDATE "users" SET "external_id"=\$1,"provider"=\$2,"updated_at"=\$3 WHERE id = \$4`).
+				WithArgs("apple123", "apple", sqlmock.AnyArg(), 7).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectCommit()
+
+			// Act
+			err := service.UnlinkIdentity(userID, "google")
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should keep the last identity", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT \* FROM "user_identities" WHERE user_id = \$1 ORDER BY created_at ASC`).
+				WithArgs(7).
+				WillReturnRows(sqlmock.NewRows(identityColumns).AddRow(1, 7, "google", "google123"))
+			sqlMock.ExpectRollback()
+
+			// Act
+			err := service.UnlinkIdentity(userID, "google")
+
+			// Assert
+			Expect(err).To(Equal(user.ErrLastIdentity))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should return not found when the provider is not linked", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT \* FROM "user_identities" WHERE user_id = \$1 ORDER BY created_at ASC`).
+				WillReturnRows(sqlmock.NewRows(identityColumns).
+					AddRow(1, 7, "google", "google123").
+					AddRow(2, 7, "apple", "apple123"))
+			sqlMock.ExpectRollback()
+
+			// Act
+			err := service.UnlinkIdentity(userID, "microsoft")
+
+			// Assert
+			Expect(err).To(Equal(gorm.ErrRecordNotFound))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+
+	Describe("RevokeIdentitySessions", func() {
+		var (
+			service user.Service
+			sqlMock sqlmock.Sqlmock
+		)
+
+		BeforeEach(func() {
+			db, sqlMockGen, _ := sqlmock.New()
+			sqlMock = sqlMockGen
+
+			conn := postgres.New(postgres.Config{
+				Conn: db,
+			})
+
+			database, _ := database.NewDB(conn)
+			service = user.NewService(database)
+
+			sqlMock.ExpectQuery(`SELECT \* FROM "user_identities" WHERE provider = \$1 AND subject = \$2`).
+				WithArgs("apple", "apple123", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "provider", "subject"}).AddRow(2, 7, "apple", "apple123"))
+		})
+
+		It("should keep the sessions while another identity is linked", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "user_identities" WHERE user_id = \$1 AND provider <> \$2`).
+				WithArgs(7, "apple").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			sqlMock.ExpectCommit()
+
+			// Act
+			err := service.RevokeIdentitySessions("apple", "apple123")
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should revoke the sessions of the last identity", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "user_identities" WHERE user_id = \$1 AND provider <> \$2`).
+				WithArgs(7, "apple").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			sqlMock.ExpectExec(`UPDATE "refresh_tokens" SET "revoked_at"=\$1 WHERE session_id IN \(SELECT "id" FROM "sessions" WHERE user_id = \$2\) AND revoked_at IS NULL`).
+				WithArgs(sqlmock.AnyArg(), 7).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			sqlMock.ExpectExec(`UPDATE "sessions" SET "expired_at"=\$1,"updated_at"=\$2 WHERE user_id = \$3 AND expired_at = \$4`).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 7, "0001-01-01 00:00:00").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectCommit()
+
+			// Act
+			err := service.RevokeIdentitySessions("apple", "apple123")
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
 		})
 	})
 
@@ -208,4 +504,73 @@ var _ = Describe("Service", func() {
 			Expect(result).To(BeNil())
 		})
 	})
+
+	Describe("MigrateLegacyRefreshToken", func() {
+		var (
+			service user.Service
+			sqlMock sqlmock.Sqlmock
+		)
+
+		BeforeEach(func() {
+			db, sqlMockGen, _ := sqlmock.New()
+			sqlMock = sqlMockGen
+
+			conn := postgres.New(postgres.Config{
+				Conn: db,
+			})
+
+			database, _ := database.NewDB(conn)
+			service = user.NewService(database)
+		})
+
+		It("should create a session with the legacy token already rotated", func() {
+			// Arrange
+			userID := uuid.New()
+			sessionID := uuid.New()
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT \* FROM "users" WHERE guid = \$1 AND is_active = \$2`).
+				WithArgs(userID, true, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "guid"}).AddRow(1, userID))
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "refresh_tokens" WHERE token_hash = \$1`).
+				WithArgs("legacy-hash").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			sqlMock.ExpectQuery(`INSERT INTO "sessions" (.+) RETURNING`).
+				WillReturnRows(sqlmock.NewRows([]string{"guid", "id"}).AddRow(sessionID, 1))
+			sqlMock.ExpectQuery(`INSERT INTO "refresh_tokens" (.+) VALUES (.+),(.+) RETURNING`).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+			sqlMock.ExpectCommit()
+
+			// Act
+			result, resultSessionID, err := service.MigrateLegacyRefreshToken(userID, "legacy-hash", "next-hash", time.Now().Add(time.Hour))
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(result.Guid).To(Equal(userID))
+			Expect(resultSessionID).To(Equal(sessionID))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should reject a legacy token exchanged before", func() {
+			// Arrange
+			userID := uuid.New()
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT \* FROM "users" WHERE guid = \$1 AND is_active = \$2`).
+				WithArgs(userID, true, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "guid"}).AddRow(1, userID))
+			sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "refresh_tokens" WHERE token_hash = \$1`).
+				WithArgs("legacy-hash").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			sqlMock.ExpectRollback()
+
+			// Act
+			result, _, err := service.MigrateLegacyRefreshToken(userID, "legacy-hash", "next-hash", time.Now().Add(time.Hour))
+
+			// Assert
+			Expect(err).To(MatchError(user.ErrRefreshTokenReused))
+			Expect(result).To(BeNil())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
 })
@@ -0,0 +1,150 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* The fields of the settings without the upgrade of UserSettings.UnmarshalJSON, to decode the patches strictly */
+type settingsFields domain.UserSettings
+
+// GetUserSettings returns the effective settings of the user, the defaults of NewUserSettings when there are none.
+func (s *serviceImpl) GetUserSettings(userID uuid.UUID) (*domain.UserSettings, error) {
+	user, err := s.GetUserByGuid(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Settings == nil {
+		return domain.NewUserSettings(), nil
+	}
+
+	return user.Settings, nil
+}
+
+// PatchUserSettings applies the JSON merge patch (RFC 7396) to the settings of the user and returns the
+// effective settings. The fields set to null go back to their default, the version is set by the server.
+func (s *serviceImpl) PatchUserSettings(userID uuid.UUID, patch []byte) (*domain.UserSettings, error) {
+	fields, err := parseSettingsPatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &domain.UserSettings{}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		/* Locked so two patches of the user cannot overwrite each other */
+		var user domain.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("guid = ?", userID).First(&user).Error
+		if err != nil {
+			return err
+		}
+
+		current := user.Settings
+		if current == nil {
+			current = domain.NewUserSettings()
+		}
+
+		merged, err := mergeSettings(current, fields)
+		if err != nil {
+			return err
+		}
+		settings = merged
+
+		jsonSettings, err := json.Marshal(settings)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&domain.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"settings":                string(jsonSettings),
+			"is_notification_enabled": settings.NotificationEnabled,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+/* Applies the fields of a merge patch to the settings and validates the result */
+func mergeSettings(settings *domain.UserSettings, fields map[string]any) (*domain.UserSettings, error) {
+	currentJSON, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	current := map[string]any{}
+	if err := json.Unmarshal(currentJSON, &current); err != nil {
+		return nil, err
+	}
+
+	mergePatch(current, fields)
+
+	mergedJSON, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	/* The removed fields get the defaults */
+	merged := &domain.UserSettings{}
+	if err := json.Unmarshal(mergedJSON, merged); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSettings, err.Error())
+	}
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(merged); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSettings, err.Error())
+	}
+
+	return merged, nil
+}
+
+/* The patch must be an object of known fields with values of the right type, the version is ignored */
+func parseSettingsPatch(patch []byte) (map[string]any, error) {
+	fields := map[string]any{}
+	if err := json.Unmarshal(patch, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("%w: the patch must be a JSON object", ErrInvalidSettings)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(patch))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&settingsFields{}); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSettings, err.Error())
+	}
+
+	delete(fields, "version")
+
+	return fields, nil
+}
+
+/* RFC 7396: null removes the field, the objects are merged recursively, any other value replaces the field */
+func mergePatch(target map[string]any, patch map[string]any) {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+
+		if patchObject, ok := value.(map[string]any); ok {
+			targetObject, ok := target[key].(map[string]any)
+			if !ok {
+				targetObject = map[string]any{}
+			}
+
+			mergePatch(targetObject, patchObject)
+			target[key] = targetObject
+			continue
+		}
+
+		target[key] = value
+	}
+}
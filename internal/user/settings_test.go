@@ -0,0 +1,145 @@
+package user_test
+
+import (
+	"errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/postgres"
+
+	"github.com/pietro-putelli/feynman-backend/internal/database"
+	"github.com/pietro-putelli/feynman-backend/internal/domain"
+	"github.com/pietro-putelli/feynman-backend/internal/user"
+)
+
+var _ = Describe("Settings", func() {
+	var (
+		service user.Service
+		sqlMock sqlmock.Sqlmock
+		userID  uuid.UUID
+	)
+
+	columns := []string{"id", "guid", "settings"}
+
+	BeforeEach(func() {
+		db, sqlMockGen, _ := sqlmock.New()
+		sqlMock = sqlMockGen
+
+		conn := postgres.New(postgres.Config{
+			Conn: db,
+		})
+
+		database, _ := database.NewDB(conn)
+		service = user.NewService(database)
+		userID = uuid.New()
+	})
+
+	Describe("GetUserSettings", func() {
+		It("should upgrade the settings written before the versioning", func() {
+			// Arrange
+			sqlMock.ExpectQuery(`SELECT \* FROM "users" WHERE guid = \$1`).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(1, userID, []byte(`{"darkMode":false,"appLanguage":"it","notificationMode":"","secondLanguage":null}`)))
+
+			// Act
+			settings, err := service.GetUserSettings(userID)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(settings.Version).To(Equal(domain.UserSettingsVersion))
+			Expect(settings.DarkMode).To(BeFalse())
+			Expect(settings.AppLanguage).To(Equal("it"))
+			Expect(settings.NotificationMode).To(Equal("all"))
+			Expect(settings.NotificationEnabled).To(BeTrue())
+		})
+
+		It("should return the defaults when the user has no settings", func() {
+			// Arrange
+			sqlMock.ExpectQuery(`SELECT \* FROM "users" WHERE guid = \$1`).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(1, userID, nil))
+
+			// Act
+			settings, err := service.GetUserSettings(userID)
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(settings).To(Equal(domain.NewUserSettings()))
+		})
+	})
+
+	Describe("PatchUserSettings", func() {
+		It("should only change the fields of the patch", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT \* FROM "users" WHERE guid = \$1 .* FOR UPDATE`).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(1, userID, []byte(`{"version":1,"darkMode":true,"appLanguage":"it","notificationMode":"last-edit","notificationEnabled":true}`)))
+			sqlMock.ExpectExec(`UPDATE "users" SET "is_notification_enabled"=\$1,"settings"=\$2,"updated_at"=\$3 WHERE id = \$4`).
+				WithArgs(true, sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectCommit()
+
+			// Act
+			settings, err := service.PatchUserSettings(userID, []byte(`{"darkMode":false,"version":7}`))
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(settings.DarkMode).To(BeFalse())
+			Expect(settings.AppLanguage).To(Equal("it"))
+			Expect(settings.NotificationMode).To(Equal("last-edit"))
+			Expect(settings.Version).To(Equal(domain.UserSettingsVersion))
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should reset the fields set to null to their default", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT \* FROM "users"`).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(1, userID, []byte(`{"version":1,"notificationMode":"reviews"}`)))
+			sqlMock.ExpectExec(`UPDATE "users"`).WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectCommit()
+
+			// Act
+			settings, err := service.PatchUserSettings(userID, []byte(`{"notificationMode":null}`))
+
+			// Assert
+			Expect(err).To(BeNil())
+			Expect(settings.NotificationMode).To(Equal("all"))
+		})
+
+		DescribeTable("should reject the invalid patches without querying",
+			func(patch string) {
+				// Act
+				settings, err := service.PatchUserSettings(userID, []byte(patch))
+
+				// Assert
+				Expect(settings).To(BeNil())
+				Expect(errors.Is(err, user.ErrInvalidSettings)).To(BeTrue())
+				Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+			},
+			Entry("not an object", `["darkMode"]`),
+			Entry("null", `null`),
+			Entry("unknown field", `{"theme":"dark"}`),
+			Entry("wrong type", `{"darkMode":"yes"}`),
+		)
+
+		It("should reject an invalid value", func() {
+			// Arrange
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT \* FROM "users"`).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(1, userID, []byte(`{"version":1}`)))
+			sqlMock.ExpectRollback()
+
+			// Act
+			settings, err := service.PatchUserSettings(userID, []byte(`{"notificationMode":"sometimes"}`))
+
+			// Assert
+			Expect(settings).To(BeNil())
+			Expect(errors.Is(err, user.ErrInvalidSettings)).To(BeTrue())
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
@@ -1,42 +1,52 @@
 package utility
 
 import (
+	"slices"
+	"strings"
+
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/google/uuid"
-	"github.com/ilyakaznacheev/cleanenv"
 	"go.uber.org/zap"
 )
 
-type Config struct {
-	IsLocalEnv bool `env:"IS_LOCAL_ENV" env-default:"false"`
-}
-
-// GetUserIDBy returns the userID from the request
+// GetUserIDBy returns the userID set by the authorizer in the request context
 func GetUserIDBy(request events.APIGatewayProxyRequest) uuid.UUID {
-	config := &Config{}
-	err := cleanenv.ReadEnv(config)
-
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
+	stringUserID, _ := request.RequestContext.Authorizer["userID"].(string)
+
+	userID, err := uuid.Parse(stringUserID)
 	if err != nil {
-		logger.Error("Failed to read config", zap.Error(err))
+		logger.Error("Failed to parse userID", zap.String("userID", stringUserID), zap.Error(err))
+		return uuid.UUID{}
 	}
 
-	stringUserID := ""
+	return userID
+}
 
-	if config.IsLocalEnv {
-		stringUserID = "16bebb13-2dfa-4137-918d-be3aa3ef940a"
-	} else {
-		stringUserID = request.RequestContext.Authorizer["userID"].(string)
-	}
+// GetSessionIDBy returns the sessionID of the access token from the request
+func GetSessionIDBy(request events.APIGatewayProxyRequest) uuid.UUID {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
 
-	userID, err := uuid.Parse(stringUserID)
+	stringSessionID, _ := request.RequestContext.Authorizer["sessionID"].(string)
 
+	sessionID, err := uuid.Parse(stringSessionID)
 	if err != nil {
-		logger.Error("Failed to parse userID", zap.String("userID", stringUserID), zap.Error(err))
+		logger.Error("Failed to parse sessionID", zap.String("sessionID", stringSessionID), zap.Error(err))
 		return uuid.UUID{}
 	}
 
-	return userID
+	return sessionID
+}
+
+// HasScope tells whether the token of the request is allowed the scope, the session tokens of the apps have them all
+func HasScope(request events.APIGatewayProxyRequest, scope string) bool {
+	scopes, ok := request.RequestContext.Authorizer["scopes"].(string)
+	if !ok {
+		return true
+	}
+
+	return slices.Contains(strings.Fields(scopes), scope)
 }
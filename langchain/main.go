@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 
 	"github.com/pietro-putelli/feynman-backend/config"
+	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/openai"
 	"github.com/tmc/langchaingo/prompts"
 )
@@ -17,7 +18,7 @@ func extractKeys(m map[string]interface{}) []string {
 	return keys
 }
 
-func NewLLMRequest(prompt string, inputs map[string]interface{}) (map[string]interface{}, error) {
+func NewLLMRequest(prompt string, inputs map[string]interface{}, options ...llms.CallOption) (map[string]interface{}, error) {
 	cfg, err := config.NewConfig()
 	if err != nil {
 		return nil, err
@@ -42,7 +43,7 @@ func NewLLMRequest(prompt string, inputs map[string]interface{}) (map[string]int
 	}
 
 	ctx := context.Background()
-	completion, err := llm.Call(ctx, result)
+	completion, err := llm.Call(ctx, result, options...)
 	// , llms.WithTemperature(0.6)
 	if err != nil {
 		return nil, err
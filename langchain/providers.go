@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 
+	"github.com/tmc/langchaingo/llms"
 	"go.uber.org/zap"
 )
 
@@ -169,3 +170,200 @@ func TranslateWord(word, language string) (map[string]interface{}, error) {
 
 	return response, nil
 }
+
+/* Translate a whole pick into a different language, keeping the rich content formatting when possible. */
+func TranslatePick(contentText, content, language string) (map[string]interface{}, error) {
+	promptString := `
+		Translate the following text into the language: "{{.language}}".
+
+		Text: "{{.text}}"
+
+		The same text is also provided as a rich JSON document that carries its formatting: {{.content}}
+
+		Requirements:
+			- The translation should be accurate, natural and reflect the meaning of the original text.
+			- Keep names of people, places and works in their commonly used form for the target language.
+			- In the rich JSON document translate only the human readable text values, do not change keys, attributes, marks or the structure.
+			- If the rich JSON document is not valid or cannot be translated keeping its structure, return null as content.
+			- Do not add explanations or notes.
+
+		Return the output as an object of type {"contentText": "translated_text", "content": translated_rich_json_document}.
+	`
+
+	response, err := NewLLMRequest(promptString, map[string]interface{}{
+		"text":     contentText,
+		"content":  content,
+		"language": language,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+/* Generate the search terms used to retrieve the picks that can answer a question. */
+func GenerateSearchTerms(question, previousQuestion string) ([]string, error) {
+	promptString := `
+		Generate up to 5 search terms to find notes that can answer the question: "{{.question}}".
+		The previous question of the same conversation, that may give context to follow-up questions, is: "{{.previous}}".
+
+		Requirements:
+			- Each term should be a single word or a short compound word found in notes about the subject.
+			- Write the terms in the same language as the question.
+			- Exclude generic words like 'book', 'books', 'say', 'said', 'idea'.
+			- Do not use underscores ("_") or hyphens ("-") to connect words; if a term consists of multiple words, use a space.
+
+		Return the output as an object of type {"terms": ["term1", "term2", ...]}.
+	`
+
+	response, err := NewLLMRequest(promptString, map[string]interface{}{
+		"question": question,
+		"previous": previousQuestion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	terms, ok := response["terms"].([]interface{})
+	if !ok {
+		return nil, errors.New("unable to parse response")
+	}
+
+	termsStr := []string{}
+	for _, term := range terms {
+		if value, ok := term.(string); ok && strings.TrimSpace(value) != "" {
+			termsStr = append(termsStr, strings.ToLower(strings.TrimSpace(value)))
+		}
+	}
+
+	return termsStr, nil
+}
+
+/* Answer a question using only the given numbered picks, citing them inline. */
+func AnswerLibraryQuestion(question, history, context string, maxTokens int) (map[string]interface{}, error) {
+	promptString := `
+		You answer questions about what the user has read, using only the notes (picks) the user saved from their books.
+
+		Previous messages of the conversation:
+		{{.history}}
+
+		Numbered picks:
+		{{.context}}
+
+		Question: "{{.question}}"
+
+		Requirements:
+			- Use only the information contained in the numbered picks, do not add external knowledge.
+			- Cite the picks you use inline with their number in square brackets, e.g. "... habits are formed by repetition [2]".
+			- Write the answer in the same language as the question.
+			- Keep the answer concise, no more than 150 words.
+			- If the picks are not relevant to the question, return an empty answer and no citations.
+
+		Return the output as an object of type {"answer": "answer_text", "citations": [1, 2]}.
+	`
+
+	response, err := NewLLMRequest(promptString, map[string]interface{}{
+		"question": question,
+		"history":  history,
+		"context":  context,
+	}, llms.WithMaxTokens(maxTokens))
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+/* Summarize numbered picks of a book into its key ideas, each idea references the picks supporting it. */
+func SummarizeBookPicks(bookTitle, picks string) (map[string]interface{}, error) {
+	promptString := `
+		The following numbered notes (picks) have been saved by a reader of the book "{{.title}}":
+		{{.picks}}
+
+		Summarize them into the key ideas of the book.
+
+		Requirements:
+			- Use only the information contained in the picks.
+			- Generate from 1 to 7 key ideas, each with a short title and a description not exceeding 300 characters.
+			- Each key idea must reference the numbers of the picks supporting it.
+			- Write a summary of the picks not exceeding 600 characters.
+			- Write in the same language as the picks.
+
+		Return the output as an object of type {"summary": "summary_text", "ideas": [{"title": "idea_title", "description": "idea_description", "picks": [1, 2]}]}.
+	`
+
+	response, err := NewLLMRequest(promptString, map[string]interface{}{
+		"title": bookTitle,
+		"picks": picks,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+/* Merge the numbered key ideas generated from separate parts of a book into a single digest. */
+func MergeBookIdeas(bookTitle, ideas string) (map[string]interface{}, error) {
+	promptString := `
+		The following numbered key ideas have been extracted from separate parts of the notes of the book "{{.title}}":
+		{{.ideas}}
+
+		Merge them into the key ideas of the whole book.
+
+		Requirements:
+			- Merge the ideas that express the same concept and keep the most important ones.
+			- Generate from 1 to 7 key ideas, each with a short title and a description not exceeding 300 characters.
+			- Each key idea must reference the numbers of the ideas it comes from.
+			- Write a summary of the whole book not exceeding 600 characters.
+			- Write in the same language as the ideas.
+
+		Return the output as an object of type {"summary": "summary_text", "ideas": [{"title": "idea_title", "description": "idea_description", "sources": [1, 2]}]}.
+	`
+
+	response, err := NewLLMRequest(promptString, map[string]interface{}{
+		"title": bookTitle,
+		"ideas": ideas,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+/* Generate active-recall cards, cloze deletions and question/answer pairs, from a pick. */
+func GeneratePickCards(pickContent string) ([]map[string]interface{}, error) {
+	promptString := `
+		Generate active-recall flashcards based on the following text: "{{.text}}".
+
+		Requirements:
+			- Generate up to 2 cloze deletion cards: the question is a sentence of the text where the most important concept is replaced by "[...]", the answer is the removed concept.
+			- Generate up to 2 question and answer cards that test the understanding of the key ideas, not the memorization of details.
+			- Each answer must be found in the text and must not exceed 150 characters.
+			- Write the cards in the same language as the text.
+			- If the text has no meaningful content to remember, return no cards.
+
+		Return the output as an object of type {"cards": [{"kind": "cloze", "question": "question_text", "answer": "answer_text"}, {"kind": "qa", "question": "question_text", "answer": "answer_text"}]}.
+	`
+
+	response, err := NewLLMRequest(promptString, map[string]interface{}{"text": pickContent})
+	if err != nil {
+		return nil, err
+	}
+
+	cards, ok := response["cards"].([]interface{})
+	if !ok {
+		return nil, errors.New("unable to parse response")
+	}
+
+	cardsMap := []map[string]interface{}{}
+	for _, card := range cards {
+		if values, ok := card.(map[string]interface{}); ok {
+			cardsMap = append(cardsMap, values)
+		}
+	}
+
+	return cardsMap, nil
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pick_translations;
\ No newline at end of file
@@ -0,0 +1,18 @@
+CREATE TABLE pick_translations (
+    id SERIAL PRIMARY KEY NOT NULL,
+
+    user_id BIGINT NOT NULL,
+    pick_id BIGINT NOT NULL,
+    language VARCHAR(32) NOT NULL,
+    content JSONB NULL,
+    content_text TEXT NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (pick_id) REFERENCES book_picks (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    UNIQUE (pick_id, language)
+);
+
+CREATE INDEX pick_translations_pick_idx ON pick_translations (pick_id);
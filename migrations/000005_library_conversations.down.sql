@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS library_messages;
+DROP TABLE IF EXISTS library_conversations;
\ No newline at end of file
@@ -0,0 +1,28 @@
+CREATE TABLE library_conversations (
+    id SERIAL PRIMARY KEY NOT NULL,
+    guid UUID DEFAULT uuid_generate_v4 () NOT NULL UNIQUE,
+
+    user_id BIGINT NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX library_conversations_user_idx ON library_conversations (user_id, guid);
+
+CREATE TABLE library_messages (
+    id SERIAL PRIMARY KEY NOT NULL,
+
+    conversation_id BIGINT NOT NULL,
+    role VARCHAR(32) NOT NULL,
+    content TEXT NOT NULL,
+    citations JSONB NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (conversation_id) REFERENCES library_conversations (id) ON DELETE CASCADE
+);
+
+CREATE INDEX library_messages_conversation_idx ON library_messages (conversation_id, created_at);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS book_digests;
\ No newline at end of file
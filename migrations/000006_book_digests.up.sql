@@ -0,0 +1,15 @@
+CREATE TABLE book_digests (
+    id SERIAL PRIMARY KEY NOT NULL,
+
+    user_id BIGINT NOT NULL,
+    book_id BIGINT NOT NULL UNIQUE,
+    picks_hash VARCHAR(64) NOT NULL,
+    summary TEXT NOT NULL,
+    key_ideas JSONB NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
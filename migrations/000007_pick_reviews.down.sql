@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pick_review_logs;
+DROP TABLE IF EXISTS pick_review_states;
\ No newline at end of file
@@ -0,0 +1,38 @@
+CREATE TABLE pick_review_states (
+    id SERIAL PRIMARY KEY NOT NULL,
+
+    user_id BIGINT NOT NULL,
+    pick_id BIGINT NOT NULL UNIQUE,
+    ease REAL NOT NULL DEFAULT 2.5,
+    interval_days INT NOT NULL DEFAULT 0,
+    repetitions INT NOT NULL DEFAULT 0,
+    lapses INT NOT NULL DEFAULT 0,
+    due_at TIMESTAMP NOT NULL,
+    last_reviewed_at TIMESTAMP NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (pick_id) REFERENCES book_picks (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX pick_review_states_due_idx ON pick_review_states (user_id, due_at);
+
+CREATE TABLE pick_review_logs (
+    id SERIAL PRIMARY KEY NOT NULL,
+
+    user_id BIGINT NOT NULL,
+    pick_id BIGINT NOT NULL,
+    grade SMALLINT NOT NULL,
+    was_new BOOLEAN NOT NULL DEFAULT FALSE,
+    ease REAL NOT NULL,
+    interval_days INT NOT NULL,
+    previous_interval_days INT NOT NULL,
+    reviewed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (pick_id) REFERENCES book_picks (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX pick_review_logs_user_idx ON pick_review_logs (user_id, reviewed_at);
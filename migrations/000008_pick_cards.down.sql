@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pick_cards;
\ No newline at end of file
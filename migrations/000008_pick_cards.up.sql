@@ -0,0 +1,20 @@
+CREATE TABLE pick_cards (
+    id SERIAL PRIMARY KEY NOT NULL,
+    guid UUID DEFAULT uuid_generate_v4 () NOT NULL UNIQUE,
+
+    user_id BIGINT NOT NULL,
+    pick_id BIGINT NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    question TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'active',
+    is_edited BOOLEAN NOT NULL DEFAULT FALSE,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (pick_id) REFERENCES book_picks (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX pick_cards_pick_idx ON pick_cards (pick_id);
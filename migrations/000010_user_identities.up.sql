@@ -0,0 +1,20 @@
+CREATE TABLE user_identities (
+    id SERIAL PRIMARY KEY NOT NULL,
+    guid UUID DEFAULT uuid_generate_v4 () NOT NULL UNIQUE,
+
+    user_id BIGINT NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
+
+-- the identity each user signed up with
+INSERT INTO user_identities (user_id, provider, subject, email)
+SELECT id, provider, external_id, email FROM users;
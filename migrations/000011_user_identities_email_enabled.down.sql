@@ -0,0 +1 @@
+ALTER TABLE user_identities DROP COLUMN IF EXISTS email_enabled;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE user_identities ADD COLUMN email_enabled BOOLEAN NOT NULL DEFAULT TRUE;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS email_login_codes;
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE email_login_codes (
+    id SERIAL PRIMARY KEY NOT NULL,
+
+    email VARCHAR(255) NOT NULL,
+    ip VARCHAR(64) NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    link_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX email_login_codes_email_idx ON email_login_codes (email, created_at);
+CREATE INDEX email_login_codes_ip_idx ON email_login_codes (ip, created_at);
\ No newline at end of file
@@ -0,0 +1,19 @@
+CREATE TABLE personal_access_tokens (
+    id SERIAL PRIMARY KEY NOT NULL,
+    guid UUID DEFAULT uuid_generate_v4 () NOT NULL UNIQUE,
+
+    user_id BIGINT NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes JSONB NOT NULL DEFAULT '[]',
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    last_used_at TIMESTAMP NULL DEFAULT NULL,
+    revoked_at TIMESTAMP NULL DEFAULT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX personal_access_tokens_user_idx ON personal_access_tokens (user_id);
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS subscription_receipt_id VARCHAR(255) NULL DEFAULT NULL;
+
+ALTER TABLE users DROP COLUMN IF EXISTS premium_expires_at;
+
+DROP TABLE IF EXISTS subscriptions;
\ No newline at end of file
@@ -0,0 +1,32 @@
+CREATE TABLE subscriptions (
+    id SERIAL PRIMARY KEY NOT NULL,
+    guid UUID DEFAULT uuid_generate_v4 () NOT NULL UNIQUE,
+
+    user_id BIGINT NOT NULL,
+    original_transaction_id VARCHAR(64) NOT NULL UNIQUE,
+    transaction_id VARCHAR(64) NOT NULL,
+    product_id VARCHAR(255) NOT NULL,
+    environment VARCHAR(16) NOT NULL,
+    purchased_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    grace_period_expires_at TIMESTAMP NULL DEFAULT NULL,
+    auto_renew_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    auto_renew_product_id VARCHAR(255) NOT NULL DEFAULT '',
+    billing_retry BOOLEAN NOT NULL DEFAULT FALSE,
+    revoked_at TIMESTAMP NULL DEFAULT NULL,
+    revocation_reason INT NULL DEFAULT NULL,
+    signed_at TIMESTAMP NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX subscriptions_user_idx ON subscriptions (user_id);
+
+-- premium status of the user, kept in sync with the subscriptions
+ALTER TABLE users ADD COLUMN premium_expires_at TIMESTAMP NULL DEFAULT NULL;
+
+-- receipts were sent by the clients and never verified
+ALTER TABLE users DROP COLUMN IF EXISTS subscription_receipt_id;
\ No newline at end of file
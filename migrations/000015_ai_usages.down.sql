@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ai_usages;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE ai_usages (
+    user_id BIGINT NOT NULL,
+    day DATE NOT NULL,
+    calls INT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (user_id, day),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
\ No newline at end of file
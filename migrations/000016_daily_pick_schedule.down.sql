@@ -0,0 +1 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS daily_pick_sent_on;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN daily_pick_sent_on DATE NULL DEFAULT NULL;
\ No newline at end of file
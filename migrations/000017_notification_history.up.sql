@@ -0,0 +1,16 @@
+CREATE TABLE notification_history (
+    id SERIAL PRIMARY KEY NOT NULL,
+
+    user_id BIGINT NOT NULL,
+    pick_id BIGINT NOT NULL,
+    strategy VARCHAR(32) NOT NULL,
+    local_date DATE NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (pick_id) REFERENCES book_picks (id) ON DELETE CASCADE,
+    UNIQUE (user_id, local_date, pick_id)
+);
+
+CREATE INDEX notification_history_pick_idx ON notification_history (pick_id, local_date);
\ No newline at end of file
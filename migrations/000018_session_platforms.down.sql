@@ -0,0 +1,3 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS push_environment;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS platform;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE sessions ADD COLUMN platform VARCHAR(16) NOT NULL DEFAULT 'ios';
+
+ALTER TABLE sessions ADD COLUMN push_environment VARCHAR(16) NOT NULL DEFAULT 'production';
\ No newline at end of file
@@ -0,0 +1,9 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS push_disabled_at;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS push_failures;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_push_at;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_push_reason;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_push_outcome;
\ No newline at end of file
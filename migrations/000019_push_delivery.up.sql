@@ -0,0 +1,9 @@
+ALTER TABLE sessions ADD COLUMN last_push_outcome VARCHAR(16);
+
+ALTER TABLE sessions ADD COLUMN last_push_reason VARCHAR(64);
+
+ALTER TABLE sessions ADD COLUMN last_push_at TIMESTAMP;
+
+ALTER TABLE sessions ADD COLUMN push_failures INT NOT NULL DEFAULT 0;
+
+ALTER TABLE sessions ADD COLUMN push_disabled_at TIMESTAMP;
\ No newline at end of file
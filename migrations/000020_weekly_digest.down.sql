@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN IF EXISTS digest_sent_on;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN digest_sent_on DATE;
\ No newline at end of file
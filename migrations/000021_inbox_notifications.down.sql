@@ -0,0 +1 @@
+DROP TABLE IF EXISTS inbox_notifications;
\ No newline at end of file
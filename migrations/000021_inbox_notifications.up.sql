@@ -0,0 +1,23 @@
+CREATE TABLE inbox_notifications (
+    id SERIAL PRIMARY KEY NOT NULL,
+    guid UUID DEFAULT uuid_generate_v4 () NOT NULL UNIQUE,
+
+    user_id BIGINT NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    book_id UUID NULL,
+    pick_id UUID NULL,
+    dedupe_key VARCHAR(255) NULL,
+    read_at TIMESTAMP NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    UNIQUE (user_id, dedupe_key)
+);
+
+CREATE INDEX inbox_notifications_user_idx ON inbox_notifications (user_id, id DESC);
+
+CREATE INDEX inbox_notifications_unread_idx ON inbox_notifications (user_id) WHERE read_at IS NULL;
\ No newline at end of file
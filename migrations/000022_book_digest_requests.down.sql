@@ -0,0 +1,3 @@
+ALTER TABLE book_digests DROP COLUMN IF EXISTS requested_at;
+
+ALTER TABLE book_digests DROP COLUMN IF EXISTS pending_hash;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE book_digests ADD COLUMN pending_hash VARCHAR(64);
+
+ALTER TABLE book_digests ADD COLUMN requested_at TIMESTAMP;
\ No newline at end of file
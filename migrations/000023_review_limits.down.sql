@@ -0,0 +1 @@
+DROP TABLE IF EXISTS review_limits;
\ No newline at end of file
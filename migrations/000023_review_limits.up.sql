@@ -0,0 +1,19 @@
+CREATE TABLE review_limits (
+    id SERIAL PRIMARY KEY NOT NULL,
+
+    user_id BIGINT NOT NULL,
+    book_id BIGINT NULL,
+    topic_id BIGINT NULL,
+    daily_limit INT NOT NULL,
+    new_daily_limit INT NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE,
+    FOREIGN KEY (topic_id) REFERENCES topics (id) ON DELETE CASCADE,
+    CHECK (book_id IS NULL OR topic_id IS NULL)
+);
+
+CREATE UNIQUE INDEX review_limits_scope_idx ON review_limits (user_id, COALESCE(book_id, 0), COALESCE(topic_id, 0));
\ No newline at end of file